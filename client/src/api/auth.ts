@@ -63,9 +63,9 @@ async function handleResponse(res: Response): Promise<AuthResult> {
       if (typeof j['message'] === 'string')
         result.message = j['message'] as string;
       if (typeof j['errors'] !== 'undefined') result.errors = j['errors'];
-      // some handlers return `error` instead of message
-      if (!result.message && typeof j['error'] === 'string')
-        result.message = j['error'] as string;
+      // errors are application/problem+json with a human-readable `detail`
+      if (!result.message && typeof j['detail'] === 'string')
+        result.message = j['detail'] as string;
     }
   } catch (err) {
     // Non-JSON response or parse error
@@ -88,7 +88,9 @@ export async function getProjects(): Promise<ApiResponse<Project[]>> {
     }
 
     const message =
-      (json && ((json as any).message || (json as any).error)) ?? undefined;
+      (json &&
+        ((json as any).detail || (json as any).message)) ??
+      undefined;
 
     return {
       ok: res.ok,
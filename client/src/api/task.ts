@@ -39,7 +39,8 @@ export async function createTask(
     if (!res.ok) {
       return {
         ok: false,
-        message: json?.error ?? json?.message ?? 'Failed to create task',
+        message:
+          json?.detail ?? json?.message ?? 'Failed to create task',
       };
     }
 
@@ -86,7 +87,7 @@ export async function getTasks(
   const json = await res.json().catch(() => null);
 
   if (!res.ok) {
-    throw new Error(json?.error ?? json?.message ?? 'Failed to fetch tasks');
+    throw new Error(json?.detail ?? json?.message ?? 'Failed to fetch tasks');
   }
 
   // normalize backend 'id' => _id
@@ -134,7 +135,8 @@ export async function updateTask(
     if (!res.ok) {
       return {
         ok: false,
-        message: json?.error ?? json?.message ?? 'Failed to update task',
+        message:
+          json?.detail ?? json?.message ?? 'Failed to update task',
         status: res.status,
       };
     }
@@ -163,7 +165,7 @@ export async function deleteTask(id: string) {
 
   if (!res.ok) {
     const err = await res.json().catch(() => null);
-    throw new Error(err?.error || 'Failed to delete task');
+    throw new Error(err?.detail || 'Failed to delete task');
   }
   return true;
 }
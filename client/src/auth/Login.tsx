@@ -54,6 +54,7 @@ function extractMessageFromAuthResult(res: AuthResult): string | undefined {
       const n = nested as Record<string, unknown>;
       if (typeof n['message'] === 'string') return n['message'] as string;
     }
+    if (typeof top['detail'] === 'string') return top['detail'] as string;
     if (typeof top['error'] === 'string') return top['error'] as string;
     if (typeof top['errors'] === 'string') return top['errors'] as string;
   }
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+	"github.com/Subomi7/todoist-clone/server/db"
+	"github.com/Subomi7/todoist-clone/server/logging"
+	"github.com/Subomi7/todoist-clone/server/migrations"
+)
+
+const migrateUsage = "usage: server migrate [up|status] [-dry-run]"
+
+// RunMigrateCommand implements the "migrate" subcommand:
+//
+//	server migrate [up]        apply pending migrations
+//	server migrate up -dry-run list pending migrations without applying them
+//	server migrate status      show applied and pending migrations
+func RunMigrateCommand(args []string) error {
+	cmd := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report pending migrations without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || (cmd != "up" && cmd != "status") {
+		return errors.New(migrateUsage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	logging.Setup(cfg.Log)
+	err = db.StartMongoDB(cfg.Mongo)
+	if err != nil {
+		return err
+	}
+	defer db.CloseMongoDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if cmd == "status" {
+		return migrations.PrintStatus(ctx, os.Stdout)
+	}
+	n, err := migrations.Up(ctx, *dryRun, os.Stdout)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *dryRun:
+		fmt.Printf("%d pending migration(s)\n", n)
+	case n == 0:
+		fmt.Println("database is up to date")
+	}
+	return nil
+}
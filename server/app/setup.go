@@ -1,42 +1,97 @@
 package app
 
 import (
-	"os"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Subomi7/todoist-clone/server/config"
 	"github.com/Subomi7/todoist-clone/server/db"
+	"github.com/Subomi7/todoist-clone/server/handlers"
+	"github.com/Subomi7/todoist-clone/server/logging"
+	"github.com/Subomi7/todoist-clone/server/mailer"
+	"github.com/Subomi7/todoist-clone/server/metrics"
+	"github.com/Subomi7/todoist-clone/server/migrations"
 	"github.com/Subomi7/todoist-clone/server/router"
+	"github.com/Subomi7/todoist-clone/server/store"
+	"github.com/Subomi7/todoist-clone/server/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
+const (
+	// idle keep-alive connections would otherwise hold shutdown open until the drain timeout
+	serverReadTimeout = 30 * time.Second
+	serverIdleTimeout = 60 * time.Second
+)
+
+// SetupAndRunApp starts the server and blocks until it fails or receives SIGINT
+// or SIGTERM. Shutdown goes in order: stop reporting ready, drain in-flight
+// requests (cancelling those still running at the deadline), stop background
+// workers, then close the database.
 func SetupAndRunApp() error {
-	// Load environment variables
-	err := config.LoadENV()
+	// load and validate configuration (env, .env, CONFIG_FILE)
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	logging.Setup(cfg.Log)
+
+	// install the tracer provider before anything opens spans
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	handlers.UseConfig(cfg)
+	mailer.Configure(cfg.SMTP)
+
+	// load JWT signing/verification keys
+	err = handlers.InitKeys(cfg.JWT)
+	if err != nil {
+		return err
+	}
+
+	// register OpenID Connect providers
+	err = handlers.InitOIDC(cfg.OIDC)
 	if err != nil {
 		return err
 	}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ReadTimeout:  serverReadTimeout,
+		IdleTimeout:  serverIdleTimeout,
+		ErrorHandler: handlers.ErrorHandler,
+		// c.IP() reads ProxyHeader only on requests from a trusted proxy
+		ProxyHeader:             cfg.Proxy.Header,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.Proxy.Trusted,
+		EnableIPValidation:      true,
+	})
 
 		// attach middleware
+	app.Use(metrics.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(logging.Middleware())
+	app.Use(handlers.RequestContext())
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format: "[${ip}]:${port} ${status} - ${method} ${path} ${latency}\n",
-	}))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "http://localhost:5173, http://127.0.0.1:5173",
+		AllowOrigins: strings.Join(cfg.CORS.AllowOrigins, ", "),
 		 AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
         AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
-		ExposeHeaders:    "Content-Type, Authorization",
+		ExposeHeaders: strings.Join([]string{"Content-Type", "Authorization", logging.RequestIDHeader, fiber.HeaderRetryAfter,
+			handlers.HeaderRateLimitLimit, handlers.HeaderRateLimitRemaining, handlers.HeaderRateLimitReset, handlers.HeaderRateLimitPolicy}, ", "),
         AllowCredentials: true,
 	}))
 
 
 	// start database
-	err = db.StartMongoDB()
+	err = db.StartMongoDB(cfg.Mongo)
 	if err != nil {
 		return err
 	}
@@ -44,10 +99,83 @@ func SetupAndRunApp() error {
 	// defer closing database
 	defer db.CloseMongoDB()
 
+	// bring indexes and documents up to the current schema
+	err = migrations.Run()
+	if err != nil {
+		return err
+	}
+
+	// handlers persist through the MongoDB stores
+	stores := store.NewMongo()
+	if cfg.RateLimit.Store == "memory" {
+		// counters are per process, so each node enforces the full quota on its own
+		stores.RateLimits = store.NewMemoryRateLimits()
+	}
+	handlers.UseStores(stores)
+	metrics.CountSessionsWith(func(ctx context.Context) (int64, error) {
+		return stores.Tokens.CountActiveRefresh(ctx, time.Now().UTC())
+	})
+
+	// promote users listed in ADMIN_EMAILS
+	err = handlers.BootstrapAdmins()
+	if err != nil {
+		return err
+	}
+
 	router.SetupRoutes(app)
 
-	port := os.Getenv("PORT")
-	app.Listen(":" + port)
+	bg := newWorkers()
+	bg.every("token-cleanup", cfg.Lifecycle.TokenCleanupInterval, handlers.CleanupExpiredTokens)
+
+	// dependencies reported by /readyz
+	handlers.AddReadinessCheck("mongo", db.Ping)
+	handlers.AddReadinessCheck("migrations", func(ctx context.Context) error {
+		n, err := migrations.Pending(ctx)
+		if err == nil && n > 0 {
+			err = fmt.Errorf("%d pending", n)
+		}
+		return err
+	})
+	handlers.AddReadinessCheck("workers", bg.healthy)
 
-	return nil
+	stopSignals, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// ready only once the port is bound; a failed Listen never gets there
+	app.Hooks().OnListen(func(fiber.ListenData) error {
+		handlers.SetReady(true)
+		return nil
+	})
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(":" + strconv.Itoa(cfg.Port))
+	}()
+
+	var serveErr error
+	select {
+	case serveErr = <-listenErr:
+		slog.Error("server stopped", "err", serveErr)
+	case <-stopSignals.Done():
+		slog.Info("shutdown signal received, draining requests")
+	}
+	stop()
+	handlers.SetReady(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Lifecycle.ShutdownTimeout)
+	defer cancel()
+
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		slog.Error("shutdown: draining requests", "err", err)
+		// cancel the stragglers so their database work stops before the client closes
+		handlers.AbortRequests()
+	}
+	if err := bg.stop(ctx); err != nil {
+		slog.Error("shutdown: stopping workers", "err", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("shutdown: flushing traces", "err", err)
+	}
+	// the deferred CloseMongoDB runs last
+	slog.Info("shutdown complete")
+	return serveErr
 }
\ No newline at end of file
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Subomi7/todoist-clone/server/metrics"
+)
+
+// workers runs periodic background jobs until stopped.
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	started atomic.Int32
+	alive   atomic.Int32
+}
+
+func newWorkers() *workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workers{ctx: ctx, cancel: cancel}
+}
+
+// every runs job each interval in its own goroutine. A failing run is logged
+// and retried on the next tick.
+func (w *workers) every(name string, interval time.Duration, job func(context.Context) error) {
+	w.wg.Add(1)
+	w.started.Add(1)
+	w.alive.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer w.alive.Add(-1)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-ticker.C:
+				start := time.Now()
+				err := job(w.ctx)
+				metrics.ObserveJob(name, start, err)
+				if err != nil {
+					slog.Error("background job failed", "job", name, "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// healthy reports an error once any job has stopped, including after stop.
+func (w *workers) healthy(context.Context) error {
+	if started, alive := w.started.Load(), w.alive.Load(); alive < started {
+		return fmt.Errorf("%d of %d workers stopped", started-alive, started)
+	}
+	return nil
+}
+
+// stop cancels every job and waits for running ones to return, or for ctx to expire.
+func (w *workers) stop(ctx context.Context) error {
+	w.cancel()
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
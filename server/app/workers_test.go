@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkersRunUntilStopped(t *testing.T) {
+	w := newWorkers()
+	var runs atomic.Int32
+	var running atomic.Bool
+	w.every("test", time.Millisecond, func(ctx context.Context) error {
+		running.Store(true)
+		defer running.Store(false)
+		runs.Add(1)
+		<-ctx.Done() // a job still busy at shutdown sees its context cancelled
+		return nil
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for runs.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if runs.Load() == 0 {
+		t.Fatal("job never ran")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := w.stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if running.Load() {
+		t.Fatal("stop returned while a job was still running")
+	}
+}
+
+func TestWorkersStopHonoursDeadline(t *testing.T) {
+	w := newWorkers()
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	w.every("stuck", time.Millisecond, func(context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release // ignores cancellation
+		return nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.stop(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("stop: got %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,495 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the server configuration. It is loaded once at startup by Load and
+// passed down to the packages that need it.
+//
+// Values come from, in increasing priority: built-in defaults, the YAML file
+// named by CONFIG_FILE, and environment variables (including those from .env).
+type Config struct {
+	Env         string                        `yaml:"env"`         // GO_ENV
+	Port        int                           `yaml:"port"`        // PORT
+	AppBaseURL  string                        `yaml:"appBaseURL"`  // APP_BASE_URL, used in emailed links
+	AdminEmails []string                      `yaml:"adminEmails"` // ADMIN_EMAILS, comma-separated
+	Lifecycle   LifecycleConfig               `yaml:"lifecycle"`
+	Timeouts    TimeoutConfig                 `yaml:"timeouts"`
+	RateLimit   RateLimitConfig               `yaml:"rateLimit"`
+	Proxy       ProxyConfig                   `yaml:"proxy"`
+	Log         LogConfig                     `yaml:"log"`
+	Tracing     TracingConfig                 `yaml:"tracing"`
+	Mongo       MongoConfig                   `yaml:"mongo"`
+	JWT         JWTConfig                     `yaml:"jwt"`
+	Cookie      CookieConfig                  `yaml:"cookie"`
+	CORS        CORSConfig                    `yaml:"cors"`
+	SMTP        SMTPConfig                    `yaml:"smtp"`
+	OIDC        map[string]OIDCProviderConfig `yaml:"oidc"` // OIDC_PROVIDERS, OIDC_<NAME>_*
+}
+
+// LifecycleConfig controls background workers and shutdown.
+type LifecycleConfig struct {
+	ShutdownTimeout      time.Duration `yaml:"shutdownTimeout"`      // SHUTDOWN_TIMEOUT, e.g. "15s"
+	TokenCleanupInterval time.Duration `yaml:"tokenCleanupInterval"` // TOKEN_CLEANUP_INTERVAL
+}
+
+// TimeoutConfig bounds the database work of each request. Routes are keyed by
+// method and route template, e.g. "GET /api/account/export".
+type TimeoutConfig struct {
+	Default time.Duration            `yaml:"default"` // REQUEST_TIMEOUT
+	Routes  map[string]time.Duration `yaml:"routes"`  // REQUEST_TIMEOUT_ROUTES, "GET /api/x=30s,POST /api/y=5s"
+}
+
+// RateLimitConfig throttles each signed-in user, or each IP before sign-in.
+// Policies are written LIMIT/WINDOW, e.g. "20/1m" for 20 requests a minute.
+type RateLimitConfig struct {
+	Store string          `yaml:"store"` // RATE_LIMIT_STORE: mongo (shared by every node), memory (single node) or off
+	Auth  RateLimitPolicy `yaml:"auth"`  // RATE_LIMIT_AUTH: sign-in, registration and token endpoints, per IP
+	Read  RateLimitPolicy `yaml:"read"`  // RATE_LIMIT_READ: GET requests
+	Write RateLimitPolicy `yaml:"write"` // RATE_LIMIT_WRITE: every other method
+}
+
+// RateLimitPolicy allows Limit requests per fixed Window.
+type RateLimitPolicy struct {
+	Limit  int           `yaml:"limit"`
+	Window time.Duration `yaml:"window"`
+}
+
+// String formats p the way RATE_LIMIT_* variables are written.
+func (p RateLimitPolicy) String() string {
+	return strconv.Itoa(p.Limit) + "/" + p.Window.String()
+}
+
+// ParseRateLimitPolicy parses "LIMIT/WINDOW", e.g. "100/1m".
+func ParseRateLimitPolicy(s string) (RateLimitPolicy, error) {
+	limit, window, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return RateLimitPolicy{}, fmt.Errorf("%q is not LIMIT/WINDOW", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("%q: limit is not a number", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("%q: window is not a duration", s)
+	}
+	return RateLimitPolicy{Limit: n, Window: d}, nil
+}
+
+// ProxyConfig names the reverse proxies in front of the server. The client IP
+// used for rate limits, sign-in lockouts and logs comes from Header only when
+// the request arrives from one of the Trusted addresses; from anywhere else the
+// header is ignored and the connection's address is used.
+type ProxyConfig struct {
+	Header  string   `yaml:"header"`  // PROXY_HEADER, e.g. X-Real-IP; the proxy must overwrite it, not append
+	Trusted []string `yaml:"trusted"` // TRUSTED_PROXIES, comma-separated IPs or CIDR ranges
+}
+
+// LogConfig controls the structured logger.
+type LogConfig struct {
+	Level  string `yaml:"level"`  // LOG_LEVEL: debug, info, warn or error
+	Format string `yaml:"format"` // LOG_FORMAT: json or text
+}
+
+// TracingConfig controls OpenTelemetry tracing. The variables are the standard OTEL_* ones.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`    // OTEL_TRACES_EXPORTER: none, otlp or stdout
+	Endpoint    string  `yaml:"endpoint"`    // OTEL_EXPORTER_OTLP_ENDPOINT, e.g. http://localhost:4318
+	ServiceName string  `yaml:"serviceName"` // OTEL_SERVICE_NAME
+	SampleRatio float64 `yaml:"sampleRatio"` // OTEL_TRACES_SAMPLER_ARG, 0 to 1 of new traces
+}
+
+type MongoConfig struct {
+	URI      string `yaml:"uri"`      // MONGODB_URI
+	Database string `yaml:"database"` // DATABASE
+}
+
+// JWTConfig holds the token signing keys; see handlers.InitKeys for their formats.
+type JWTConfig struct {
+	SigningKey       string `yaml:"signingKey"`       // JWT_SIGNING_KEY
+	VerificationKeys string `yaml:"verificationKeys"` // JWT_VERIFICATION_KEYS
+	Secret           string `yaml:"secret"`           // JWT_SECRET
+}
+
+// CookieConfig applies to every cookie the API sets.
+type CookieConfig struct {
+	Domain   string `yaml:"domain"`   // COOKIE_DOMAIN
+	Secure   bool   `yaml:"secure"`   // COOKIE_SECURE
+	SameSite string `yaml:"sameSite"` // COOKIE_SAMESITE: Strict, Lax or None
+}
+
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allowOrigins"` // CORS_ALLOW_ORIGINS, comma-separated
+}
+
+// SMTPConfig configures outgoing mail. Without an address mail is only logged.
+type SMTPConfig struct {
+	Addr     string `yaml:"addr"`     // SMTP_ADDR, host:port
+	From     string `yaml:"from"`     // SMTP_FROM
+	Username string `yaml:"username"` // SMTP_USERNAME
+	Password string `yaml:"password"` // SMTP_PASSWORD
+}
+
+type OIDCProviderConfig struct {
+	Issuer       string   `yaml:"issuer"`       // OIDC_<NAME>_ISSUER
+	ClientID     string   `yaml:"clientID"`     // OIDC_<NAME>_CLIENT_ID
+	ClientSecret string   `yaml:"clientSecret"` // OIDC_<NAME>_CLIENT_SECRET
+	RedirectURL  string   `yaml:"redirectURL"`  // OIDC_<NAME>_REDIRECT_URL
+	Scopes       []string `yaml:"scopes"`       // OIDC_<NAME>_SCOPES, space-separated
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Env:  "development",
+		Port: 8080,
+		Lifecycle: LifecycleConfig{
+			ShutdownTimeout:      15 * time.Second,
+			TokenCleanupInterval: time.Hour,
+		},
+		Timeouts: TimeoutConfig{
+			Default: 10 * time.Second,
+			Routes: map[string]time.Duration{
+				"GET /api/account/export":               30 * time.Second,
+				"DELETE /api/account":                   30 * time.Second,
+				"GET /api/auth/oidc/:provider/callback": 15 * time.Second,
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Store: "mongo",
+			Auth:  RateLimitPolicy{Limit: 20, Window: time.Minute},
+			Read:  RateLimitPolicy{Limit: 600, Window: time.Minute},
+			Write: RateLimitPolicy{Limit: 120, Window: time.Minute},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "todoist-clone-server",
+			SampleRatio: 1,
+		},
+		Cookie: CookieConfig{
+			Secure:   true,
+			SameSite: "Strict",
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"http://localhost:5173", "http://127.0.0.1:5173"},
+		},
+	}
+}
+
+// Load reads .env (in development), the optional CONFIG_FILE and the environment,
+// and validates the result.
+func Load() (*Config, error) {
+	if err := LoadENV(); err != nil {
+		return nil, err
+	}
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides c with every variable that is set.
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	str := func(key string, dst *string) {
+		if v, ok := lookup(key); ok {
+			*dst = strings.TrimSpace(v)
+		}
+	}
+	list := func(key, sep string, dst *[]string) {
+		if v, ok := lookup(key); ok {
+			*dst = splitList(v, sep)
+		}
+	}
+
+	str("GO_ENV", &c.Env)
+	str("APP_BASE_URL", &c.AppBaseURL)
+	list("ADMIN_EMAILS", ",", &c.AdminEmails)
+	str("RATE_LIMIT_STORE", &c.RateLimit.Store)
+	str("PROXY_HEADER", &c.Proxy.Header)
+	list("TRUSTED_PROXIES", ",", &c.Proxy.Trusted)
+	str("LOG_LEVEL", &c.Log.Level)
+	str("LOG_FORMAT", &c.Log.Format)
+	str("OTEL_TRACES_EXPORTER", &c.Tracing.Exporter)
+	str("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.Endpoint)
+	str("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	str("MONGODB_URI", &c.Mongo.URI)
+	str("DATABASE", &c.Mongo.Database)
+	str("JWT_SIGNING_KEY", &c.JWT.SigningKey)
+	str("JWT_VERIFICATION_KEYS", &c.JWT.VerificationKeys)
+	str("JWT_SECRET", &c.JWT.Secret)
+	str("COOKIE_DOMAIN", &c.Cookie.Domain)
+	str("COOKIE_SAMESITE", &c.Cookie.SameSite)
+	list("CORS_ALLOW_ORIGINS", ",", &c.CORS.AllowOrigins)
+	str("SMTP_ADDR", &c.SMTP.Addr)
+	str("SMTP_FROM", &c.SMTP.From)
+	str("SMTP_USERNAME", &c.SMTP.Username)
+	str("SMTP_PASSWORD", &c.SMTP.Password)
+
+	if v, ok := lookup("PORT"); ok && strings.TrimSpace(v) != "" {
+		port, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("PORT: %q is not a number", v)
+		}
+		c.Port = port
+	}
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"SHUTDOWN_TIMEOUT", &c.Lifecycle.ShutdownTimeout},
+		{"TOKEN_CLEANUP_INTERVAL", &c.Lifecycle.TokenCleanupInterval},
+		{"REQUEST_TIMEOUT", &c.Timeouts.Default},
+	}
+	for _, d := range durations {
+		if v, ok := lookup(d.key); ok && strings.TrimSpace(v) != "" {
+			parsed, err := time.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a duration", d.key, v)
+			}
+			*d.dst = parsed
+		}
+	}
+	if v, ok := lookup("REQUEST_TIMEOUT_ROUTES"); ok {
+		if c.Timeouts.Routes == nil {
+			c.Timeouts.Routes = map[string]time.Duration{}
+		}
+		for _, entry := range splitList(v, ",") {
+			route, d, found := strings.Cut(entry, "=")
+			if !found {
+				return fmt.Errorf("REQUEST_TIMEOUT_ROUTES: %q is not ROUTE=DURATION", entry)
+			}
+			parsed, err := time.ParseDuration(strings.TrimSpace(d))
+			if err != nil {
+				return fmt.Errorf("REQUEST_TIMEOUT_ROUTES: %q is not a duration", d)
+			}
+			c.Timeouts.Routes[strings.TrimSpace(route)] = parsed
+		}
+	}
+	policies := []struct {
+		key string
+		dst *RateLimitPolicy
+	}{
+		{"RATE_LIMIT_AUTH", &c.RateLimit.Auth},
+		{"RATE_LIMIT_READ", &c.RateLimit.Read},
+		{"RATE_LIMIT_WRITE", &c.RateLimit.Write},
+	}
+	for _, p := range policies {
+		if v, ok := lookup(p.key); ok && strings.TrimSpace(v) != "" {
+			parsed, err := ParseRateLimitPolicy(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p.key, err)
+			}
+			*p.dst = parsed
+		}
+	}
+	if v, ok := lookup("OTEL_TRACES_SAMPLER_ARG"); ok && strings.TrimSpace(v) != "" {
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return fmt.Errorf("OTEL_TRACES_SAMPLER_ARG: %q is not a number", v)
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+	if v, ok := lookup("COOKIE_SECURE"); ok && strings.TrimSpace(v) != "" {
+		secure, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("COOKIE_SECURE: %q is not a boolean", v)
+		}
+		c.Cookie.Secure = secure
+	}
+
+	if v, ok := lookup("OIDC_PROVIDERS"); ok {
+		if c.OIDC == nil {
+			c.OIDC = map[string]OIDCProviderConfig{}
+		}
+		for _, name := range splitList(v, ",") {
+			name = strings.ToLower(name)
+			p := c.OIDC[name]
+			prefix := "OIDC_" + strings.ToUpper(name) + "_"
+			str(prefix+"ISSUER", &p.Issuer)
+			str(prefix+"CLIENT_ID", &p.ClientID)
+			str(prefix+"CLIENT_SECRET", &p.ClientSecret)
+			str(prefix+"REDIRECT_URL", &p.RedirectURL)
+			list(prefix+"SCOPES", " ", &p.Scopes)
+			c.OIDC[name] = p
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid or missing setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Port < 1 || c.Port > 65535 {
+		fail("port (PORT) must be between 1 and 65535, got %d", c.Port)
+	}
+	if c.Lifecycle.ShutdownTimeout <= 0 {
+		fail("lifecycle.shutdownTimeout (SHUTDOWN_TIMEOUT) must be positive")
+	}
+	if c.Lifecycle.TokenCleanupInterval <= 0 {
+		fail("lifecycle.tokenCleanupInterval (TOKEN_CLEANUP_INTERVAL) must be positive")
+	}
+	if c.Timeouts.Default <= 0 {
+		fail("timeouts.default (REQUEST_TIMEOUT) must be positive")
+	}
+	for route, d := range c.Timeouts.Routes {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			fail("timeouts.routes (REQUEST_TIMEOUT_ROUTES): %q must look like \"GET /api/tasks/:id\"", route)
+		}
+		if d <= 0 {
+			fail("timeouts.routes (REQUEST_TIMEOUT_ROUTES): %q must be positive", route)
+		}
+	}
+	switch c.RateLimit.Store {
+	case "mongo", "memory", "off":
+	default:
+		fail("rateLimit.store (RATE_LIMIT_STORE) must be mongo, memory or off, got %q", c.RateLimit.Store)
+	}
+	for _, p := range []struct {
+		name, env string
+		policy    RateLimitPolicy
+	}{
+		{"auth", "RATE_LIMIT_AUTH", c.RateLimit.Auth},
+		{"read", "RATE_LIMIT_READ", c.RateLimit.Read},
+		{"write", "RATE_LIMIT_WRITE", c.RateLimit.Write},
+	} {
+		if p.policy.Limit < 1 || p.policy.Window < time.Second {
+			fail("rateLimit.%s (%s) needs a positive limit and a window of at least 1s, got %s", p.name, p.env, p.policy)
+		}
+	}
+	if c.Proxy.Header != "" && len(c.Proxy.Trusted) == 0 {
+		fail("proxy.trusted (TRUSTED_PROXIES) is required with proxy.header (PROXY_HEADER)")
+	}
+	for _, p := range c.Proxy.Trusted {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			fail("proxy.trusted (TRUSTED_PROXIES): %q is not an IP address or CIDR range", p)
+		}
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("log.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		fail("log.format (LOG_FORMAT) must be json or text, got %q", c.Log.Format)
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if !isHTTPURL(c.Tracing.Endpoint) {
+			fail("tracing.endpoint (OTEL_EXPORTER_OTLP_ENDPOINT) must be an http(s) URL, got %q", c.Tracing.Endpoint)
+		}
+	default:
+		fail("tracing.exporter (OTEL_TRACES_EXPORTER) must be none, otlp or stdout, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sampleRatio (OTEL_TRACES_SAMPLER_ARG) must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	if c.Mongo.URI == "" {
+		fail("mongo.uri (MONGODB_URI) is required")
+	}
+	if c.Mongo.Database == "" {
+		fail("mongo.database (DATABASE) is required")
+	}
+	if c.JWT.SigningKey == "" && c.JWT.Secret == "" {
+		fail("one of jwt.signingKey (JWT_SIGNING_KEY) or jwt.secret (JWT_SECRET) is required")
+	}
+	if c.AppBaseURL != "" && !isHTTPURL(c.AppBaseURL) {
+		fail("appBaseURL (APP_BASE_URL) must be an http(s) URL, got %q", c.AppBaseURL)
+	}
+
+	switch c.Cookie.SameSite {
+	case "Strict", "Lax":
+	case "None":
+		if !c.Cookie.Secure {
+			fail("cookie.sameSite (COOKIE_SAMESITE) None requires cookie.secure (COOKIE_SECURE)")
+		}
+	default:
+		fail("cookie.sameSite (COOKIE_SAMESITE) must be Strict, Lax or None, got %q", c.Cookie.SameSite)
+	}
+
+	// credentials are allowed cross-origin, so origins must be listed explicitly
+	if len(c.CORS.AllowOrigins) == 0 {
+		fail("cors.allowOrigins (CORS_ALLOW_ORIGINS) must list at least one origin")
+	}
+	for _, o := range c.CORS.AllowOrigins {
+		if !isHTTPURL(o) {
+			fail("cors.allowOrigins (CORS_ALLOW_ORIGINS): %q is not an http(s) origin", o)
+		}
+	}
+
+	if c.SMTP.Addr != "" && c.SMTP.From == "" {
+		fail("smtp.from (SMTP_FROM) is required when smtp.addr (SMTP_ADDR) is set")
+	}
+
+	for name, p := range c.OIDC {
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			fail("oidc provider %q: issuer, clientID and redirectURL are required", name)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func splitList(s, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
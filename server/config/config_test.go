@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookupIn(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+port: 9000
+mongo:
+  uri: mongodb://file
+  database: todo
+cookie:
+  secure: false
+  sameSite: Lax
+cors:
+  allowOrigins: [https://app.example.com]
+oidc:
+  google:
+    issuer: https://accounts.google.com
+    clientID: file-client
+    redirectURL: https://api.example.com/api/auth/oidc/google/callback
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Default()
+	if err := cfg.loadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	err := cfg.loadEnv(lookupIn(map[string]string{
+		"MONGODB_URI":           "mongodb://env",
+		"JWT_SECRET":            "s3cret",
+		"COOKIE_SECURE":         "true",
+		"ADMIN_EMAILS":          " root@example.com, ,ops@example.com",
+		"OIDC_PROVIDERS":        "Google",
+		"OIDC_GOOGLE_CLIENT_ID": "env-client",
+		"OIDC_GOOGLE_SCOPES":    "openid  email",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 9000 || cfg.Mongo.URI != "mongodb://env" || cfg.Mongo.Database != "todo" {
+		t.Errorf("port/mongo: %d %+v", cfg.Port, cfg.Mongo)
+	}
+	if !cfg.Cookie.Secure || cfg.Cookie.SameSite != "Lax" {
+		t.Errorf("cookie: %+v", cfg.Cookie)
+	}
+	if strings.Join(cfg.AdminEmails, ",") != "root@example.com,ops@example.com" {
+		t.Errorf("admin emails: %q", cfg.AdminEmails)
+	}
+	g := cfg.OIDC["google"]
+	if g.ClientID != "env-client" || g.Issuer != "https://accounts.google.com" || strings.Join(g.Scopes, ",") != "openid,email" {
+		t.Errorf("oidc: %+v", g)
+	}
+}
+
+func TestUnknownFileKeysAreRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("mongo:\n  url: mongodb://typo\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Default().loadFile(path); err == nil {
+		t.Fatal("expected an error for an unknown key")
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	err := cfg.loadEnv(lookupIn(map[string]string{
+		"PORT":                 "70000",
+		"COOKIE_SECURE":        "false",
+		"COOKIE_SAMESITE":      "None",
+		"CORS_ALLOW_ORIGINS":   "*",
+		"SMTP_ADDR":            "smtp.example.com:587",
+		"LOG_LEVEL":            "verbose",
+		"OTEL_TRACES_EXPORTER": "jaeger",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"PORT", "MONGODB_URI", "DATABASE", "JWT_SECRET", "COOKIE_SAMESITE", "CORS_ALLOW_ORIGINS", "SMTP_FROM", "LOG_LEVEL", "OTEL_TRACES_EXPORTER"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestRequestTimeouts(t *testing.T) {
+	cfg := Default()
+	err := cfg.loadEnv(lookupIn(map[string]string{
+		"REQUEST_TIMEOUT":        "4s",
+		"REQUEST_TIMEOUT_ROUTES": "GET /api/tasks=2s, POST /api/tasks = 500ms",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Timeouts.Default != 4*time.Second {
+		t.Errorf("default = %v", cfg.Timeouts.Default)
+	}
+	if cfg.Timeouts.Routes["GET /api/tasks"] != 2*time.Second || cfg.Timeouts.Routes["POST /api/tasks"] != 500*time.Millisecond {
+		t.Errorf("routes = %v", cfg.Timeouts.Routes)
+	}
+	// built-in overrides stay unless replaced
+	if cfg.Timeouts.Routes["GET /api/account/export"] == 0 {
+		t.Error("default route timeouts were dropped")
+	}
+
+	cfg.Timeouts.Routes["/api/tasks"] = time.Second
+	cfg.Timeouts.Routes["GET /api/tags"] = 0
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `"/api/tasks"`) || !strings.Contains(err.Error(), `"GET /api/tags"`) {
+		t.Errorf("invalid routes not reported: %v", err)
+	}
+}
+
+func TestRateLimitPolicies(t *testing.T) {
+	cfg := Default()
+	err := cfg.loadEnv(lookupIn(map[string]string{
+		"RATE_LIMIT_STORE": "memory",
+		"RATE_LIMIT_AUTH":  "5/30s",
+		"RATE_LIMIT_WRITE": " 1000 / 1h ",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RateLimit.Store != "memory" || cfg.RateLimit.Auth != (RateLimitPolicy{Limit: 5, Window: 30 * time.Second}) {
+		t.Errorf("rate limit = %+v", cfg.RateLimit)
+	}
+	if cfg.RateLimit.Write != (RateLimitPolicy{Limit: 1000, Window: time.Hour}) {
+		t.Errorf("write = %s", cfg.RateLimit.Write)
+	}
+	if cfg.RateLimit.Read != Default().RateLimit.Read {
+		t.Errorf("unset read policy changed to %s", cfg.RateLimit.Read)
+	}
+
+	cfg.RateLimit.Store = "redis"
+	cfg.RateLimit.Read = RateLimitPolicy{Limit: 0, Window: time.Minute}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "RATE_LIMIT_STORE") || !strings.Contains(err.Error(), "RATE_LIMIT_READ") {
+		t.Errorf("invalid rate limits not reported: %v", err)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	cfg := Default()
+	err := cfg.loadEnv(lookupIn(map[string]string{
+		"PROXY_HEADER":    "X-Real-IP",
+		"TRUSTED_PROXIES": "10.0.0.0/8, 127.0.0.1",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Proxy.Header != "X-Real-IP" || len(cfg.Proxy.Trusted) != 2 || cfg.Proxy.Trusted[1] != "127.0.0.1" {
+		t.Errorf("proxy = %+v", cfg.Proxy)
+	}
+
+	cfg.Proxy.Trusted = []string{"10.0.0.0/8", "proxy.internal"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `"proxy.internal"`) {
+		t.Errorf("bad proxy address not reported: %v", err)
+	}
+	cfg.Proxy.Trusted = nil
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "TRUSTED_PROXIES") {
+		t.Errorf("header without trusted proxies not reported: %v", err)
+	}
+}
+
+func TestMalformedEnvValues(t *testing.T) {
+	for key, val := range map[string]string{"PORT": "eighty", "COOKIE_SECURE": "maybe", "REQUEST_TIMEOUT_ROUTES": "GET /api/tasks", "RATE_LIMIT_AUTH": "lots", "RATE_LIMIT_READ": "10/often"} {
+		if err := Default().loadEnv(lookupIn(map[string]string{key: val})); err == nil {
+			t.Errorf("%s=%q: expected an error", key, val)
+		}
+	}
+}
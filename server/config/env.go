@@ -1,18 +1,22 @@
 package config
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 
 	"github.com/joho/godotenv"
 )
 
+// LoadENV loads .env into the environment in development. A missing .env is fine;
+// variables already set in the environment take precedence.
 func LoadENV() error {
 	goEnv := os.Getenv("GO_ENV")
 	if goEnv == "" || goEnv == "development" {
 		err := godotenv.Load()
-		if err != nil {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
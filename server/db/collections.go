@@ -12,3 +12,80 @@ func ProjectsCol() *mongo.Collection {
 func TasksCol() *mongo.Collection {
 	return GetCollection("tasks")
 }
+
+func PersonalTokensCol() *mongo.Collection {
+	return GetCollection("personal_access_tokens")
+}
+
+func LoginAttemptsCol() *mongo.Collection {
+	return GetCollection("login_attempts")
+}
+
+func RateLimitsCol() *mongo.Collection {
+	return GetCollection("rate_limits")
+}
+
+func OAuthClientsCol() *mongo.Collection {
+	return GetCollection("oauth_clients")
+}
+
+func OAuthCodesCol() *mongo.Collection {
+	return GetCollection("oauth_codes")
+}
+
+func OAuthTokensCol() *mongo.Collection {
+	return GetCollection("oauth_tokens")
+}
+
+func OAuthConsentsCol() *mongo.Collection {
+	return GetCollection("oauth_consents")
+}
+
+func WorkspacesCol() *mongo.Collection {
+	return GetCollection("workspaces")
+}
+
+func WorkspaceMembersCol() *mongo.Collection {
+	return GetCollection("workspace_members")
+}
+
+func AdminAuditCol() *mongo.Collection {
+	return GetCollection("admin_audit_log")
+}
+
+// OwnedCollection names a collection holding per-user documents and the field
+// that references the owning user.
+type OwnedCollection struct {
+	Name      string
+	UserField string
+	// ExportOnly collections are exported but not deleted with the account;
+	// handlers release those documents themselves.
+	ExportOnly bool
+}
+
+// UserOwnedCollections lists every collection with per-user data, in the order
+// account deletion removes it (credentials first). Account deletion and data
+// export walk this list, so new per-user collections must be registered here.
+// Owned workspaces are only exported: deletion hands shared ones to an heir
+// (handlers.releaseWorkspacesOwnedBy) instead of removing them.
+var UserOwnedCollections = []OwnedCollection{
+	{Name: "refresh_tokens", UserField: "user_id"},
+	{Name: "personal_access_tokens", UserField: "user_id"},
+	{Name: "oauth_tokens", UserField: "user_id"},
+	{Name: "oauth_codes", UserField: "user_id"},
+	{Name: "oauth_consents", UserField: "user_id"},
+	{Name: "oauth_clients", UserField: "owner_id"},
+	{Name: "workspace_members", UserField: "user_id"},
+	{Name: "tasks", UserField: "userId"},
+	{Name: "projects", UserField: "userId"},
+	{Name: "workspaces", UserField: "owner_id", ExportOnly: true},
+}
+
+func OIDCStatesCol() *mongo.Collection {
+	return GetCollection("oidc_states")
+}
+
+// MigrationsCol records the schema migrations applied to the database.
+func MigrationsCol() *mongo.Collection {
+	return GetCollection("schema_migrations")
+}
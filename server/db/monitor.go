@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Subomi7/todoist-clone/server/metrics"
+	"github.com/Subomi7/todoist-clone/server/tracing"
+)
+
+// noCollection labels commands that do not target a collection, such as ping.
+const noCollection = "none"
+
+type inflightCommand struct {
+	collection string
+	span       trace.Span
+}
+
+// commandMonitor records a metric and a trace span for every MongoDB command.
+// Spans are children of the span in the context the operation was called with.
+func commandMonitor() *event.CommandMonitor {
+	// finished events carry no command document or context, so keep both by request ID
+	var inflight sync.Map
+
+	finish := func(e event.CommandFinishedEvent, err error) {
+		v, ok := inflight.LoadAndDelete(e.RequestID)
+		if !ok {
+			return
+		}
+		cmd := v.(inflightCommand)
+		metrics.ObserveMongoCommand(cmd.collection, e.CommandName, e.Duration, err != nil)
+		tracing.End(cmd.span, err)
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			collection := commandCollection(e.CommandName, e.Command)
+			spanCollection := collection
+			if collection == noCollection {
+				spanCollection = ""
+			}
+			inflight.Store(e.RequestID, inflightCommand{
+				collection: collection,
+				span:       tracing.StartMongoSpan(ctx, e.DatabaseName, spanCollection, e.CommandName),
+			})
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.CommandFinishedEvent, nil)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.CommandFinishedEvent, errors.New(e.Failure))
+		},
+	}
+}
+
+// commandCollection returns the collection a command targets. Most commands
+// name it as the value of their first field; getMore names it separately.
+func commandCollection(name string, cmd bson.Raw) string {
+	if name == "getMore" {
+		if coll, ok := cmd.Lookup("collection").StringValueOK(); ok {
+			return coll
+		}
+		return noCollection
+	}
+	// sensitive commands such as saslStart are redacted to an empty document
+	first, err := cmd.IndexErr(0)
+	if err != nil {
+		return noCollection
+	}
+	if coll, ok := first.Value().StringValueOK(); ok {
+		return coll
+	}
+	return noCollection
+}
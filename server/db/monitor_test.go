@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCommandCollection(t *testing.T) {
+	raw := func(d bson.D) bson.Raw {
+		b, err := bson.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	tests := []struct {
+		name string
+		cmd  bson.D
+		want string
+	}{
+		{"find", bson.D{{Key: "find", Value: "tasks"}, {Key: "filter", Value: bson.D{}}}, "tasks"},
+		{"getMore", bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "projects"}}, "projects"},
+		{"ping", bson.D{{Key: "ping", Value: 1}}, noCollection},
+		{"saslStart", bson.D{}, noCollection},
+	}
+	for _, tt := range tests {
+		if got := commandCollection(tt.name, raw(tt.cmd)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
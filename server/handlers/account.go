@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+// secret fields that never leave the server, not even in the user's own export
+var exportRedactedFields = []string{
+	"password_hash",
+	"token_hash",
+	"totp_secret",
+	"totp_last_step",
+	"recovery_code_hashes",
+	"unlock_token_hash",
+	"secret_hash",
+	"code_hash",
+	"code_challenge",
+}
+
+// reauthWindow is how recent a sign-in must be to confirm a destructive change
+// for a user without a password.
+const reauthWindow = 5 * time.Minute
+
+type DeleteAccountRequest struct {
+	Password     string `json:"password,omitempty"`      // required unless the user signs in only through SSO
+	Code         string `json:"code,omitempty"`          // required when 2FA is enabled
+	RecoveryCode string `json:"recovery_code,omitempty"` // alternative to code
+}
+
+// reauthenticate checks that the caller has proved again that they are user,
+// and returns the error to answer with when they have not. Users with a
+// password must give it; wrong passwords count towards the same lockout as
+// Login, so a stolen session cannot be used to guess it. Users created through
+// SSO have none, so a second factor (which the caller checks when 2FA is on)
+// or a sign-in within reauthWindow stands in for it.
+func reauthenticate(c *fiber.Ctx, ctx context.Context, user *models.User, password string) error {
+	if user.PasswordHash == "" {
+		if user.TOTPEnabled {
+			return nil
+		}
+		if authTime, ok := c.Locals("auth_time").(time.Time); ok && time.Since(authTime) <= reauthWindow {
+			return nil
+		}
+		return newError(fiber.StatusUnauthorized, CodeReauthRequired, "sign in again to confirm")
+	}
+
+	ip := c.IP()
+	if wait, err := lockRemaining(ctx, accountLockKey(user.Email), ipLockKey(ip)); err != nil {
+		slog.ErrorContext(ctx, "reauthenticate: lockout check failed", "err", err)
+	} else if wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	if checkPasswordHash(user.PasswordHash, password) != nil {
+		recordLoginFailure(ctx, user.Email, ip, user)
+		return newError(fiber.StatusUnauthorized, CodeInvalidCredentials, "invalid credentials")
+	}
+	if err := clearFailures(ctx, accountLockKey(user.Email)); err != nil {
+		slog.ErrorContext(ctx, "reauthenticate: failed to clear login failures", "err", err)
+	}
+	return nil
+}
+
+// exportCollection returns the documents of one collection as relaxed extended
+// JSON, without the secret fields.
+func exportCollection(col store.Collection) ([]byte, error) {
+	docs := []json.RawMessage{}
+	for _, doc := range col.Docs {
+		for _, f := range exportRedactedFields {
+			delete(doc, f)
+		}
+		b, err := bson.MarshalExtJSON(doc, false, false)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, b)
+	}
+	return json.MarshalIndent(docs, "", "  ")
+}
+
+// ExportAccount returns a zip archive with one JSON file per collection holding
+// everything stored about the authenticated user.
+func ExportAccount(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	collections, err := stores.Accounts.Export(ctx, userID)
+	if err != nil {
+		return errInternal("failed to export account", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	addFile := func(col store.Collection) error {
+		data, err := exportCollection(col)
+		if err != nil {
+			return fmt.Errorf("%s: %w", col.Name, err)
+		}
+		w, err := zw.Create(col.Name + ".json")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	for _, col := range collections {
+		if err := addFile(col); err != nil {
+			return errInternal("failed to export account", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return errInternal("failed to export account", err)
+	}
+
+	filename := fmt.Sprintf("todoist-export-%s.zip", time.Now().UTC().Format("20060102"))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.Send(buf.Bytes())
+}
+
+// DeleteAccount permanently removes the authenticated user and all data they own.
+// Requires the password, or a recent sign-in for SSO-only users, and a second
+// factor when 2FA is enabled.
+func DeleteAccount(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	var req DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	user, err := findUserByID(ctx, userID)
+	if err != nil {
+		return errNotFound("user")
+	}
+	if user.PasswordHash != "" && req.Password == "" {
+		return errField("password", validate.Required, "password is required")
+	}
+	if err := reauthenticate(c, ctx, user, req.Password); err != nil {
+		return err
+	}
+
+	if user.TOTPEnabled {
+		ok, err := checkSecondFactor(ctx, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			return errInternal("database error", err)
+		}
+		if !ok {
+			return newError(fiber.StatusUnauthorized, CodeInvalidCode, "invalid code")
+		}
+	}
+
+	// owned data first and the user document last, so a failure part way can be retried
+	if err := revokeOAuthClientsOwnedBy(ctx, userID); err != nil {
+		return errInternal("failed to delete account", err)
+	}
+	if err := releaseWorkspacesOwnedBy(ctx, userID); err != nil {
+		return errInternal("failed to delete account", err)
+	}
+	if err := handOverSharedProjects(ctx, user); err != nil {
+		return errInternal("failed to delete account", err)
+	}
+	if err := stores.Accounts.DeleteData(ctx, userID); err != nil {
+		return errInternal("failed to delete account", err)
+	}
+	if err := clearFailures(ctx, accountLockKey(user.Email), mfaLockKey(userID.Hex())); err != nil {
+		slog.ErrorContext(c.UserContext(), "DeleteAccount: failed to clear login attempts", "err", err)
+	}
+	if err := stores.Users.Delete(ctx, userID); err != nil {
+		return errInternal("failed to delete account", err)
+	}
+
+	clearRefreshCookie(c)
+
+	slog.InfoContext(c.UserContext(), "account deleted", "user_id", userID.Hex())
+	return c.SendStatus(fiber.StatusNoContent)
+}
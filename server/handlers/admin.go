@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+const (
+	adminOpTimeout    = 10 * time.Second
+	ImpersonationTTL  = 15 * time.Minute
+	maxAdminPageSize  = 100
+	defaultAdminLimit = 20
+)
+
+// -------- DTOs ----------
+
+// AdminUserView is the admin-facing view of a user; it never includes secrets.
+type AdminUserView struct {
+	ID          string     `json:"id"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	Disabled    bool       `json:"disabled"`
+	DisabledAt  *time.Time `json:"disabledAt,omitempty"`
+	TOTPEnabled bool       `json:"totpEnabled"`
+	Providers   []string   `json:"providers,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type AdminUsersListResponse struct {
+	Data []AdminUserView       `json:"data"`
+	Meta models.PaginationMeta `json:"meta"`
+}
+
+type AdminAuditLogResponse struct {
+	Data []models.AdminAuditEntry `json:"data"`
+	Meta PaginationMeta           `json:"meta"`
+}
+
+type AdminUnlockRequest struct {
+	Email string `json:"email"`
+}
+
+type AdminSetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type AdminStats struct {
+	Users          int64 `json:"users"`
+	DisabledUsers  int64 `json:"disabledUsers"`
+	Admins         int64 `json:"admins"`
+	Tasks          int64 `json:"tasks"`
+	CompletedTasks int64 `json:"completedTasks"`
+	Projects       int64 `json:"projects"`
+	ActiveSessions int64 `json:"activeSessions"`
+	PersonalTokens int64 `json:"personalTokens"`
+	OAuthClients   int64 `json:"oauthClients"`
+}
+
+// -------- helpers ----------
+
+func adminUserView(u *models.User) AdminUserView {
+	role := u.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	v := AdminUserView{
+		ID:          u.ID.Hex(),
+		Email:       u.Email,
+		Role:        role,
+		Disabled:    u.Disabled,
+		DisabledAt:  u.DisabledAt,
+		TOTPEnabled: u.TOTPEnabled,
+		CreatedAt:   u.CreatedAt,
+	}
+	for _, ident := range u.Identities {
+		v.Providers = append(v.Providers, ident.Provider)
+	}
+	return v
+}
+
+// adminEmails returns the normalized addresses listed in ADMIN_EMAILS.
+func adminEmails() []string {
+	var emails []string
+	for _, e := range conf.AdminEmails {
+		if e = strings.TrimSpace(strings.ToLower(e)); e != "" {
+			emails = append(emails, e)
+		}
+	}
+	return emails
+}
+
+// BootstrapAdmins grants the admin role to existing users listed in ADMIN_EMAILS,
+// so the first administrator can be created without database access.
+func BootstrapAdmins() error {
+	emails := adminEmails()
+	if len(emails) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminOpTimeout)
+	defer cancel()
+
+	promoted, err := stores.Users.PromoteAdmins(ctx, emails)
+	if err != nil {
+		return err
+	}
+	if promoted > 0 {
+		slog.Info("BootstrapAdmins: granted admin role from ADMIN_EMAILS", "users", promoted)
+	}
+	return nil
+}
+
+// RequireAdmin only lets administrators through. Must be layered after JWTMiddleware.
+func RequireAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := getUserIDFromCtx(c)
+		if err != nil {
+			return errUnauthorized
+		}
+		ctx, cancel := context.WithTimeout(c.UserContext(), authLookupTimeout)
+		user, err := findUserByID(ctx, userID)
+		cancel()
+		if err != nil || !user.IsAdmin() || user.Disabled {
+			return newError(fiber.StatusForbidden, CodeForbidden, "admin access required")
+		}
+		c.Locals("admin", user)
+		return c.Next()
+	}
+}
+
+// auditAdminAction writes an audit log entry; failures are logged but do not fail the request.
+func auditAdminAction(c *fiber.Ctx, ctx context.Context, action string, target *primitive.ObjectID, details string) {
+	admin, _ := c.Locals("admin").(*models.User)
+	if admin == nil {
+		return
+	}
+	entry := models.AdminAuditEntry{
+		AdminID:      admin.ID,
+		Action:       action,
+		TargetUserID: target,
+		Details:      details,
+		IP:           c.IP(),
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := stores.Audit.Record(ctx, &entry); err != nil {
+		slog.ErrorContext(c.UserContext(), "auditAdminAction: failed to record action", "action", action, "admin_id", admin.ID.Hex(), "err", err)
+	}
+}
+
+// targetUser loads the user referenced by the :id route parameter.
+func targetUser(c *fiber.Ctx) (*models.User, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, errInvalidID("user")
+	}
+	user, err := findUserByID(c.UserContext(), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, errNotFound("user")
+		}
+		return nil, errInternal("failed to fetch user", err)
+	}
+	return user, nil
+}
+
+// revokeAllSessionsForUser signs a user out everywhere: refresh tokens, and the
+// tokens and unredeemed codes issued to OAuth clients. Access JWTs already
+// issued expire within AccessTokenTTL.
+func revokeAllSessionsForUser(ctx context.Context, userID primitive.ObjectID) error {
+	if err := revokeAllRefreshTokensForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := stores.OAuth.DeleteCodesForUser(ctx, userID); err != nil {
+		return err
+	}
+	return stores.OAuth.DeleteTokensForUser(ctx, userID)
+}
+
+// createImpersonationToken issues a short-lived access token for target carrying an
+// RFC 8693 "act" claim that identifies the administrator acting on their behalf.
+func createImpersonationToken(target *models.User, adminID primitive.ObjectID) (string, time.Time, error) {
+	exp := time.Now().Add(ImpersonationTTL)
+	claims := jwt.MapClaims{
+		"user_id": target.ID.Hex(),
+		"email":   target.Email,
+		"act":     map[string]string{"sub": adminID.Hex()},
+		"exp":     exp.Unix(),
+		"iat":     time.Now().Unix(),
+		"iss":     issuer,
+	}
+	signed, err := keySet.sign(claims)
+	return signed, exp, err
+}
+
+// -------- Handlers ----------
+
+// AdminListUsers searches users by email substring with pagination.
+// supports ?search=&page=&pageSize=&role=&disabled=
+func AdminListUsers(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("pageSize", strconv.Itoa(defaultAdminLimit)))
+	if pageSize < 1 || pageSize > maxAdminPageSize {
+		pageSize = defaultAdminLimit
+	}
+
+	filter := store.UserFilter{
+		Search: strings.ToLower(strings.TrimSpace(c.Query("search"))),
+		Role:   c.Query("role"),
+	}
+	if v := c.Query("disabled"); v != "" {
+		disabled := v == "true" || v == "1"
+		filter.Disabled = &disabled
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	users, total, err := stores.Users.List(ctx, filter, int64((page-1)*pageSize), int64(pageSize))
+	if err != nil {
+		return errInternal("failed to fetch users", err)
+	}
+	views := make([]AdminUserView, 0, len(users))
+	for i := range users {
+		views = append(views, adminUserView(&users[i]))
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return c.JSON(AdminUsersListResponse{
+		Data: views,
+		Meta: models.PaginationMeta{Page: page, PageSize: pageSize, Total: int(total), TotalPages: totalPages},
+	})
+}
+
+// AdminGetUser returns one user.
+func AdminGetUser(c *fiber.Ctx) error {
+	user, err := targetUser(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"data": adminUserView(user)})
+}
+
+// AdminDisableUser blocks sign-in for a user and ends their sessions.
+func AdminDisableUser(c *fiber.Ctx) error {
+	return setUserDisabled(c, true)
+}
+
+// AdminEnableUser re-enables a disabled user.
+func AdminEnableUser(c *fiber.Ctx) error {
+	return setUserDisabled(c, false)
+}
+
+func setUserDisabled(c *fiber.Ctx, disabled bool) error {
+	user, err := targetUser(c)
+	if err != nil {
+		return err
+	}
+	admin, _ := c.Locals("admin").(*models.User)
+	if disabled && admin != nil && admin.ID == user.ID {
+		return newError(fiber.StatusBadRequest, CodeNotAllowed, "cannot disable your own account")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	action := "user.disable"
+	if !disabled {
+		action = "user.enable"
+	}
+	if err := stores.Users.SetDisabled(ctx, user.ID, disabled, time.Now().UTC()); err != nil {
+		return errInternal("failed to update user", err)
+	}
+	if disabled {
+		if err := revokeAllSessionsForUser(ctx, user.ID); err != nil {
+			slog.ErrorContext(c.UserContext(), "setUserDisabled: failed to revoke sessions", "user_id", user.ID.Hex(), "err", err)
+		}
+	}
+	auditAdminAction(c, ctx, action, &user.ID, "")
+
+	updated, err := findUserByID(ctx, user.ID)
+	if err != nil {
+		return errInternal("failed to fetch updated user", err)
+	}
+	return c.JSON(fiber.Map{"data": adminUserView(updated)})
+}
+
+// AdminSetRole grants or revokes the admin role.
+func AdminSetRole(c *fiber.Ctx) error {
+	user, err := targetUser(c)
+	if err != nil {
+		return err
+	}
+	var req AdminSetRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+	if req.Role != models.RoleAdmin && req.Role != models.RoleUser {
+		return errField("role", validate.Invalid, `role must be "user" or "admin"`)
+	}
+	admin, _ := c.Locals("admin").(*models.User)
+	if admin != nil && admin.ID == user.ID && req.Role != models.RoleAdmin {
+		return newError(fiber.StatusBadRequest, CodeNotAllowed, "cannot remove your own admin role")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	if err := stores.Users.SetRole(ctx, user.ID, req.Role); err != nil {
+		return errInternal("failed to update user", err)
+	}
+	auditAdminAction(c, ctx, "user.set_role", &user.ID, "role="+req.Role)
+
+	user.Role = req.Role
+	return c.JSON(fiber.Map{"data": adminUserView(user)})
+}
+
+// AdminForceLogout revokes every session of a user.
+func AdminForceLogout(c *fiber.Ctx) error {
+	user, err := targetUser(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	if err := revokeAllSessionsForUser(ctx, user.ID); err != nil {
+		return errInternal("failed to revoke sessions", err)
+	}
+	auditAdminAction(c, ctx, "user.force_logout", &user.ID, "")
+	return c.JSON(fiber.Map{"message": "user logged out"})
+}
+
+// AdminImpersonate issues a short-lived, non-refreshable access token for a user.
+// The token is marked with the administrator's id and cannot manage the account itself.
+func AdminImpersonate(c *fiber.Ctx) error {
+	user, err := targetUser(c)
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin() {
+		return newError(fiber.StatusForbidden, CodeNotAllowed, "cannot impersonate another administrator")
+	}
+	if user.Disabled {
+		return newError(fiber.StatusBadRequest, CodeAccountDisabled, "user is disabled")
+	}
+	admin, _ := c.Locals("admin").(*models.User)
+
+	token, exp, err := createImpersonationToken(user, admin.ID)
+	if err != nil {
+		return errInternal("could not create token", err)
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+	auditAdminAction(c, ctx, "user.impersonate", &user.ID, "")
+
+	slog.InfoContext(c.UserContext(), "admin impersonating user", "admin_id", admin.ID.Hex(), "user_id", user.ID.Hex())
+	return c.JSON(TokenResponse{AccessToken: token, ExpiresAt: exp})
+}
+
+// AdminUnlockAccount clears failed-login state and lockouts for an account.
+func AdminUnlockAccount(c *fiber.Ctx) error {
+	var req AdminUnlockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+	email := strings.TrimSpace(strings.ToLower(req.Email))
+	if email == "" {
+		return errField("email", validate.Required, "email is required")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	keys := []string{accountLockKey(email)}
+	var target *primitive.ObjectID
+	if user, err := findUserByEmail(ctx, email); err == nil {
+		keys = append(keys, mfaLockKey(user.ID.Hex()))
+		target = &user.ID
+	}
+	if err := clearFailures(ctx, keys...); err != nil {
+		return errInternal("database error", err)
+	}
+	auditAdminAction(c, ctx, "user.unlock", target, "")
+	return c.JSON(fiber.Map{"message": "account unlocked"})
+}
+
+// AdminAuditLog lists audit entries, newest first. supports ?userId=&page=&pageSize=
+func AdminAuditLog(c *fiber.Ctx) error {
+	// the log pages by number only, always with its total
+	w := parseWindow(c, defaultAdminLimit)
+	w.ByCursor, w.Total = false, true
+	var target *primitive.ObjectID
+	if uid := c.Query("userId"); uid != "" {
+		objID, err := primitive.ObjectIDFromHex(uid)
+		if err != nil {
+			return errInvalidID("user")
+		}
+		target = &objID
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	entries, err := stores.Audit.List(ctx, target, w.skip(), int64(w.PageSize))
+	if err != nil {
+		return errInternal("failed to fetch audit log", err)
+	}
+	total, err := stores.Audit.Count(ctx, target)
+	if err != nil {
+		return errInternal("failed to count audit log", err)
+	}
+	return c.JSON(AdminAuditLogResponse{Data: entries, Meta: w.meta(total)})
+}
+
+// AdminGetStats returns system-wide counters.
+func AdminGetStats(c *fiber.Ctx) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	now := time.Now()
+	disabled, completed := true, true
+	var stats AdminStats
+	counts := []struct {
+		dst   *int64
+		count func() (int64, error)
+	}{
+		{&stats.Users, func() (int64, error) { return stores.Users.Count(ctx, store.UserFilter{}) }},
+		{&stats.DisabledUsers, func() (int64, error) { return stores.Users.Count(ctx, store.UserFilter{Disabled: &disabled}) }},
+		{&stats.Admins, func() (int64, error) { return stores.Users.Count(ctx, store.UserFilter{Role: models.RoleAdmin}) }},
+		{&stats.Tasks, func() (int64, error) { return stores.Tasks.Count(ctx, store.TaskFilter{}) }},
+		{&stats.CompletedTasks, func() (int64, error) { return stores.Tasks.Count(ctx, store.TaskFilter{Completed: &completed}) }},
+		{&stats.Projects, func() (int64, error) { return stores.Projects.Count(ctx, store.ProjectFilter{}) }},
+		{&stats.ActiveSessions, func() (int64, error) { return stores.Tokens.CountActiveRefresh(ctx, now) }},
+		{&stats.PersonalTokens, func() (int64, error) { return stores.Tokens.CountAllPersonal(ctx) }},
+		{&stats.OAuthClients, func() (int64, error) { return stores.OAuth.CountAllClients(ctx) }},
+	}
+	for _, cnt := range counts {
+		n, err := cnt.count()
+		if err != nil {
+			return errInternal("failed to compute stats", err)
+		}
+		*cnt.dst = n
+	}
+	return c.JSON(fiber.Map{"data": stats})
+}
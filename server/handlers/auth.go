@@ -6,31 +6,26 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
-	"log"
-	"net/mail"
-	"os"
+	"log/slog"
 	"strings"
 	"time"
 
 	"crypto/rand"
 
-	"go.mongodb.org/mongo-driver/mongo"
-
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 
-	"github.com/Subomi7/todoist-clone/server/db"
 	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
 )
 
 // -------- DTOs ----------
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Name     string `json:"name,omitempty"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8,maxbytes=72"` // bcrypt rejects anything longer
+	Name     string `json:"name,omitempty" validate:"max=100"`
 }
 
 type LoginRequest struct {
@@ -45,6 +40,13 @@ type TokenResponse struct {
 	RefreshToken string    `json:"refresh_token,omitempty"` // Include if input was in body
 }
 
+// MFAChallengeResponse is returned by Login instead of tokens when the user has 2FA enabled.
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
 // -------- settings ----------
 const (
 	AccessTokenTTL    = 15 * time.Minute
@@ -53,6 +55,9 @@ const (
 	bcryptCost        = 12 // Increased from default (10) for better security
 	issuer            = "todoist-clone" // Issuer for JWT validation
 	maxNameLength     = 100 // Maximum length for user name
+	MFATokenTTL       = 5 * time.Minute
+	mfaTokenType      = "mfa" // "typ" claim of MFA challenge tokens; never accepted as an access token
+	authLookupTimeout = 3 * time.Second // token and role lookups in middleware, before the route's own timeout starts
 )
 
 // -------- helpers ----------
@@ -61,16 +66,16 @@ func hashPassword(password string) (string, error) {
 	return string(bytes), err
 }
 
-func checkPasswordHash(hash, password string) error {
+// checkPasswordHash is a variable so tests can see which hash a login was
+// compared against.
+var checkPasswordHash = func(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-func createAccessToken(userID primitive.ObjectID, email string) (string, time.Time, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", time.Time{}, errors.New("JWT_SECRET not set")
-	}
-
+// createAccessToken signs a session access token. authTime is when the user
+// last signed in interactively; tokens minted by Refresh pass the zero time and
+// carry no auth_time claim.
+func createAccessToken(userID primitive.ObjectID, email string, authTime time.Time) (string, time.Time, error) {
 	exp := time.Now().Add(AccessTokenTTL)
 	claims := jwt.MapClaims{
 		"user_id": userID.Hex(),
@@ -79,12 +84,42 @@ func createAccessToken(userID primitive.ObjectID, email string) (string, time.Ti
 		"iat":     time.Now().Unix(),
 		"iss":     issuer,
 	}
+	if !authTime.IsZero() {
+		claims["auth_time"] = authTime.Unix()
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(secret))
+	signed, err := keySet.sign(claims)
 	return signed, exp, err
 }
 
+// createMFAToken issues a short-lived challenge token proving the password step of Login succeeded.
+func createMFAToken(userID primitive.ObjectID) (string, time.Time, error) {
+	exp := time.Now().Add(MFATokenTTL)
+	claims := jwt.MapClaims{
+		"user_id": userID.Hex(),
+		"typ":     mfaTokenType,
+		"exp":     exp.Unix(),
+		"iat":     time.Now().Unix(),
+		"iss":     issuer,
+	}
+
+	signed, err := keySet.sign(claims)
+	return signed, exp, err
+}
+
+// parseMFAToken validates an MFA challenge token and returns the user it was issued for.
+func parseMFAToken(tokenString string) (primitive.ObjectID, error) {
+	claims, err := keySet.parseToken(tokenString)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if typ, _ := claims["typ"].(string); typ != mfaTokenType {
+		return primitive.NilObjectID, errors.New("not an mfa token")
+	}
+	uid, _ := claims["user_id"].(string)
+	return primitive.ObjectIDFromHex(uid)
+}
+
 // generate a cryptographically secure random string (base64-url encoded)
 func generateRandomToken(nBytes int) (string, error) {
 	b := make([]byte, nBytes)
@@ -100,11 +135,7 @@ func hashToken(tok string) string {
 }
 
 // DB helpers for refresh tokens
-func saveRefreshToken(db *mongo.Database, userID primitive.ObjectID, tokenPlain string, expiresAt time.Time) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	col := db.Collection("refresh_tokens")
+func saveRefreshToken(ctx context.Context, userID primitive.ObjectID, tokenPlain string, expiresAt time.Time) error {
 	rt := models.RefreshToken{
 		UserID:    userID,
 		TokenHash: hashToken(tokenPlain),
@@ -112,61 +143,57 @@ func saveRefreshToken(db *mongo.Database, userID primitive.ObjectID, tokenPlain
 		ExpiresAt: expiresAt,
 		Revoked:   false,
 	}
-	_, err := col.InsertOne(ctx, rt)
+	err := stores.Tokens.SaveRefresh(ctx, &rt)
 	if err != nil {
-		log.Printf("Failed to save refresh token: %v", err)
+		slog.ErrorContext(ctx, "failed to save refresh token", "user_id", userID.Hex(), "err", err)
 	}
 	return err
 }
 
-func deleteRefreshTokenByHash(hash string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	col := db.GetCollection("refresh_tokens")
-	_, err := col.DeleteOne(ctx, bson.M{"token_hash": hash})
+func deleteRefreshTokenByHash(ctx context.Context, hash string) error {
+	err := stores.Tokens.DeleteRefresh(ctx, hash)
 	if err != nil {
-		log.Printf("Failed to delete refresh token by hash: %v", err)
+		slog.ErrorContext(ctx, "failed to delete refresh token", "err", err)
 	}
 	return err
 }
 
-func findRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	col := db.GetCollection("refresh_tokens")
-	var r models.RefreshToken
-	err := col.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&r)
+func findRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	r, err := stores.Tokens.FindRefresh(ctx, hash)
 	if err != nil {
-		log.Printf("Failed to find refresh token: %v", err)
+		if !errors.Is(err, store.ErrNotFound) {
+			slog.ErrorContext(ctx, "refresh token lookup failed", "err", err)
+		}
 		return nil, err
 	}
-	return &r, nil
+	return r, nil
 }
 
-// revoke (delete) all refresh tokens for a user (useful on password change)
-func revokeAllRefreshTokensForUser(userID primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func findUserByID(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	return stores.Users.FindByID(ctx, userID)
+}
+
+func findUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return stores.Users.FindByEmail(ctx, email)
+}
 
-	col := db.GetCollection("refresh_tokens")
-	_, err := col.DeleteMany(ctx, bson.M{"user_id": userID})
+// revoke (delete) all refresh tokens for a user (useful on password change)
+func revokeAllRefreshTokensForUser(ctx context.Context, userID primitive.ObjectID) error {
+	err := stores.Tokens.DeleteRefreshForUser(ctx, userID)
 	if err != nil {
-		log.Printf("Failed to revoke all refresh tokens: %v", err)
+		slog.ErrorContext(ctx, "failed to revoke refresh tokens", "user_id", userID.Hex(), "err", err)
 	}
 	return err
 }
 
 // CleanupExpiredTokens deletes expired refresh tokens from the database
-func CleanupExpiredTokens() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func CleanupExpiredTokens(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	col := db.GetCollection("refresh_tokens")
-	_, err := col.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": time.Now()}})
+	err := stores.Tokens.DeleteExpiredRefresh(ctx, time.Now())
 	if err != nil {
-		log.Printf("Failed to cleanup expired tokens: %v", err)
+		slog.ErrorContext(ctx, "failed to clean up expired refresh tokens", "err", err)
 	}
 	return err
 }
@@ -176,69 +203,39 @@ func CleanupExpiredTokens() error {
 // Register creates a new user with validated input and hashed password
 func Register(c *fiber.Ctx) error {
 	req := new(RegisterRequest)
-	if err := c.BodyParser(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	if err := parseBody(c, req); err != nil {
+		return err
 	}
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 	req.Name = strings.TrimSpace(req.Name)
-	if req.Email == "" || req.Password == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email and password required"})
-	}
-
-	// Validate email format
-	if _, err := mail.ParseAddress(req.Email); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid email format"})
-	}
-
-	// Enforce password strength (basic: min length)
-	if len(req.Password) < 8 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "password must be at least 8 characters"})
-	}
-
-	// Validate name length
-	if len(req.Name) > maxNameLength {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name too long"})
-	}
 
 	hashed, err := hashPassword(req.Password)
 	if err != nil {
-		log.Printf("Failed to hash password: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not hash password"})
+		return errInternal("could not hash password", err)
 	}
 
 	user := &models.User{
 		ID:           primitive.NewObjectID(),
 		Email:        req.Email,
 		PasswordHash: hashed,
+		Role:         models.RoleUser, // ADMIN_EMAILS only promotes at startup; the address is not verified here
 		CreatedAt:    time.Now(),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
-	collection := db.GetCollection("users")
-	_, err = collection.InsertOne(ctx, user)
+	err = stores.Users.Create(ctx, user)
 	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email already in use"})
+		if errors.Is(err, store.ErrDuplicate) {
+			return newError(fiber.StatusConflict, CodeEmailTaken, "email already in use")
 		}
-		log.Printf("Database error in register: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
-	}
-
-	pcol := db.ProjectsCol()
-	inbox := models.Project{
-    ID:        primitive.NewObjectID(),
-    UserID:    user.ID,
-    Name:      "Inbox",
-    IsSystem:  true,
-    CreatedAt: time.Now().UTC(),
-    UpdatedAt: time.Now().UTC(),
-}
-_, err = pcol.InsertOne(ctx, inbox)
-if err != nil {
-    log.Printf("failed to create Inbox for user %s: %v", user.ID.Hex(), err)
-}
+		return errInternal("database error", err)
+	}
+
+	if _, err := createInboxProject(ctx, user.ID); err != nil {
+		slog.ErrorContext(c.UserContext(), "register: failed to create Inbox", "user_id", user.ID.Hex(), "err", err)
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"id":    user.ID.Hex(),
@@ -250,75 +247,115 @@ if err != nil {
 func Login(c *fiber.Ctx) error {
 	req := new(LoginRequest)
 	if err := c.BodyParser(req); err != nil {
-		log.Printf("Login: body parse error: %v\n", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+		slog.DebugContext(c.UserContext(), "login: invalid body", "err", err)
+		return errInvalidBody
 	}
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 	if req.Email == "" || req.Password == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email and password required"})
+		return newError(fiber.StatusBadRequest, CodeValidationFailed, "email and password required")
 	}
 
 	// ctx with timeout for DB call
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
-	collection := db.GetCollection("users")
+	// refuse early while the account or client IP is locked, before spending any bcrypt work
+	ip := c.IP()
+	if wait, err := lockRemaining(ctx, accountLockKey(req.Email), ipLockKey(ip)); err != nil {
+		slog.ErrorContext(c.UserContext(), "login: lockout check failed", "err", err)
+	} else if wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
 	var user models.User
-	if err := collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user); err != nil {
+	found := true
+	u, err := stores.Users.FindByEmail(ctx, req.Email)
+	if err == nil {
+		user = *u
+	}
+	if err != nil {
 		// log details on server for debugging (don't return raw error to client)
-		if err == mongo.ErrNoDocuments {
-			log.Printf("Login: user not found for email=%s\n", req.Email)
+		if errors.Is(err, store.ErrNotFound) {
+			slog.DebugContext(c.UserContext(), "login: unknown email", "email", req.Email)
+		} else {
+			slog.ErrorContext(c.UserContext(), "login: user lookup failed", "err", err)
+		}
+		found = false
+	} else if user.PasswordHash == "" {
+		// accounts created through OIDC have no password
+		slog.DebugContext(c.UserContext(), "login: account has no password", "user_id", user.ID.Hex())
+		found = false
+	}
+
+	// Compare password. Unknown users are checked against a dummy hash so the
+	// response takes the same time as a wrong password.
+	hash := user.PasswordHash
+	if !found {
+		hash = dummyPasswordHash()
+	}
+	if err := checkPasswordHash(hash, req.Password); err != nil || !found {
+		if found {
+			slog.InfoContext(c.UserContext(), "login: wrong password", "user_id", user.ID.Hex())
+			recordLoginFailure(ctx, req.Email, ip, &user)
 		} else {
-			log.Printf("Login: FindOne error for email=%s: %v\n", req.Email, err)
+			recordLoginFailure(ctx, req.Email, ip, nil)
 		}
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
+		return newError(fiber.StatusUnauthorized, CodeInvalidCredentials, "invalid credentials")
 	}
 
-	// Quick sanity: log existence of password hash (not the hash value itself)
-	if user.PasswordHash == "" {
-		log.Printf("Login: user %s has empty password hash!\n", req.Email)
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
+	if err := clearFailures(ctx, accountLockKey(req.Email)); err != nil {
+		slog.ErrorContext(c.UserContext(), "login: failed to clear login failures", "err", err)
 	}
-	log.Printf("Login: found user id=%s email=%s hash_len=%d\n", user.ID.Hex(), user.Email, len(user.PasswordHash))
 
-	// Compare password
-	if err := checkPasswordHash(user.PasswordHash, req.Password); err != nil {
-		// bcrypt will return ErrMismatchedHashAndPassword for wrong password; log for debug
-		log.Printf("Login: password mismatch for email=%s: %v\n", req.Email, err)
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
+	return completeLogin(c, &user)
+}
+
+// completeLogin finishes a successful first-factor login (password or OIDC):
+// users with 2FA enabled get an MFA challenge, everyone else gets tokens.
+func completeLogin(c *fiber.Ctx, user *models.User) error {
+	if user.Disabled {
+		slog.InfoContext(c.UserContext(), "login: rejected disabled user", "user_id", user.ID.Hex())
+		return errAccountDisabled
+	}
+	if user.TOTPEnabled {
+		// first factor is correct but a second factor is required before any real tokens are issued
+		mfaToken, mfaExp, err := createMFAToken(user.ID)
+		if err != nil {
+			return errInternal("could not create token", err)
+		}
+		slog.InfoContext(c.UserContext(), "login: mfa challenge issued", "user_id", user.ID.Hex())
+		return c.Status(fiber.StatusOK).JSON(MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresAt:   mfaExp,
+		})
 	}
 
+	slog.InfoContext(c.UserContext(), "login succeeded", "user_id", user.ID.Hex())
+	return issueTokens(c, user)
+}
+
+// issueTokens creates an access token and a rotating refresh token for user,
+// sets the refresh cookie and writes the token response.
+func issueTokens(c *fiber.Ctx, user *models.User) error {
 	// create access token
-	accessToken, exp, err := createAccessToken(user.ID, user.Email)
+	accessToken, exp, err := createAccessToken(user.ID, user.Email, time.Now())
 	if err != nil {
-		log.Printf("Login: token creation failed for user=%s: %v\n", user.Email, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create token"})
+		return errInternal("could not create token", err)
 	}
 
 	// create refresh token (opaque)
 	refreshPlain, err := generateRandomToken(32)
 	if err != nil {
-		log.Printf("Login: refresh token gen failed for user=%s: %v\n", user.Email, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create refresh token"})
+		return errInternal("could not create refresh token", err)
 	}
 	refreshExp := time.Now().Add(RefreshTokenTTL)
-	if err := saveRefreshToken(db.GetCollection("refresh_tokens").Database(), user.ID, refreshPlain, refreshExp); err != nil {
-		log.Printf("Login: saveRefreshToken failed for user=%s: %v\n", user.Email, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not save refresh token"})
-	}
-
-	// set refresh cookie (note: if testing over HTTP, set Secure:false)
-	c.Cookie(&fiber.Cookie{
-		Name:     RefreshCookieName,
-		Value:    refreshPlain,
-		Expires:  refreshExp,
-		HTTPOnly: true,
-		Secure:   false, // use false while testing locally over HTTP
-		SameSite: "Lax",
-		Path:     "/api/auth",
-	})
+	if err := saveRefreshToken(c.UserContext(), user.ID, refreshPlain, refreshExp); err != nil {
+		return errInternal("could not save refresh token", err)
+	}
+
+	setRefreshCookie(c, refreshPlain, refreshExp)
 
-	log.Printf("Login: success for user=%s id=%s\n", user.Email, user.ID.Hex())
 	return c.Status(fiber.StatusOK).JSON(TokenResponse{
 		AccessToken: accessToken,
 		ExpiresAt:   exp,
@@ -338,55 +375,60 @@ func Refresh(c *fiber.Ctx) error {
 		}
 	}
 	if refreshToken == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "refresh token missing"})
+		return newError(fiber.StatusUnauthorized, CodeUnauthorized, "refresh token missing")
 	}
 
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
 	// look up by hash
 	hash := hashToken(refreshToken)
-	rt, err := findRefreshTokenByHash(hash)
+	rt, err := findRefreshTokenByHash(ctx, hash)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid refresh token"})
+		return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid refresh token")
 	}
 
 	// check expiry / revoked
 	if rt.ExpiresAt.Before(time.Now()) || rt.Revoked {
-		if err := deleteRefreshTokenByHash(hash); err != nil {
-			log.Printf("Failed to delete expired/revoked token: %v", err)
+		if err := deleteRefreshTokenByHash(ctx, hash); err != nil {
+			slog.ErrorContext(c.UserContext(), "refresh: failed to delete expired or revoked token", "err", err)
 		}
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "refresh token expired or revoked"})
+		return newError(fiber.StatusUnauthorized, CodeInvalidToken, "refresh token expired or revoked")
 	}
 
 	userID := rt.UserID
 	// fetch user to include email claim
-	var user models.User
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := db.GetCollection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
-		log.Printf("Failed to find user in refresh: %v", err)
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not found"})
+	user, err := findUserByID(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "refresh: user lookup failed", "err", err)
+		return newError(fiber.StatusUnauthorized, CodeInvalidToken, "user not found")
+	}
+	if user.Disabled {
+		if err := deleteRefreshTokenByHash(ctx, hash); err != nil {
+			slog.ErrorContext(c.UserContext(), "refresh: failed to delete token of disabled user", "err", err)
+		}
+		return errAccountDisabled
 	}
 
 	// create new access token
-	accessToken, exp, err := createAccessToken(userID, user.Email)
+	accessToken, exp, err := createAccessToken(userID, user.Email, time.Time{})
 	if err != nil {
-		log.Printf("Failed to create access token in refresh: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create access token"})
+		return errInternal("could not create access token", err)
 	}
 
 	// create a new refresh token and save it first (to avoid race)
 	newRefresh, err := generateRandomToken(32)
 	if err != nil {
-		log.Printf("Failed to generate new refresh token: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create refresh token"})
+		return errInternal("could not create refresh token", err)
 	}
 	newExp := time.Now().Add(RefreshTokenTTL)
-	if err := saveRefreshToken(db.GetCollection("refresh_tokens").Database(), userID, newRefresh, newExp); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not save refresh token"})
+	if err := saveRefreshToken(ctx, userID, newRefresh, newExp); err != nil {
+		return errInternal("could not save refresh token", err)
 	}
 
 	// Now delete old token
-	if err := deleteRefreshTokenByHash(hash); err != nil {
-		log.Printf("Failed to delete old refresh token: %v", err)
+	if err := deleteRefreshTokenByHash(ctx, hash); err != nil {
+		slog.ErrorContext(c.UserContext(), "refresh: failed to delete rotated token", "err", err)
 		// Continue, as new token is already saved
 	}
 
@@ -399,17 +441,7 @@ func Refresh(c *fiber.Ctx) error {
 	if !isCookieInput {
 		resp.RefreshToken = newRefresh
 	} else {
-		// set cookie
-		c.Cookie(&fiber.Cookie{
-			Name:     RefreshCookieName,
-			Value:    newRefresh,
-			Expires:  newExp,
-			HTTPOnly: true,
-			Secure:   true, // Always true in production
-			SameSite: "Strict",
-			Path:     "/api/auth",
-			Domain:   os.Getenv("COOKIE_DOMAIN"),
-		})
+		setRefreshCookie(c, newRefresh, newExp)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
@@ -423,31 +455,24 @@ func Logout(c *fiber.Ctx) error {
 		// also accept token in body
 		var body struct{ RefreshToken string `json:"refresh_token"` }
 		if err := c.BodyParser(&body); err != nil {
-			log.Printf("Failed to parse logout body: %v", err)
+			slog.DebugContext(c.UserContext(), "logout: invalid body", "err", err)
 		} else {
 			refreshToken = body.RefreshToken
 		}
 	}
 
 	if refreshToken != "" {
+		ctx, cancel := requestContext(c)
+		defer cancel()
+
 		hash := hashToken(refreshToken)
-		if err := deleteRefreshTokenByHash(hash); err != nil {
-			log.Printf("Failed to delete refresh token in logout: %v", err)
+		if err := deleteRefreshTokenByHash(ctx, hash); err != nil {
+			slog.ErrorContext(c.UserContext(), "logout: failed to delete refresh token", "err", err)
 			// Continue with logout
 		}
 	}
 
-	// clear cookie
-	c.Cookie(&fiber.Cookie{
-		Name:     RefreshCookieName,
-		Value:    "",
-		Expires:  time.Now().Add(-time.Hour),
-		HTTPOnly: true,
-		Secure:   true,
-		SameSite: "Strict",
-		Path:     "/api/auth",
-		Domain:   os.Getenv("COOKIE_DOMAIN"),
-	})
+	clearRefreshCookie(c)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "logged out"})
 }
@@ -456,24 +481,24 @@ func Logout(c *fiber.Ctx) error {
 func ProtectedProfile(c *fiber.Ctx) error {
 	uid := c.Locals("user_id")
 	if uid == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return errUnauthorized
 	}
 	idHex, ok := uid.(string)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
+		return errInvalidToken
 	}
 	oid, err := primitive.ObjectIDFromHex(idHex)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
+		return errInvalidToken
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
-	var user models.User
-	if err := db.GetCollection("users").FindOne(ctx, bson.M{"_id": oid}).Decode(&user); err != nil {
-		log.Printf("Failed to find user in profile: %v", err)
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+	user, err := stores.Users.FindByID(ctx, oid)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "profile: user lookup failed", "err", err)
+		return errNotFound("user")
 	}
 
 	return c.JSON(fiber.Map{
@@ -482,61 +507,117 @@ func ProtectedProfile(c *fiber.Ctx) error {
 	})
 }
 
-// JWTMiddleware validates the JWT access token
+// JWTMiddleware validates the JWT access token, a personal access token or an
+// OAuth2 access token. For the latter two the granted scopes are stored in c.Locals("scopes");
+// route groups enforce them with RequireScope.
 func JWTMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
 		if auth == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "missing authorization header"})
+			return newError(fiber.StatusUnauthorized, CodeUnauthorized, "missing authorization header")
 		}
 		parts := strings.SplitN(auth, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid authorization header"})
+			return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid authorization header")
 		}
 
 		tokenString := parts[1]
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			log.Printf("JWT secret not configured")
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "jwt secret not configured"})
+
+		// personal access tokens are opaque and looked up by hash instead of verified as JWTs
+		if strings.HasPrefix(tokenString, personalTokenPrefix) {
+			ctx, cancel := context.WithTimeout(c.UserContext(), authLookupTimeout)
+			pat, err := authenticatePersonalToken(ctx, tokenString)
+			cancel()
+			if err != nil {
+				if !errors.Is(err, store.ErrNotFound) {
+					slog.ErrorContext(c.UserContext(), "personal token lookup failed", "err", err)
+				}
+				return errInvalidToken
+			}
+			c.Locals("user_id", pat.UserID.Hex())
+			c.Locals("scopes", pat.Scopes)
+			return c.Next()
 		}
 
-		claims := jwt.MapClaims{}
-		_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
+		// access tokens issued to third-party apps through the OAuth2 flow
+		if strings.HasPrefix(tokenString, oauthAccessPrefix) {
+			ctx, cancel := context.WithTimeout(c.UserContext(), authLookupTimeout)
+			tok, err := authenticateOAuthAccessToken(ctx, tokenString)
+			cancel()
+			if err != nil {
+				if !errors.Is(err, store.ErrNotFound) {
+					slog.ErrorContext(c.UserContext(), "oauth token lookup failed", "err", err)
+				}
+				return errInvalidToken
 			}
-			return []byte(secret), nil
-		})
-		if err != nil {
-			log.Printf("Invalid token: %v", err)
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
+			c.Locals("user_id", tok.UserID.Hex())
+			c.Locals("scopes", tok.Scopes)
+			c.Locals("oauth_client_id", tok.ClientID)
+			return c.Next()
 		}
 
-		// Check expiration
-		if exp, ok := claims["exp"].(float64); ok {
-			if time.Unix(int64(exp), 0).Before(time.Now()) {
-				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "token expired"})
+		if keySet == nil {
+			return errInternal("jwt keys not configured", errors.New("no signing keys loaded"))
+		}
+
+		claims, err := keySet.parseToken(tokenString)
+		if err != nil {
+			switch {
+			case errors.Is(err, jwt.ErrTokenExpired):
+				return newError(fiber.StatusUnauthorized, CodeTokenExpired, "token expired")
+			case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+				return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid token issuer")
+			case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+				return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid token claims")
 			}
-		} else {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token claims"})
+			slog.DebugContext(c.UserContext(), "invalid access token", "err", err)
+			return errInvalidToken
 		}
 
-		// Check issuer
-		if iss, ok := claims["iss"].(string); !ok || iss != issuer {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token issuer"})
+		// MFA challenge tokens only grant access to the MFA verify step
+		if typ, _ := claims["typ"].(string); typ == mfaTokenType {
+			return errInvalidToken
 		}
 
 		uidRaw, ok := claims["user_id"]
 		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token claims"})
+			return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid token claims")
 		}
 		uid, ok := uidRaw.(string)
 		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token claims"})
+			return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid token claims")
+		}
+		objID, err := primitive.ObjectIDFromHex(uid)
+		if err != nil {
+			return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid token claims")
+		}
+
+		// a disabled or deleted account loses its session and impersonation
+		// tokens at once instead of when they expire
+		ctx, cancel := context.WithTimeout(c.UserContext(), authLookupTimeout)
+		user, err := stores.Users.FindByID(ctx, objID)
+		cancel()
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				slog.ErrorContext(c.UserContext(), "session user lookup failed", "err", err)
+			}
+			return errInvalidToken
+		}
+		if user.Disabled {
+			return errAccountDisabled
 		}
 
 		c.Locals("user_id", uid)
+		if at, ok := claims["auth_time"].(float64); ok {
+			c.Locals("auth_time", time.Unix(int64(at), 0))
+		}
+
+		// tokens minted by AdminImpersonate name the acting administrator
+		if act, ok := claims["act"].(map[string]interface{}); ok {
+			if sub, _ := act["sub"].(string); sub != "" {
+				c.Locals("impersonator_id", sub)
+			}
+		}
 		return c.Next()
 	}
 }
\ No newline at end of file
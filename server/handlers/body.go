@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+// parseBody decodes the request body into the DTO dst points to and checks its
+// validate tags. JSON bodies may not carry fields the DTO does not declare.
+// Every problem found is returned in one validation error.
+func parseBody(c *fiber.Ctx, dst any) error {
+	if !strings.HasPrefix(strings.ToLower(c.Get(fiber.HeaderContentType)), fiber.MIMEApplicationJSON) {
+		// form and XML bodies keep Fiber's lenient parsing
+		if err := c.BodyParser(dst); err != nil {
+			return errInvalidBody
+		}
+	} else if err := decodeJSON(c.Body(), dst); err != nil {
+		return err
+	}
+	if fields := validate.Struct(dst); len(fields) > 0 {
+		return errValidation(fields...)
+	}
+	return nil
+}
+
+func decodeJSON(body []byte, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err == nil && dec.More() {
+		return newError(fiber.StatusBadRequest, CodeInvalidBody, "body must hold a single JSON object")
+	}
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return errValidation(validate.FieldError{
+			Field:   typeErr.Field,
+			Code:    validate.Invalid,
+			Message: typeErr.Field + " must be " + jsonKind(typeErr.Type),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for this one
+		name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return errValidation(validate.FieldError{Field: name, Code: validate.Unknown, Message: "unknown field " + name})
+	case errors.Is(err, io.EOF):
+		return newError(fiber.StatusBadRequest, CodeInvalidBody, "body is empty")
+	}
+	return errInvalidBody
+}
+
+// jsonKind names the JSON type that decodes into t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
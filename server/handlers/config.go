@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+)
+
+// conf holds the settings the handlers read at request time. Defaults apply until
+// UseConfig is called.
+var conf = config.Default()
+
+// UseConfig sets the configuration. Must be called before serving requests.
+func UseConfig(c *config.Config) {
+	conf = c
+}
+
+// setRefreshCookie sets (or, with an empty value, clears) the refresh token cookie.
+// Every endpoint uses the same attributes so browsers replace rather than duplicate it.
+func setRefreshCookie(c *fiber.Ctx, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     RefreshCookieName,
+		Value:    value,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   conf.Cookie.Secure,
+		SameSite: conf.Cookie.SameSite,
+		Path:     "/api/auth",
+		Domain:   conf.Cookie.Domain,
+	})
+}
+
+func clearRefreshCookie(c *fiber.Ctx) {
+	setRefreshCookie(c, "", time.Now().Add(-time.Hour))
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrServerShutdown is the cancellation cause of requests still running when
+// graceful shutdown gives up waiting for them.
+var ErrServerShutdown = errors.New("server shutting down")
+
+var errRequestTimeout = errors.New("request timed out")
+
+// serverCtx ends when AbortRequests is called; every request context derives from it.
+var serverCtx, abortRequests = context.WithCancelCause(context.Background())
+
+// AbortRequests cancels the context of every in-flight request, so their
+// database work stops and they answer 503. Shutdown calls it once the drain
+// timeout expires.
+func AbortRequests() {
+	abortRequests(ErrServerShutdown)
+}
+
+// RequestContext makes c.UserContext() end when the server aborts in-flight
+// requests. A handler that fails because its context ended answers 503 instead
+// of its own error. Only the route's timeout and shutdown cancel work: fasthttp
+// does not tell a handler that the client disconnected, so a request whose
+// caller went away runs to completion.
+func RequestContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancelCause(c.UserContext())
+		defer cancel(nil)
+		stop := context.AfterFunc(serverCtx, func() { cancel(context.Cause(serverCtx)) })
+		defer stop()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if err == nil && c.Response().StatusCode() < fiber.StatusBadRequest {
+			return nil
+		}
+		// requestContext replaces c.UserContext() with the handler's own context,
+		// whose cause tells a timeout apart from the deferred cancel
+		switch cause := context.Cause(c.UserContext()); {
+		case errors.Is(cause, errRequestTimeout):
+			return &Error{Status: fiber.StatusServiceUnavailable, Code: CodeRequestTimeout, Detail: "request timed out", Cause: err}
+		case errors.Is(cause, ErrServerShutdown):
+			return &Error{Status: fiber.StatusServiceUnavailable, Code: CodeShuttingDown, Detail: "server shutting down", Cause: err}
+		}
+		return err
+	}
+}
+
+// requestContext returns the context for a handler's database work: the
+// request context bounded by the route's timeout. It also becomes
+// c.UserContext() so RequestContext can recognise a timeout.
+func requestContext(c *fiber.Ctx) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeoutCause(c.UserContext(), routeTimeout(c), errRequestTimeout)
+	c.SetUserContext(ctx)
+	return ctx, cancel
+}
+
+// routeTimeout returns the configured timeout of the matched route, or the default.
+func routeTimeout(c *fiber.Ctx) time.Duration {
+	route := c.Route()
+	path := route.Path
+	if len(path) > 1 {
+		// group routes registered as "/" end in a slash: "/api/account/"
+		path = strings.TrimSuffix(path, "/")
+	}
+	if d, ok := conf.Timeouts.Routes[route.Method+" "+path]; ok {
+		return d
+	}
+	return conf.Timeouts.Default
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+)
+
+// waitForDB stands in for a handler whose database call fails once its context ends.
+func waitForDB(c *fiber.Ctx) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+	<-ctx.Done()
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to fetch tasks"})
+}
+
+func TestRequestContext(t *testing.T) {
+	saved := conf
+	t.Cleanup(func() { conf = saved })
+	conf = config.Default()
+	conf.Timeouts.Routes = map[string]time.Duration{
+		"GET /slow":         time.Millisecond,
+		"DELETE /api/group": time.Millisecond,
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(RequestContext())
+	app.Get("/slow", waitForDB)
+	app.Group("/api/group").Delete("/", waitForDB)
+	app.Get("/missing", func(c *fiber.Ctx) error {
+		_, cancel := requestContext(c)
+		defer cancel()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "task not found"})
+	})
+
+	tests := []struct {
+		name, method, path string
+		want               int
+	}{
+		{"route timeout", "GET", "/slow", fiber.StatusServiceUnavailable},
+		{"group route timeout", "DELETE", "/api/group", fiber.StatusServiceUnavailable},
+		{"handler error passes through", "GET", "/missing", fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		resp, err := app.Test(req, 2000)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+// Error codes are part of the API: clients branch on them, so existing codes
+// must never change meaning or be renamed.
+const (
+	CodeInvalidBody        = "invalid_body"
+	CodeInvalidQuery       = "invalid_query"
+	CodeInvalidID          = "invalid_id"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeInvalidToken       = "invalid_token"
+	CodeTokenExpired       = "token_expired"
+	CodeAccountDisabled    = "account_disabled"
+	CodeReauthRequired     = "reauth_required"
+	CodeInvalidCode        = "invalid_code"
+	CodeMFAEnabled         = "mfa_already_enabled"
+	CodeMFANotEnabled      = "mfa_not_enabled"
+	CodeSSOFailed          = "sso_failed"
+	CodeEmailUnverified    = "email_not_verified"
+	CodeProviderDown       = "provider_unavailable"
+	CodeForbidden          = "forbidden"
+	CodeInsufficientScope  = "insufficient_scope"
+	CodeNotAllowed         = "not_allowed"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeEmailTaken         = "email_taken"
+	CodeProjectExists      = "project_exists"
+	CodeAlreadyMember      = "already_member"
+	CodeInboxProtected     = "inbox_protected"
+	CodeLimitReached       = "limit_reached"
+	CodeTooManyAttempts    = "too_many_attempts"
+	CodeRateLimited        = "rate_limited"
+	CodeRequestTimeout     = "request_timeout"
+	CodeShuttingDown       = "shutting_down"
+	CodeInternal           = "internal_error"
+)
+
+// MIMEProblemJSON is the media type of error responses (RFC 7807).
+const MIMEProblemJSON = "application/problem+json"
+
+// problemTypePrefix turns an error code into the problem "type" URI.
+const problemTypePrefix = "urn:todoist-clone:problem:"
+
+// Error is an application error. Handlers return it and ErrorHandler renders
+// it; the cause, if any, is logged but never sent to the client.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []FieldError
+	Cause  error
+}
+
+// FieldError describes one invalid field of a request body or query.
+type FieldError = validate.FieldError
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Code + ": " + e.Detail + ": " + e.Cause.Error()
+	}
+	return e.Code + ": " + e.Detail
+}
+
+func (e *Error) Unwrap() error { return e.Cause }
+
+// Problem is the RFC 7807 body of every error response.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func newError(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+var (
+	errUnauthorized    = newError(fiber.StatusUnauthorized, CodeUnauthorized, "unauthorized")
+	errInvalidToken    = newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid token")
+	errInvalidBody     = newError(fiber.StatusBadRequest, CodeInvalidBody, "invalid body")
+	errAccountDisabled = newError(fiber.StatusForbidden, CodeAccountDisabled, "account disabled")
+)
+
+// errInvalidID reports a malformed id of the named resource.
+func errInvalidID(resource string) *Error {
+	return newError(fiber.StatusBadRequest, CodeInvalidID, "invalid "+resource+" id")
+}
+
+// errField reports one invalid field of a request.
+func errField(field, code, message string) *Error {
+	return errValidation(FieldError{Field: field, Code: code, Message: message})
+}
+
+// errNotFound reports a missing (or inaccessible) resource.
+func errNotFound(resource string) *Error {
+	return newError(fiber.StatusNotFound, CodeNotFound, resource+" not found")
+}
+
+// errInternal reports a server-side failure; detail is safe to show, cause is only logged.
+func errInternal(detail string, cause error) *Error {
+	return &Error{Status: fiber.StatusInternalServerError, Code: CodeInternal, Detail: detail, Cause: cause}
+}
+
+// errValidation reports every invalid field at once.
+func errValidation(fields ...FieldError) *Error {
+	detail := "invalid request"
+	if len(fields) == 1 {
+		detail = fields[0].Message
+	}
+	return &Error{Status: fiber.StatusBadRequest, Code: CodeValidationFailed, Detail: detail, Fields: fields}
+}
+
+// ErrorHandler renders every error a handler returns as application/problem+json.
+// Errors other than *Error become a bare status (Fiber's own errors, such as an
+// unknown route) or an opaque 500. Server errors are logged with their cause.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			appErr = newError(fe.Code, statusCode(fe.Code), fe.Message)
+		} else {
+			appErr = errInternal("internal server error", err)
+		}
+	}
+	if appErr.Status >= fiber.StatusInternalServerError {
+		slog.ErrorContext(c.UserContext(), "request failed",
+			"status", appErr.Status, "code", appErr.Code, "detail", appErr.Detail, "err", appErr.Cause)
+	}
+
+	c.Status(appErr.Status)
+	c.Set(fiber.HeaderContentType, MIMEProblemJSON)
+	b, err := c.App().Config().JSONEncoder(Problem{
+		Type:     problemTypePrefix + appErr.Code,
+		Title:    utils.StatusMessage(appErr.Status),
+		Status:   appErr.Status,
+		Detail:   appErr.Detail,
+		Instance: c.Path(),
+		Code:     appErr.Code,
+		Errors:   appErr.Fields,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Send(b)
+}
+
+// statusCode derives a code from a status without one of its own: 405 becomes "method_not_allowed".
+func statusCode(status int) string {
+	if status == fiber.StatusNotFound {
+		return CodeNotFound
+	}
+	return strings.ReplaceAll(strings.ToLower(utils.StatusMessage(status)), " ", "_")
+}
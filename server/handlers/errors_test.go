@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+func TestErrorHandlerRendersProblems(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/tasks", func(c *fiber.Ctx) error {
+		return errValidation(
+			FieldError{Field: "title", Code: validate.Required, Message: "title is required"},
+			FieldError{Field: "priority", Code: validate.Invalid, Message: "invalid priority"},
+		)
+	})
+	app.Get("/tasks/:id", func(c *fiber.Ctx) error { return errNotFound("task") })
+	app.Get("/broken", func(c *fiber.Ctx) error { return errors.New("dial tcp 10.0.0.7:27017: connection refused") })
+	app.Get("/wrapped", func(c *fiber.Ctx) error { return errInternal("failed to fetch tasks", errors.New("mongo down")) })
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+		detail       string
+		fields       int
+	}{
+		{"POST", "/tasks", fiber.StatusBadRequest, CodeValidationFailed, "invalid request", 2},
+		{"GET", "/tasks/42", fiber.StatusNotFound, CodeNotFound, "task not found", 0},
+		{"GET", "/nowhere", fiber.StatusNotFound, CodeNotFound, "Cannot GET /nowhere", 0},
+		{"PUT", "/broken", fiber.StatusMethodNotAllowed, "method_not_allowed", "Method Not Allowed", 0},
+		{"GET", "/broken", fiber.StatusInternalServerError, CodeInternal, "internal server error", 0},
+		{"GET", "/wrapped", fiber.StatusInternalServerError, CodeInternal, "failed to fetch tasks", 0},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p Problem
+		err = json.NewDecoder(resp.Body).Decode(&p)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != MIMEProblemJSON {
+			t.Errorf("%s %s: content type %q", tt.method, tt.path, ct)
+		}
+		if resp.StatusCode != tt.status || p.Status != tt.status || p.Code != tt.code || p.Detail != tt.detail || len(p.Errors) != tt.fields {
+			t.Errorf("%s %s: %d %+v", tt.method, tt.path, resp.StatusCode, p)
+		}
+		if p.Type != problemTypePrefix+tt.code || p.Instance != tt.path || p.Title == "" {
+			t.Errorf("%s %s: type/title/instance = %q %q %q", tt.method, tt.path, p.Type, p.Title, p.Instance)
+		}
+		// causes stay in the logs
+		if strings.Contains(p.Detail, "mongo") || strings.Contains(p.Detail, "10.0.0.7") {
+			t.Errorf("%s %s: detail leaks the cause: %q", tt.method, tt.path, p.Detail)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+// ready reports whether the server accepts traffic. It is set once startup
+// completes and cleared as soon as shutdown begins, before requests drain.
+var ready atomic.Bool
+
+// SetReady marks the server as ready (or no longer ready) for traffic.
+func SetReady(v bool) {
+	ready.Store(v)
+}
+
+// HealthCheck reports whether a dependency is usable; a nil error means healthy.
+type HealthCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+var (
+	readinessMu     sync.RWMutex
+	readinessChecks []namedCheck
+)
+
+// AddReadinessCheck registers a dependency that /readyz reports on. Register
+// checks at startup, before serving requests.
+func AddReadinessCheck(name string, check HealthCheck) {
+	readinessMu.Lock()
+	defer readinessMu.Unlock()
+	readinessChecks = append(readinessChecks, namedCheck{name: name, check: check})
+}
+
+// CheckResult is the outcome of one readiness check.
+type CheckResult struct {
+	Status    string  `json:"status"` // "ok" or "fail"
+	LatencyMS float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status string                 `json:"status"` // "ok", "unavailable" or "not_ready"
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Healthz is the liveness probe: it answers as long as the process can serve HTTP.
+func Healthz(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Readyz is the readiness probe. It runs every registered check concurrently and
+// answers 503 unless all pass and the server is not starting up or shutting down.
+func Readyz(c *fiber.Ctx) error {
+	readinessMu.RLock()
+	checks := append([]namedCheck(nil), readinessChecks...)
+	readinessMu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), readinessCheckTimeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			res := CheckResult{Status: "ok", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				res.Status = "fail"
+				res.Error = err.Error()
+			}
+			results[i] = res
+		}(i, nc.check)
+	}
+	wg.Wait()
+
+	resp := ReadinessResponse{Status: "ok", Checks: make(map[string]CheckResult, len(checks))}
+	for i, nc := range checks {
+		resp.Checks[nc.name] = results[i]
+		if results[i].Status != "ok" {
+			resp.Status = "unavailable"
+		}
+	}
+	if !ready.Load() {
+		resp.Status = "not_ready"
+	}
+
+	if resp.Status != "ok" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(resp)
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func probe(t *testing.T, path string) (int, ReadinessResponse) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/healthz", Healthz)
+	app.Get("/readyz", Readyz)
+
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body ReadinessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func withReadinessChecks(t *testing.T) {
+	t.Helper()
+	readinessMu.Lock()
+	saved := readinessChecks
+	readinessChecks = nil
+	readinessMu.Unlock()
+	t.Cleanup(func() {
+		readinessMu.Lock()
+		readinessChecks = saved
+		readinessMu.Unlock()
+		SetReady(false)
+	})
+}
+
+func TestHealthzAlwaysOK(t *testing.T) {
+	withReadinessChecks(t)
+	AddReadinessCheck("broken", func(context.Context) error { return errors.New("down") })
+
+	status, body := probe(t, "/healthz")
+	if status != fiber.StatusOK || body.Status != "ok" {
+		t.Fatalf("got %d %q, want 200 ok", status, body.Status)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	withReadinessChecks(t)
+	healthy := true
+	AddReadinessCheck("db", func(context.Context) error {
+		if !healthy {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	status, body := probe(t, "/readyz")
+	if status != fiber.StatusServiceUnavailable || body.Status != "not_ready" {
+		t.Fatalf("before startup: got %d %q, want 503 not_ready", status, body.Status)
+	}
+
+	SetReady(true)
+	status, body = probe(t, "/readyz")
+	if status != fiber.StatusOK || body.Status != "ok" || body.Checks["db"].Status != "ok" {
+		t.Fatalf("ready: got %d %+v, want 200 ok", status, body)
+	}
+
+	healthy = false
+	status, body = probe(t, "/readyz")
+	if status != fiber.StatusServiceUnavailable || body.Status != "unavailable" {
+		t.Fatalf("failing check: got %d %q, want 503 unavailable", status, body.Status)
+	}
+	if got := body.Checks["db"]; got.Status != "fail" || got.Error != "connection refused" {
+		t.Fatalf("db check = %+v, want fail with error", got)
+	}
+}
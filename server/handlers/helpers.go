@@ -2,45 +2,57 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
-	"github.com/Subomi7/todoist-clone/server/db"
 	"github.com/Subomi7/todoist-clone/server/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/Subomi7/todoist-clone/server/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func GetInboxProjectID(ctx context.Context, userID primitive.ObjectID) (primitive.ObjectID, error) {
-    col := db.ProjectsCol()
-
-    var proj models.Project
-    err := col.FindOne(ctx, bson.M{
-        "userId": userID,
-        "name":    "Inbox",
-    }).Decode(&proj)
+    // the Inbox lives in the personal workspace; other workspaces may have their own "Inbox"
+    wsID, err := ensurePersonalWorkspace(ctx, userID)
+    if err != nil {
+        slog.ErrorContext(ctx, "GetInboxProjectID: failed to resolve personal workspace", "user_id", userID.Hex(), "err", err)
+        return primitive.NilObjectID, err
+    }
+    found, err := stores.Projects.FindByName(ctx, userID, wsID, "Inbox")
 
-    if err == mongo.ErrNoDocuments {
-        now := time.Now().UTC() 
-        proj = models.Project{
-            ID:        primitive.NewObjectID(),
-            UserID:    userID,
-            Name:      "Inbox",
-            CreatedAt: now,
-            UpdatedAt: now,
-        }
-        _, err := col.InsertOne(ctx, proj)
+    if err == store.ErrNotFound {
+        id, err := createInboxProject(ctx, userID)
         if err != nil {
-            log.Printf("[GetInboxProjectID] failed to create Inbox project for user %s: %v", userID.Hex(), err)
+            slog.ErrorContext(ctx, "GetInboxProjectID: failed to create Inbox project", "user_id", userID.Hex(), "err", err)
             return primitive.NilObjectID, err
         }
-        log.Printf("[GetInboxProjectID] created Inbox project for user %s with ID %s", userID.Hex(), proj.ID.Hex())
-        return proj.ID, nil
+        slog.InfoContext(ctx, "GetInboxProjectID: created Inbox project", "user_id", userID.Hex(), "project_id", id.Hex())
+        return id, nil
     } else if err != nil {
-        log.Printf("[GetInboxProjectID] failed to query Inbox for user %s: %v", userID.Hex(), err)
+        slog.ErrorContext(ctx, "GetInboxProjectID: failed to query Inbox", "user_id", userID.Hex(), "err", err)
+        return primitive.NilObjectID, err
+    }
+    return found.ID, nil
+}
+
+// createInboxProject creates the personal workspace and its system Inbox project
+// for a newly registered user, or for one whose Inbox is missing.
+func createInboxProject(ctx context.Context, userID primitive.ObjectID) (primitive.ObjectID, error) {
+    wsID, err := ensurePersonalWorkspace(ctx, userID)
+    if err != nil {
+        return primitive.NilObjectID, err
+    }
+    now := time.Now().UTC()
+    inbox := models.Project{
+        ID:          primitive.NewObjectID(),
+        UserID:      userID,
+        WorkspaceID: &wsID,
+        Name:        "Inbox",
+        IsSystem:    true,
+        CreatedAt:   now,
+        UpdatedAt:   now,
+    }
+    if err := stores.Projects.Create(ctx, &inbox); err != nil {
         return primitive.NilObjectID, err
     }
-        log.Printf("[GetInboxProjectID] found existing Inbox project for user %s with ID %s", userID.Hex(), proj.ID.Hex())
-    return proj.ID, nil
+    return inbox.ID, nil
 }
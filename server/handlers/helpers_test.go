@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/store"
+)
+
+func TestLazyInboxIsSystemProject(t *testing.T) {
+	UseStores(store.NewMemory())
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	id, err := GetInboxProjectID(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsID, err := ensurePersonalWorkspace(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inbox, err := stores.Projects.FindByName(ctx, userID, wsID, "Inbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inbox.ID != id || !inbox.IsSystem {
+		t.Fatalf("lazily created Inbox = %+v", inbox)
+	}
+	if again, err := GetInboxProjectID(ctx, userID); err != nil || again != id {
+		t.Fatalf("second lookup = %s, %v; want %s", again.Hex(), err, id.Hex())
+	}
+}
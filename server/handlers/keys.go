@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+)
+
+// JWT key configuration:
+//
+//	JWT_SIGNING_KEY        PEM private key (inline or file path) used to sign new tokens.
+//	                       RSA keys sign with RS256, Ed25519 keys with EdDSA.
+//	JWT_VERIFICATION_KEYS  comma-separated PEM public/private keys (inline or file paths)
+//	                       that are still accepted for verification, e.g. the previous
+//	                       signing key during a rotation.
+//	JWT_SECRET             legacy HS256 secret. Used for signing when JWT_SIGNING_KEY is
+//	                       unset; otherwise tokens without a kid signed with it are still
+//	                       accepted until they expire.
+//
+// Each asymmetric key is identified by its RFC 7638 thumbprint, sent as the "kid" header
+// and published at /.well-known/jwks.json.
+const minRSAKeyBits = 2048
+
+type jwtKey struct {
+	kid    string
+	method jwt.SigningMethod
+	signer crypto.Signer // nil for verification-only keys
+	public crypto.PublicKey
+}
+
+// KeySet holds the active signing key and every key accepted for verification.
+type KeySet struct {
+	active     *jwtKey
+	keys       map[string]*jwtKey
+	hmacSecret []byte
+}
+
+var keySet *KeySet
+
+// InitKeys loads the configured JWT key set. Must be called once at startup.
+func InitKeys(cfg config.JWTConfig) error {
+	ks, err := loadKeySet(cfg.SigningKey, cfg.VerificationKeys, cfg.Secret)
+	if err != nil {
+		return err
+	}
+	keySet = ks
+	return nil
+}
+
+func loadKeySet(signingKey, verificationKeys, secret string) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]*jwtKey{}}
+	if secret != "" {
+		ks.hmacSecret = []byte(secret)
+	}
+
+	if strings.TrimSpace(signingKey) != "" {
+		k, err := parseJWTKey(signingKey)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY: %w", err)
+		}
+		if k.signer == nil {
+			return nil, errors.New("JWT_SIGNING_KEY: expected a private key")
+		}
+		ks.active = k
+		ks.keys[k.kid] = k
+	} else if ks.hmacSecret == nil {
+		return nil, errors.New("JWT_SIGNING_KEY or JWT_SECRET must be set")
+	}
+
+	for _, v := range splitKeyList(verificationKeys) {
+		k, err := parseJWTKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_VERIFICATION_KEYS: %w", err)
+		}
+		k.signer = nil
+		if _, exists := ks.keys[k.kid]; !exists {
+			ks.keys[k.kid] = k
+		}
+	}
+	return ks, nil
+}
+
+// splitKeyList splits a comma-separated list of file paths or inline PEM blocks.
+func splitKeyList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseJWTKey parses a PEM encoded RSA or Ed25519 key given inline or as a file path.
+func parseJWTKey(src string) (*jwtKey, error) {
+	data := []byte(src)
+	if !strings.HasPrefix(strings.TrimSpace(src), "-----BEGIN") {
+		b, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	k := &jwtKey{}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.signer, k.public, k.method = key, &key.PublicKey, jwt.SigningMethodRS256
+	case *rsa.PublicKey:
+		k.public, k.method = key, jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		k.signer, k.public, k.method = key, key.Public(), jwt.SigningMethodEdDSA
+	case ed25519.PublicKey:
+		k.public, k.method = key, jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	if pub, ok := k.public.(*rsa.PublicKey); ok && pub.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+	}
+
+	k.kid, err = jwkThumbprint(k.public)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// sign signs claims with the active key, falling back to the legacy HS256 secret.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	if ks == nil {
+		return "", errors.New("jwt keys not initialised")
+	}
+	if ks.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+	}
+	token := jwt.NewWithClaims(ks.active.method, claims)
+	token.Header["kid"] = ks.active.kid
+	return token.SignedString(ks.active.signer)
+}
+
+// keyfunc selects the verification key by kid; tokens without a kid must be legacy HS256.
+func (ks *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	if ks == nil {
+		return nil, errors.New("jwt keys not initialised")
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || ks.hmacSecret == nil {
+			return nil, errors.New("unexpected signing method")
+		}
+		return ks.hmacSecret, nil
+	}
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return k.public, nil
+}
+
+// parseToken verifies signature, issuer and expiry of a token and returns its claims.
+func (ks *KeySet) parseToken(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, ks.keyfunc,
+		jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	return claims, err
+}
+
+// -------- JWKS ----------
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func publicJWK(k *jwtKey) JWK {
+	jwk := JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	}
+	return jwk
+}
+
+// jwkThumbprint computes the RFC 7638 SHA-256 thumbprint used as the key id.
+func jwkThumbprint(pub crypto.PublicKey) (string, error) {
+	var canonical []byte
+	var err error
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		// members must be in lexicographic order: e, kty, n
+		canonical, err = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{b64(big.NewInt(int64(p.E)).Bytes()), "RSA", b64(p.N.Bytes())})
+	case ed25519.PublicKey:
+		// crv, kty, x
+		canonical, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{"Ed25519", "OKP", b64(p)})
+	default:
+		return "", fmt.Errorf("unsupported key type %T", pub)
+	}
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return b64(sum[:]), nil
+}
+
+// JWKS publishes the public verification keys so other services can validate our tokens.
+func JWKS(c *fiber.Ctx) error {
+	set := JWKSet{Keys: []JWK{}}
+	if keySet != nil {
+		// active key first, then the remaining verification keys
+		if keySet.active != nil {
+			set.Keys = append(set.Keys, publicJWK(keySet.active))
+		}
+		kids := make([]string, 0, len(keySet.keys))
+		for kid := range keySet.keys {
+			if keySet.active == nil || kid != keySet.active.kid {
+				kids = append(kids, kid)
+			}
+		}
+		sort.Strings(kids)
+		for _, kid := range kids {
+			set.Keys = append(set.Keys, publicJWK(keySet.keys[kid]))
+		}
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(set)
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// privatePEM returns a PKCS #8 PEM block for key.
+func privatePEM(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// publicPEM returns a PKIX PEM block for the public half of key.
+func publicPEM(t *testing.T, pub interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func mustKeySet(t *testing.T, signingKey, verificationKeys, secret string) *KeySet {
+	t.Helper()
+	ks, err := loadKeySet(signingKey, verificationKeys, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": "u1", "iss": issuer, "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		key  interface{}
+		alg  string
+	}{
+		{"RSA", newRSAKey(t), "RS256"},
+		{"Ed25519", newEd25519Key(t), "EdDSA"},
+	}
+	for _, tt := range tests {
+		ks := mustKeySet(t, privatePEM(t, tt.key), "", "")
+		signed, err := ks.sign(testClaims())
+		if err != nil {
+			t.Fatalf("%s: sign: %v", tt.name, err)
+		}
+		tok, _, err := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Method.Alg() != tt.alg || tok.Header["kid"] != ks.active.kid {
+			t.Errorf("%s: header %v, want alg %s and kid %s", tt.name, tok.Header, tt.alg, ks.active.kid)
+		}
+		claims, err := ks.parseToken(signed)
+		if err != nil || claims["user_id"] != "u1" {
+			t.Errorf("%s: parseToken = %v, %v", tt.name, claims, err)
+		}
+	}
+}
+
+func TestVerificationKeyIsChosenByKid(t *testing.T) {
+	retired, current, stranger := newRSAKey(t), newEd25519Key(t), newRSAKey(t)
+	ks := mustKeySet(t, privatePEM(t, current), publicPEM(t, &retired.PublicKey), "")
+
+	// a token signed before the rotation still verifies while its key is listed
+	old, err := mustKeySet(t, privatePEM(t, retired), "", "").sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.parseToken(old); err != nil {
+		t.Errorf("token of a retired but listed key rejected: %v", err)
+	}
+	if _, err := mustKeySet(t, privatePEM(t, current), "", "").parseToken(old); err == nil {
+		t.Error("token of an unlisted key accepted")
+	}
+
+	unknown, err := mustKeySet(t, privatePEM(t, stranger), "", "").sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.parseToken(unknown); err == nil {
+		t.Error("token with an unknown kid accepted")
+	}
+
+	// the kid picks the key: a valid signature under another key does not count
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, testClaims())
+	forged.Header["kid"] = mustKeySet(t, privatePEM(t, retired), "", "").active.kid
+	signed, err := forged.SignedString(stranger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.parseToken(signed); err == nil {
+		t.Error("token signed by another key under a listed kid accepted")
+	}
+}
+
+func TestLegacySecretNeedsNoKid(t *testing.T) {
+	ks := mustKeySet(t, privatePEM(t, newEd25519Key(t)), "", "legacy")
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.parseToken(legacy); err != nil {
+		t.Errorf("legacy HS256 token rejected: %v", err)
+	}
+	noKid, err := jwt.NewWithClaims(jwt.SigningMethodRS256, testClaims()).SignedString(newRSAKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.parseToken(noKid); err == nil {
+		t.Error("RS256 token without a kid accepted")
+	}
+}
+
+// RFC 7638 section 3.1 and RFC 8037 appendix A.3.
+func TestJWKThumbprintVectors(t *testing.T) {
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		pub  interface{}
+		want string
+	}{
+		{"RSA", &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"},
+		{"Ed25519", ed25519.PublicKey(x), "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"},
+	}
+	for _, tt := range tests {
+		if got, err := jwkThumbprint(tt.pub); err != nil || got != tt.want {
+			t.Errorf("%s thumbprint = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestJWKSPublishesOnlyPublicKeys(t *testing.T) {
+	retired := newRSAKey(t)
+	ks := mustKeySet(t, privatePEM(t, newEd25519Key(t)), privatePEM(t, retired), "legacy")
+	saved := keySet
+	keySet = ks
+	t.Cleanup(func() { keySet = saved })
+
+	app := fiber.New()
+	app.Get("/jwks", JWKS)
+	resp, err := app.Test(httptest.NewRequest("GET", "/jwks", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 2 || set.Keys[0]["kid"] != ks.active.kid || set.Keys[0]["kty"] != "OKP" || set.Keys[1]["kty"] != "RSA" {
+		t.Fatalf("jwks = %s", body)
+	}
+	public := map[string]bool{"kty": true, "kid": true, "use": true, "alg": true, "n": true, "e": true, "crv": true, "x": true}
+	for _, k := range set.Keys {
+		for member := range k {
+			if !public[member] {
+				t.Errorf("key %s publishes %q", k["kid"], member)
+			}
+		}
+	}
+}
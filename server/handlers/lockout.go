@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/mailer"
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+// lockoutPolicy describes how failures for one kind of key are throttled:
+// the first BackoffAfter-1 failures are free, then every failure doubles the
+// wait starting at one second, and from LockAfter failures the key is locked
+// for LockFor. Failures older than Window are forgotten.
+type lockoutPolicy struct {
+	BackoffAfter int
+	LockAfter    int
+	LockFor      time.Duration
+	Window       time.Duration
+}
+
+var (
+	accountLockout = lockoutPolicy{BackoffAfter: 5, LockAfter: 10, LockFor: 15 * time.Minute, Window: time.Hour}
+	// IPs are shared behind NATs, so they get more room before throttling kicks in
+	ipLockout = lockoutPolicy{BackoffAfter: 20, LockAfter: 50, LockFor: 15 * time.Minute, Window: time.Hour}
+	// guessing the second factor after a correct password
+	mfaLockout = lockoutPolicy{BackoffAfter: 3, LockAfter: 10, LockFor: 15 * time.Minute, Window: time.Hour}
+)
+
+const loginAttemptRetention = 24 * time.Hour
+
+// sendMail delivers the unlock email; tests replace it to read the token.
+var sendMail = mailer.Send
+
+func accountLockKey(email string) string { return "email:" + email }
+func ipLockKey(ip string) string         { return "ip:" + ip }
+func mfaLockKey(userID string) string    { return "mfa:" + userID }
+
+// delay returns how long a key with the given number of failures must wait.
+func (p lockoutPolicy) delay(failures int) time.Duration {
+	switch {
+	case failures >= p.LockAfter:
+		return p.LockFor
+	case failures >= p.BackoffAfter:
+		d := time.Second << uint(failures-p.BackoffAfter)
+		if d > p.LockFor {
+			d = p.LockFor
+		}
+		return d
+	default:
+		return 0
+	}
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash is compared against when the user does not exist so that
+// unknown emails cost the same bcrypt work as wrong passwords.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		h, err := hashPassword("not-a-real-password-used-for-timing")
+		if err != nil {
+			slog.Error("failed to create dummy password hash", "err", err)
+		}
+		dummyHash = h
+	})
+	return dummyHash
+}
+
+// lockRemaining returns the longest remaining lock across keys (0 if none are locked).
+func lockRemaining(ctx context.Context, keys ...string) (time.Duration, error) {
+	attempts, err := stores.Attempts.Find(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	var wait time.Duration
+	for _, a := range attempts {
+		if d := a.LockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// recordFailure atomically counts a failure for key and applies the policy's
+// backoff. It returns the updated attempt.
+func recordFailure(ctx context.Context, key string, p lockoutPolicy) (*models.LoginAttempt, error) {
+	now := time.Now().UTC()
+	attempt, err := stores.Attempts.RecordFailure(ctx, key, now, p.Window, loginAttemptRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	if d := p.delay(attempt.Failures); d > 0 {
+		attempt.LockedUntil = now.Add(d)
+		if err := stores.Attempts.SetLockedUntil(ctx, key, attempt.LockedUntil); err != nil {
+			return nil, err
+		}
+	}
+	return attempt, nil
+}
+
+// clearFailures forgets failures for the given keys, e.g. after a successful login.
+func clearFailures(ctx context.Context, keys ...string) error {
+	return stores.Attempts.Delete(ctx, keys)
+}
+
+// tooManyAttempts writes the lockout response. It is identical for known and unknown accounts.
+func tooManyAttempts(c *fiber.Ctx, wait time.Duration) error {
+	secs := int(wait.Round(time.Second) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(secs))
+	return newError(fiber.StatusTooManyRequests, CodeTooManyAttempts, "too many failed attempts, try again later")
+}
+
+// recordLoginFailure counts a failed password attempt for the account and the client IP.
+// When the account becomes locked and belongs to a real user, an unlock link is emailed.
+func recordLoginFailure(ctx context.Context, email, ip string, user *models.User) {
+	if _, err := recordFailure(ctx, ipLockKey(ip), ipLockout); err != nil {
+		slog.ErrorContext(ctx, "failed to record login failure for ip", "err", err)
+	}
+	attempt, err := recordFailure(ctx, accountLockKey(email), accountLockout)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record login failure for account", "err", err)
+		return
+	}
+	if attempt.Failures == accountLockout.LockAfter && user != nil {
+		// send in the background so locking a real account takes as long as an unknown one
+		go sendUnlockEmail(context.WithoutCancel(ctx), user.Email)
+	}
+}
+
+// sendUnlockEmail runs after the request has been answered, so ctx carries the
+// request's values but not its deadline.
+func sendUnlockEmail(ctx context.Context, email string) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		slog.ErrorContext(ctx, "sendUnlockEmail: token generation failed", "err", err)
+		return
+	}
+	if err := stores.Attempts.SetUnlockToken(ctx, accountLockKey(email), hashToken(token)); err != nil {
+		slog.ErrorContext(ctx, "sendUnlockEmail: failed to store token", "err", err)
+		return
+	}
+
+	body := fmt.Sprintf("We locked your account for %d minutes after too many failed sign-in attempts.\n\n", int(accountLockout.LockFor.Minutes()))
+	if base := strings.TrimRight(conf.AppBaseURL, "/"); base != "" {
+		body += "If this was you, unlock it now: " + base + "/unlock?token=" + token + "\n"
+	} else {
+		body += "If this was you, unlock it now with this code: " + token + "\n"
+	}
+	body += "\nIf it wasn't you, consider changing your password once you are back in."
+
+	if err := sendMail(email, "Your account has been temporarily locked", body); err != nil {
+		slog.ErrorContext(ctx, "sendUnlockEmail: send failed", "email", email, "err", err)
+	}
+}
+
+// -------- Handlers ----------
+
+type UnlockRequest struct {
+	Token string `json:"token"`
+}
+
+// UnlockAccount clears an account lock using the token from the unlock email.
+func UnlockAccount(c *fiber.Ctx) error {
+	var req UnlockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+	if strings.TrimSpace(req.Token) == "" {
+		return errField("token", validate.Required, "token is required")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	unlocked, err := stores.Attempts.DeleteByUnlockToken(ctx, hashToken(strings.TrimSpace(req.Token)))
+	if err != nil {
+		return errInternal("database error", err)
+	}
+	if !unlocked {
+		return newError(fiber.StatusBadRequest, CodeInvalidToken, "invalid or expired unlock token")
+	}
+	return c.JSON(fiber.Map{"message": "account unlocked"})
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+)
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Second},
+		{6, 2 * time.Second},
+		{9, 16 * time.Second},
+		{10, 15 * time.Minute},
+		{40, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := accountLockout.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// newLoginApp serves Login and UnlockAccount on a fresh in-memory store with
+// one password user, alice@example.com / "correct horse".
+func newLoginApp(t *testing.T) *fiber.App {
+	t.Helper()
+	if err := InitKeys(config.JWTConfig{Secret: "test-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	UseStores(store.NewMemory())
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{ID: primitive.NewObjectID(), Email: "alice@example.com", PasswordHash: hash, CreatedAt: time.Now()}
+	if err := stores.Users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/login", Login)
+	app.Post("/unlock", UnlockAccount)
+	return app
+}
+
+func login(t *testing.T, app *fiber.App, email, password string) (int, []byte, string) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	resp, raw := doJSON(t, app, "POST", "/login", "", string(body))
+	return resp.StatusCode, raw, resp.Header.Get(fiber.HeaderRetryAfter)
+}
+
+// waitOut clears the current lock of key as if its backoff had passed.
+func waitOut(t *testing.T, key string) {
+	t.Helper()
+	if err := stores.Attempts.SetLockedUntil(context.Background(), key, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoginBackoffLockAndUnlock(t *testing.T) {
+	app := newLoginApp(t)
+	mails := make(chan string, 1)
+	saved := sendMail
+	sendMail = func(to, subject, body string) error {
+		mails <- body
+		return nil
+	}
+	t.Cleanup(func() { sendMail = saved })
+	key := accountLockKey("alice@example.com")
+
+	for i := 1; i <= 4; i++ {
+		if status, _, _ := login(t, app, "alice@example.com", "wrong"); status != fiber.StatusUnauthorized {
+			t.Fatalf("failure %d: status %d", i, status)
+		}
+	}
+	// the fifth failure starts the backoff, even for the right password
+	login(t, app, "alice@example.com", "wrong")
+	if status, _, retry := login(t, app, "alice@example.com", "correct horse"); status != fiber.StatusTooManyRequests || retry != "1" {
+		t.Fatalf("after 5 failures: status %d, Retry-After %q", status, retry)
+	}
+
+	for i := 6; i <= 10; i++ {
+		waitOut(t, key)
+		if status, _, _ := login(t, app, "alice@example.com", "wrong"); status != fiber.StatusUnauthorized {
+			t.Fatalf("failure %d: status %d", i, status)
+		}
+	}
+	status, raw, retry := login(t, app, "alice@example.com", "correct horse")
+	if status != fiber.StatusTooManyRequests || retry != "900" {
+		t.Fatalf("after 10 failures: status %d, Retry-After %q", status, retry)
+	}
+	var p Problem
+	if json.Unmarshal(raw, &p) != nil || p.Code != CodeTooManyAttempts {
+		t.Fatalf("lockout body %s", raw)
+	}
+
+	var mail string
+	select {
+	case mail = <-mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no unlock email")
+	}
+	_, token, ok := strings.Cut(mail, "unlock it now with this code: ")
+	if !ok {
+		t.Fatalf("unlock email without a code: %q", mail)
+	}
+	token = strings.TrimSpace(strings.SplitN(token, "\n", 2)[0])
+
+	resp, _ := doJSON(t, app, "POST", "/unlock", "", `{"token":"not-the-token"}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("unlock with a wrong token: status %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, app, "POST", "/unlock", "", `{"token":"`+token+`"}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("unlock: status %d", resp.StatusCode)
+	}
+	if status, _, _ := login(t, app, "alice@example.com", "correct horse"); status != fiber.StatusOK {
+		t.Fatalf("login after unlock: status %d", status)
+	}
+	resp, _ = doJSON(t, app, "POST", "/unlock", "", `{"token":"`+token+`"}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("unlock token reused: status %d", resp.StatusCode)
+	}
+}
+
+func TestUnknownEmailLooksLikeWrongPassword(t *testing.T) {
+	app := newLoginApp(t)
+	var compared []string
+	saved := checkPasswordHash
+	checkPasswordHash = func(hash, password string) error {
+		compared = append(compared, hash)
+		return saved(hash, password)
+	}
+	t.Cleanup(func() { checkPasswordHash = saved })
+
+	wrongStatus, wrongBody, _ := login(t, app, "alice@example.com", "wrong")
+	unknownStatus, unknownBody, _ := login(t, app, "nobody@example.com", "wrong")
+	if wrongStatus != fiber.StatusUnauthorized || unknownStatus != wrongStatus || !bytes.Equal(unknownBody, wrongBody) {
+		t.Fatalf("wrong password: %d %s; unknown email: %d %s", wrongStatus, wrongBody, unknownStatus, unknownBody)
+	}
+	// the unknown email still pays for one bcrypt comparison
+	if len(compared) != 2 || compared[1] != dummyPasswordHash() {
+		t.Fatalf("compared hashes %q; want the dummy hash last", compared)
+	}
+}
+
+func TestReauthFailuresShareLoginLockout(t *testing.T) {
+	app := newLoginApp(t)
+	app.Delete("/account", JWTMiddleware(), DeleteAccount)
+	status, raw, _ := login(t, app, "alice@example.com", "correct horse")
+	var tok TokenResponse
+	if status != fiber.StatusOK || json.Unmarshal(raw, &tok) != nil {
+		t.Fatalf("login: status %d body %s", status, raw)
+	}
+
+	// a stolen session cannot guess the password past the account lockout
+	for i := 1; i <= 5; i++ {
+		if resp, _ := doJSON(t, app, "DELETE", "/account", tok.AccessToken, `{"password":"wrong"}`); resp.StatusCode != fiber.StatusUnauthorized {
+			t.Fatalf("failure %d: status %d", i, resp.StatusCode)
+		}
+	}
+	if resp, _ := doJSON(t, app, "DELETE", "/account", tok.AccessToken, `{"password":"correct horse"}`); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("re-check after 5 failures: status %d", resp.StatusCode)
+	}
+	if status, _, _ := login(t, app, "alice@example.com", "correct horse"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("login after 5 failed re-checks: status %d", status)
+	}
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/models"
+)
+
+const recoveryCodeCount = 10
+
+// -------- DTOs ----------
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type MFADisableRequest struct {
+	Password     string `json:"password,omitempty"` // not needed by SSO-only users
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// -------- helpers ----------
+
+// generateRecoveryCodes returns plain codes (shown to the user once) and their hashes (stored).
+func generateRecoveryCodes(n int) ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	plain := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(enc.EncodeToString(b)[:10])
+		plain = append(plain, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return plain, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// consumeTOTP validates a TOTP code and records its time step so it cannot be reused.
+func consumeTOTP(ctx context.Context, user *models.User, code string) (bool, error) {
+	step, ok := validateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return false, nil
+	}
+	// the store only records a step later than the last one, so two concurrent
+	// requests cannot both use the same code
+	return stores.Users.ConsumeTOTPStep(ctx, user.ID, step)
+}
+
+// consumeRecoveryCode removes a matching recovery code; each code works exactly once.
+func consumeRecoveryCode(ctx context.Context, user *models.User, code string) (bool, error) {
+	return stores.Users.ConsumeRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+}
+
+// checkSecondFactor accepts either a TOTP code or a recovery code.
+func checkSecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) (bool, error) {
+	if strings.TrimSpace(code) != "" {
+		return consumeTOTP(ctx, user, code)
+	}
+	if strings.TrimSpace(recoveryCode) != "" {
+		return consumeRecoveryCode(ctx, user, recoveryCode)
+	}
+	return false, nil
+}
+
+// -------- Handlers ----------
+
+// EnrollTOTP generates a new TOTP secret for the authenticated user. 2FA is not
+// enforced until the secret is confirmed with ConfirmTOTP.
+func EnrollTOTP(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	user, err := findUserByID(ctx, userID)
+	if err != nil {
+		return errNotFound("user")
+	}
+	if user.TOTPEnabled {
+		return newError(fiber.StatusConflict, CodeMFAEnabled, "two-factor authentication already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return errInternal("could not generate secret", err)
+	}
+
+	if err := stores.Users.StartTOTP(ctx, userID, secret); err != nil {
+		return errInternal("database error", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(secret, user.Email),
+	})
+}
+
+// ConfirmTOTP enables 2FA once the user proves their authenticator produces valid
+// codes, and returns the one-time recovery codes.
+func ConfirmTOTP(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	user, err := findUserByID(ctx, userID)
+	if err != nil {
+		return errNotFound("user")
+	}
+	if user.TOTPEnabled {
+		return newError(fiber.StatusConflict, CodeMFAEnabled, "two-factor authentication already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return newError(fiber.StatusBadRequest, CodeMFANotEnabled, "no pending two-factor enrollment")
+	}
+
+	step, ok := validateTOTP(user.TOTPSecret, req.Code, time.Now(), 0)
+	if !ok {
+		return newError(fiber.StatusBadRequest, CodeInvalidCode, "invalid code")
+	}
+
+	plain, hashes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return errInternal("could not generate recovery codes", err)
+	}
+
+	enabled, err := stores.Users.EnableTOTP(ctx, userID, user.TOTPSecret, step, hashes)
+	if err != nil {
+		return errInternal("database error", err)
+	}
+	if !enabled {
+		return newError(fiber.StatusConflict, CodeConflict, "enrollment changed, please retry")
+	}
+
+	// existing sessions were established with a single factor
+	if err := revokeAllRefreshTokensForUser(ctx, userID); err != nil {
+		slog.ErrorContext(c.UserContext(), "ConfirmTOTP: failed to revoke sessions", "err", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(RecoveryCodesResponse{RecoveryCodes: plain})
+}
+
+// VerifyMFA exchanges the MFA challenge token from Login plus a TOTP or recovery
+// code for real access and refresh tokens.
+func VerifyMFA(c *fiber.Ctx) error {
+	var req MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return newError(fiber.StatusBadRequest, CodeValidationFailed, "mfa_token and code or recovery_code required")
+	}
+
+	userID, err := parseMFAToken(req.MFAToken)
+	if err != nil {
+		return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid or expired mfa token")
+	}
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	user, err := findUserByID(ctx, userID)
+	if err != nil || !user.TOTPEnabled {
+		return newError(fiber.StatusUnauthorized, CodeInvalidToken, "invalid or expired mfa token")
+	}
+	if user.Disabled {
+		return errAccountDisabled
+	}
+
+	lockKey := mfaLockKey(user.ID.Hex())
+	if wait, err := lockRemaining(ctx, lockKey); err != nil {
+		slog.ErrorContext(c.UserContext(), "VerifyMFA: lockout check failed", "err", err)
+	} else if wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	ok, err := checkSecondFactor(ctx, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		return errInternal("database error", err)
+	}
+	if !ok {
+		if _, err := recordFailure(ctx, lockKey, mfaLockout); err != nil {
+			slog.ErrorContext(c.UserContext(), "VerifyMFA: failed to record failure", "err", err)
+		}
+		return newError(fiber.StatusUnauthorized, CodeInvalidCode, "invalid code")
+	}
+	if err := clearFailures(ctx, lockKey); err != nil {
+		slog.ErrorContext(c.UserContext(), "VerifyMFA: failed to clear failures", "err", err)
+	}
+
+	return issueTokens(c, user)
+}
+
+// DisableTOTP turns 2FA off. Requires the password (users without one only give
+// the code) and a current code so a stolen access token alone cannot remove the
+// second factor.
+func DisableTOTP(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	var req MFADisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	user, err := findUserByID(ctx, userID)
+	if err != nil {
+		return errNotFound("user")
+	}
+	if !user.TOTPEnabled {
+		return newError(fiber.StatusBadRequest, CodeMFANotEnabled, "two-factor authentication is not enabled")
+	}
+	// SSO-only users have no password; the code below is their proof
+	if err := reauthenticate(c, ctx, user, req.Password); err != nil {
+		return err
+	}
+
+	ok, err := checkSecondFactor(ctx, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		return errInternal("database error", err)
+	}
+	if !ok {
+		return newError(fiber.StatusUnauthorized, CodeInvalidCode, "invalid code")
+	}
+
+	if err := stores.Users.DisableTOTP(ctx, userID); err != nil {
+		return errInternal("database error", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "two-factor authentication disabled"})
+}
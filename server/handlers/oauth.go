@@ -0,0 +1,668 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+// OAuth2 authorization server (RFC 6749) for third-party apps: authorization
+// code grant with mandatory PKCE (RFC 7636), refresh token rotation, revocation
+// (RFC 7009) and introspection (RFC 7662). Access tokens are opaque, stored
+// hashed, and carry the same scopes as personal access tokens.
+const (
+	OAuthAccessTokenTTL  = time.Hour
+	OAuthRefreshTokenTTL = 30 * 24 * time.Hour
+	oauthCodeTTL         = time.Minute
+
+	oauthAccessPrefix  = "tdo_"
+	oauthRefreshPrefix = "tdr_"
+	oauthClientPrefix  = "tdcl_"
+
+	maxOAuthClients      = 20
+	maxOAuthRedirectURIs = 10
+)
+
+// -------- DTOs ----------
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+}
+
+type OAuthClientResponse struct {
+	Data         *models.OAuthClient `json:"data"`
+	ClientSecret string              `json:"client_secret,omitempty"` // only returned on creation
+}
+
+// AuthorizeRequest holds the authorization request parameters (query on GET, body on POST).
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" query:"response_type"`
+	ClientID            string `json:"client_id" query:"client_id"`
+	RedirectURI         string `json:"redirect_uri" query:"redirect_uri"`
+	Scope               string `json:"scope" query:"scope"`
+	State               string `json:"state" query:"state"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
+
+// ConsentResponse is what the consent screen needs to render.
+type ConsentResponse struct {
+	Client      ConsentClient `json:"client"`
+	Scopes      []string      `json:"scopes"`
+	RedirectURI string        `json:"redirect_uri"`
+	State       string        `json:"state,omitempty"`
+	Consented   bool          `json:"consented"` // the user already granted these scopes
+}
+
+type ConsentClient struct {
+	ClientID string `json:"client_id"`
+	Name     string `json:"name"`
+}
+
+type AuthorizeResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// TokenRequest is the token endpoint body (application/x-www-form-urlencoded per RFC 6749).
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" json:"grant_type"`
+	Code         string `form:"code" json:"code"`
+	RedirectURI  string `form:"redirect_uri" json:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier" json:"code_verifier"`
+	RefreshToken string `form:"refresh_token" json:"refresh_token"`
+	Scope        string `form:"scope" json:"scope"` // optional narrowing on refresh
+	ClientID     string `form:"client_id" json:"client_id"`
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+	Token        string `form:"token" json:"token"` // revoke / introspect
+}
+
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// OAuthErrorResponse is the error body of the token, revocation and
+// introspection endpoints (RFC 6749 section 5.2), which OAuth client libraries
+// parse instead of problem+json.
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+}
+
+// -------- helpers ----------
+
+// oauthError writes an RFC 6749 section 5.2 error response.
+func oauthError(c *fiber.Ctx, status int, code, description string) error {
+	return c.Status(status).JSON(OAuthErrorResponse{Error: code, ErrorDescription: description})
+}
+
+// validRedirectURI requires https, except for loopback addresses used by native apps and development.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Fragment != "" || u.Host == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return false
+}
+
+// parseScopes splits a space-delimited scope string and checks it against allowed.
+func parseScopes(scope string, allowed []string) ([]string, bool) {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !validScopes[s] || !hasScope(allowed, s) {
+			return nil, false
+		}
+		if !hasScope(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, len(scopes) > 0
+}
+
+func pkceS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func findOAuthClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	return stores.OAuth.FindClient(ctx, clientID)
+}
+
+// validateAuthorizeRequest checks client, redirect URI, scopes and PKCE parameters
+// and returns the client and the requested scopes.
+func validateAuthorizeRequest(ctx context.Context, req *AuthorizeRequest) (*models.OAuthClient, []string, error) {
+	client, err := findOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, errField("client_id", validate.Invalid, "unknown client_id")
+		}
+		return nil, nil, errInternal("database error", err)
+	}
+	// redirect URIs must match a registered one exactly
+	registered := false
+	for _, u := range client.RedirectURIs {
+		if u == req.RedirectURI {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return nil, nil, errField("redirect_uri", validate.Invalid, "redirect_uri is not registered for this client")
+	}
+	if req.ResponseType != "code" {
+		return nil, nil, errField("response_type", validate.Invalid, `unsupported response_type; only "code" is supported`)
+	}
+	scopes, ok := parseScopes(req.Scope, client.Scopes)
+	if !ok {
+		return nil, nil, errField("scope", validate.Invalid, "invalid scope")
+	}
+	if req.CodeChallengeMethod != "S256" || len(req.CodeChallenge) < 43 {
+		return nil, nil, errField("code_challenge", validate.Invalid, "PKCE with code_challenge_method=S256 is required")
+	}
+	return client, scopes, nil
+}
+
+// authenticateOAuthClient checks client credentials from HTTP Basic auth or the
+// request body. Public clients only need to identify themselves.
+func authenticateOAuthClient(ctx context.Context, c *fiber.Ctx, req *TokenRequest) (*models.OAuthClient, error) {
+	clientID, secret := req.ClientID, req.ClientSecret
+	if id, s, ok := basicAuth(c); ok {
+		clientID, secret = id, s
+	}
+	if clientID == "" {
+		return nil, errors.New("client authentication required")
+	}
+	client, err := findOAuthClient(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("unknown client")
+	}
+	if client.Confidential {
+		if secret == "" || subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) != 1 {
+			return nil, errors.New("invalid client credentials")
+		}
+	}
+	return client, nil
+}
+
+func basicAuth(c *fiber.Ctx) (string, string, bool) {
+	auth := c.Get(fiber.HeaderAuthorization)
+	if !strings.HasPrefix(auth, "Basic ") {
+		return "", "", false
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+	if err != nil {
+		return "", "", false
+	}
+	id, secret, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return "", "", false
+	}
+	// RFC 6749 2.3.1: credentials are form-urlencoded before base64
+	if v, err := url.QueryUnescape(id); err == nil {
+		id = v
+	}
+	if v, err := url.QueryUnescape(secret); err == nil {
+		secret = v
+	}
+	return id, secret, true
+}
+
+// issueOAuthTokens stores a new access/refresh token pair for grant and writes the token response.
+func issueOAuthTokens(ctx context.Context, c *fiber.Ctx, grantID primitive.ObjectID, clientID string, userID primitive.ObjectID, scopes []string) error {
+	access, err := generateRandomToken(32)
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "could not create token")
+	}
+	refresh, err := generateRandomToken(32)
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "could not create token")
+	}
+	access, refresh = oauthAccessPrefix+access, oauthRefreshPrefix+refresh
+
+	now := time.Now().UTC()
+	err = stores.OAuth.SaveTokens(ctx,
+		&models.OAuthToken{TokenHash: hashToken(access), Kind: "access", GrantID: grantID, ClientID: clientID, UserID: userID, Scopes: scopes, CreatedAt: now, ExpiresAt: now.Add(OAuthAccessTokenTTL)},
+		&models.OAuthToken{TokenHash: hashToken(refresh), Kind: "refresh", GrantID: grantID, ClientID: clientID, UserID: userID, Scopes: scopes, CreatedAt: now, ExpiresAt: now.Add(OAuthRefreshTokenTTL)},
+	)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "issueOAuthTokens: insert failed", "err", err)
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "could not save token")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderPragma, "no-cache")
+	return c.JSON(OAuthTokenResponse{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(OAuthAccessTokenTTL / time.Second),
+		RefreshToken: refresh,
+		Scope:        strings.Join(scopes, " "),
+	})
+}
+
+// authenticateOAuthAccessToken resolves an OAuth access token for JWTMiddleware.
+func authenticateOAuthAccessToken(ctx context.Context, tokenPlain string) (*models.OAuthToken, error) {
+	tok, err := stores.OAuth.FindToken(ctx, hashToken(tokenPlain), "access")
+	if err != nil {
+		return nil, err
+	}
+	if tok.ExpiresAt.Before(time.Now()) {
+		return nil, store.ErrNotFound
+	}
+	// as with personal tokens, disabling the account stops its grants
+	if err := grantUserActive(ctx, tok.UserID); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
+
+// revokeOAuthClientsOwnedBy deletes a user's registered clients along with every
+// code, token and consent issued to them.
+func revokeOAuthClientsOwnedBy(ctx context.Context, ownerID primitive.ObjectID) error {
+	return stores.OAuth.DeleteClientsOwnedBy(ctx, ownerID)
+}
+
+// -------- Client registration ----------
+
+// CreateOAuthClient registers a third-party application owned by the authenticated user.
+func CreateOAuthClient(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	var req CreateOAuthClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxNameLength {
+		return errField("name", validate.Invalid, "name is required and must be at most 100 characters")
+	}
+	if len(req.RedirectURIs) == 0 || len(req.RedirectURIs) > maxOAuthRedirectURIs {
+		return errField("redirect_uris", validate.Invalid, "between 1 and 10 redirect_uris are required")
+	}
+	for _, u := range req.RedirectURIs {
+		if !validRedirectURI(u) {
+			return errField("redirect_uris", validate.Invalid, "invalid redirect_uri (https required except for localhost): "+u)
+		}
+	}
+	scopes, ok := parseScopes(strings.Join(req.Scopes, " "), req.Scopes)
+	if !ok {
+		return errField("scopes", validate.Invalid, "invalid scopes")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	count, err := stores.OAuth.CountClients(ctx, userID)
+	if err != nil {
+		return errInternal("database error", err)
+	}
+	if count >= maxOAuthClients {
+		return newError(fiber.StatusConflict, CodeLimitReached, "too many oauth clients")
+	}
+
+	id, err := generateRandomToken(16)
+	if err != nil {
+		return errInternal("could not create client", err)
+	}
+	client := models.OAuthClient{
+		ID:           primitive.NewObjectID(),
+		ClientID:     oauthClientPrefix + id,
+		Name:         name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       scopes,
+		Confidential: req.Confidential,
+		OwnerID:      userID,
+		CreatedAt:    time.Now().UTC(),
+	}
+	var secret string
+	if req.Confidential {
+		if secret, err = generateRandomToken(32); err != nil {
+			return errInternal("could not create client", err)
+		}
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := stores.OAuth.CreateClient(ctx, &client); err != nil {
+		return errInternal("could not save client", err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(OAuthClientResponse{Data: &client, ClientSecret: secret})
+}
+
+// ListOAuthClients returns the clients registered by the authenticated user.
+func ListOAuthClients(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	clients, err := stores.OAuth.ListClients(ctx, userID)
+	if err != nil {
+		return errInternal("failed to fetch clients", err)
+	}
+	return c.JSON(fiber.Map{"data": clients})
+}
+
+// DeleteOAuthClient removes a client and invalidates everything issued to it.
+func DeleteOAuthClient(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	clientID := c.Params("clientId")
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	deleted, err := stores.OAuth.DeleteClient(ctx, clientID, userID)
+	if err != nil {
+		return errInternal("failed to delete client", err)
+	}
+	if !deleted {
+		return errNotFound("client")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// -------- Authorization / consent ----------
+
+// GetAuthorization validates an authorization request and returns what the consent
+// screen should show. The SPA calls this with the user's session token.
+func GetAuthorization(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	var req AuthorizeRequest
+	if err := c.QueryParser(&req); err != nil {
+		return newError(fiber.StatusBadRequest, CodeInvalidQuery, "invalid query")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	client, scopes, err := validateAuthorizeRequest(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	consented := false
+	if consent, err := stores.OAuth.FindConsent(ctx, userID, client.ClientID); err == nil {
+		consented = true
+		for _, s := range scopes {
+			if !hasScope(consent.Scopes, s) {
+				consented = false
+			}
+		}
+	}
+
+	return c.JSON(ConsentResponse{
+		Client:      ConsentClient{ClientID: client.ClientID, Name: client.Name},
+		Scopes:      scopes,
+		RedirectURI: req.RedirectURI,
+		State:       req.State,
+		Consented:   consented,
+	})
+}
+
+// Authorize records the user's consent decision and returns where to redirect the
+// browser: the client's redirect URI with either a code or error=access_denied.
+func Authorize(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	var req AuthorizeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	client, scopes, err := validateAuthorizeRequest(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	redirect, _ := url.Parse(req.RedirectURI)
+	q := redirect.Query()
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		q.Set("error", "access_denied")
+		redirect.RawQuery = q.Encode()
+		return c.JSON(AuthorizeResponse{RedirectTo: redirect.String()})
+	}
+
+	code, err := generateRandomToken(32)
+	if err != nil {
+		return errInternal("could not create code", err)
+	}
+	now := time.Now().UTC()
+	err = stores.OAuth.CreateCode(ctx, &models.OAuthAuthCode{
+		CodeHash:      hashToken(code),
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(oauthCodeTTL),
+	})
+	if err != nil {
+		return errInternal("could not save code", err)
+	}
+
+	if err := stores.OAuth.AddConsent(ctx, userID, client.ClientID, scopes, now); err != nil {
+		slog.ErrorContext(c.UserContext(), "Authorize: failed to record consent", "err", err)
+	}
+
+	q.Set("code", code)
+	redirect.RawQuery = q.Encode()
+	return c.JSON(AuthorizeResponse{RedirectTo: redirect.String()})
+}
+
+// -------- Token, revocation, introspection ----------
+
+// OAuthTokenEndpoint implements the token endpoint for the authorization_code and refresh_token grants.
+func OAuthTokenEndpoint(c *fiber.Ctx) error {
+	var req TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "invalid body")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	client, err := authenticateOAuthClient(ctx, c, &req)
+	if err != nil {
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_client", err.Error())
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		if req.Code == "" || req.CodeVerifier == "" {
+			return oauthError(c, fiber.StatusBadRequest, "invalid_request", "code and code_verifier are required")
+		}
+		// codes are single use; a second attempt revokes what the first one was
+		// issued, as the code has evidently leaked (RFC 6749 section 4.1.2)
+		grantID := primitive.NewObjectID()
+		code, err := stores.OAuth.RedeemCode(ctx, hashToken(req.Code), grantID, time.Now().UTC())
+		if err == nil && code.UsedAt != nil {
+			if err := stores.OAuth.DeleteGrant(ctx, code.GrantID, ""); err != nil {
+				slog.ErrorContext(c.UserContext(), "OAuthToken: failed to revoke grant of a reused code", "err", err)
+			}
+			return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "invalid or expired authorization code")
+		}
+		if err != nil || code.ExpiresAt.Before(time.Now()) || code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+			return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "invalid or expired authorization code")
+		}
+		if subtle.ConstantTimeCompare([]byte(pkceS256(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+			return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "code_verifier does not match code_challenge")
+		}
+		if err := grantUserActive(ctx, code.UserID); err != nil {
+			return oauthGrantUserError(c, err)
+		}
+		return issueOAuthTokens(ctx, c, grantID, client.ClientID, code.UserID, code.Scopes)
+
+	case "refresh_token":
+		if req.RefreshToken == "" {
+			return oauthError(c, fiber.StatusBadRequest, "invalid_request", "refresh_token is required")
+		}
+		// rotate: the presented refresh token is consumed
+		old, err := stores.OAuth.TakeToken(ctx, hashToken(req.RefreshToken), "refresh")
+		if err != nil || old.ExpiresAt.Before(time.Now()) || old.ClientID != client.ClientID {
+			return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "invalid or expired refresh token")
+		}
+		if err := grantUserActive(ctx, old.UserID); err != nil {
+			return oauthGrantUserError(c, err)
+		}
+		// a refresh may narrow the grant but never widen it (RFC 6749 section 6)
+		scopes := old.Scopes
+		if req.Scope != "" {
+			var ok bool
+			if scopes, ok = parseScopes(req.Scope, old.Scopes); !ok {
+				return oauthError(c, fiber.StatusBadRequest, "invalid_scope", "scope exceeds the original grant")
+			}
+		}
+		// access tokens of the previous rotation stop working as well
+		if err := stores.OAuth.DeleteGrant(ctx, old.GrantID, "access"); err != nil {
+			slog.ErrorContext(c.UserContext(), "OAuthToken: failed to revoke previous access tokens", "err", err)
+		}
+		return issueOAuthTokens(ctx, c, old.GrantID, client.ClientID, old.UserID, scopes)
+	}
+
+	return oauthError(c, fiber.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+}
+
+// grantUserActive reports store.ErrNotFound when the user behind a grant was
+// deleted or disabled after the grant was issued.
+func grantUserActive(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := stores.Users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Disabled {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func oauthGrantUserError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "the account behind this grant is disabled")
+	}
+	slog.ErrorContext(c.UserContext(), "OAuthToken: failed to load user", "err", err)
+	return oauthError(c, fiber.StatusInternalServerError, "server_error", "could not load user")
+}
+
+// OAuthRevoke implements RFC 7009. Revoking a refresh token revokes the whole grant.
+func OAuthRevoke(c *fiber.Ctx) error {
+	var req TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "invalid body")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	client, err := authenticateOAuthClient(ctx, c, &req)
+	if err != nil {
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_client", err.Error())
+	}
+
+	tok, err := stores.OAuth.FindToken(ctx, hashToken(req.Token), "")
+	switch {
+	case err == nil && tok.ClientID == client.ClientID:
+		if tok.Kind == "refresh" {
+			err = stores.OAuth.DeleteGrant(ctx, tok.GrantID, "")
+		} else {
+			err = stores.OAuth.DeleteToken(ctx, tok.ID)
+		}
+		if err != nil {
+			return oauthError(c, fiber.StatusServiceUnavailable, "temporarily_unavailable", "could not revoke token")
+		}
+	case err != nil && !errors.Is(err, store.ErrNotFound):
+		return oauthError(c, fiber.StatusServiceUnavailable, "temporarily_unavailable", "could not revoke token")
+	}
+
+	// unknown tokens are not an error (RFC 7009 section 2.2)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// OAuthIntrospect implements RFC 7662 for confidential clients, limited to their own tokens.
+func OAuthIntrospect(c *fiber.Ctx) error {
+	var req TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "invalid body")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	client, err := authenticateOAuthClient(ctx, c, &req)
+	if err != nil || !client.Confidential {
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_client", "confidential client authentication required")
+	}
+
+	tok, err := stores.OAuth.FindToken(ctx, hashToken(req.Token), "")
+	if err != nil || tok.ClientID != client.ClientID || tok.ExpiresAt.Before(time.Now()) {
+		return c.JSON(IntrospectionResponse{Active: false})
+	}
+	tokenType := "access_token"
+	if tok.Kind == "refresh" {
+		tokenType = "refresh_token"
+	}
+	return c.JSON(IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(tok.Scopes, " "),
+		ClientID:  tok.ClientID,
+		Sub:       tok.UserID.Hex(),
+		TokenType: tokenType,
+		Exp:       tok.ExpiresAt.Unix(),
+		Iat:       tok.CreatedAt.Unix(),
+	})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+)
+
+// A disabled account keeps its OAuth documents until an administrator revokes
+// them; none of them may work in the meantime.
+func TestOAuthGrantsOfDisabledUser(t *testing.T) {
+	UseStores(store.NewMemory())
+	ctx := context.Background()
+	now := time.Now().UTC()
+	user := &models.User{ID: primitive.NewObjectID(), Email: "alice@example.com", CreatedAt: now}
+	if err := stores.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	client := &models.OAuthClient{ClientID: "sync", Name: "Sync", RedirectURIs: []string{"http://127.0.0.1/cb"}, Scopes: []string{"read"}, OwnerID: user.ID, CreatedAt: now}
+	if err := stores.OAuth.CreateClient(ctx, client); err != nil {
+		t.Fatal(err)
+	}
+	grantID := primitive.NewObjectID()
+	if err := stores.OAuth.SaveTokens(ctx,
+		&models.OAuthToken{TokenHash: hashToken("tdo_access"), Kind: "access", GrantID: grantID, ClientID: "sync", UserID: user.ID, Scopes: []string{"read"}, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		&models.OAuthToken{TokenHash: hashToken("tdr_refresh"), Kind: "refresh", GrantID: grantID, ClientID: "sync", UserID: user.ID, Scopes: []string{"read"}, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticateOAuthAccessToken(ctx, "tdo_access"); err != nil {
+		t.Fatalf("access token of an active user: %v", err)
+	}
+
+	if err := stores.Users.SetDisabled(ctx, user.ID, true, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticateOAuthAccessToken(ctx, "tdo_access"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("access token of a disabled user: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/token", OAuthTokenEndpoint)
+	form := url.Values{"grant_type": {"refresh_token"}, "client_id": {"sync"}, "refresh_token": {"tdr_refresh"}}
+	req := httptest.NewRequest("POST", "/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("refresh of a disabled user: status %d", resp.StatusCode)
+	}
+}
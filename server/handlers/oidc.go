@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+)
+
+// OpenID Connect providers are configured through the environment (or the oidc
+// section of the config file):
+//
+//	OIDC_PROVIDERS               comma-separated provider names, e.g. "google,corp"
+//	OIDC_<NAME>_ISSUER           issuer URL used for discovery
+//	OIDC_<NAME>_CLIENT_ID
+//	OIDC_<NAME>_CLIENT_SECRET    optional for public clients (PKCE is always used)
+//	OIDC_<NAME>_REDIRECT_URL     must point at /api/auth/oidc/<name>/callback
+//	OIDC_<NAME>_SCOPES           optional, defaults to "openid email profile"
+const oidcStateTTL = 10 * time.Minute
+
+type oidcProvider struct {
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+
+	// discovery happens on first use so a provider outage does not block startup
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// oidcIdentity is the verified subset of ID token claims used for sign-in.
+type oidcIdentity struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Nonce         string `json:"nonce"`
+}
+
+var oidcProviders = map[string]*oidcProvider{}
+
+// InitOIDC registers the configured OIDC providers. Must be called once at startup.
+func InitOIDC(cfg map[string]config.OIDCProviderConfig) error {
+	providers := map[string]*oidcProvider{}
+	for name, pc := range cfg {
+		p := &oidcProvider{
+			name:         name,
+			issuer:       pc.Issuer,
+			clientID:     pc.ClientID,
+			clientSecret: pc.ClientSecret,
+			redirectURL:  pc.RedirectURL,
+			scopes:       pc.Scopes,
+		}
+		if p.issuer == "" || p.clientID == "" || p.redirectURL == "" {
+			return fmt.Errorf("oidc provider %q: ISSUER, CLIENT_ID and REDIRECT_URL are required", name)
+		}
+		if len(p.scopes) == 0 {
+			p.scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+		}
+		providers[name] = p
+	}
+	oidcProviders = providers
+	return nil
+}
+
+// discover fetches the provider metadata and signing keys on first use.
+func (p *oidcProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc discovery for %s: %w", p.name, err)
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.clientID,
+		ClientSecret: p.clientSecret,
+		RedirectURL:  p.redirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.clientID})
+	return p.oauth, p.verifier, nil
+}
+
+// authCodeURL builds the authorization request with a PKCE S256 challenge.
+func (p *oidcProvider) authCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	cfg, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cfg.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+// exchange redeems an authorization code and returns the verified identity.
+func (p *oidcProvider) exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidcIdentity, error) {
+	cfg, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := cfg.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange: %w", err)
+	}
+	rawIDToken, ok := tok.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id token: %w", err)
+	}
+
+	var ident oidcIdentity
+	if err := idToken.Claims(&ident); err != nil {
+		return nil, fmt.Errorf("id token claims: %w", err)
+	}
+	if ident.Nonce != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+	ident.Subject = idToken.Subject
+	ident.Email = strings.TrimSpace(strings.ToLower(ident.Email))
+	return &ident, nil
+}
+
+// findOrLinkOIDCUser resolves the local user for an external identity: an already
+// linked user, else an existing user with the same verified email (which gets the
+// identity linked), else a newly created password-less user.
+func findOrLinkOIDCUser(ctx context.Context, provider string, ident *oidcIdentity) (*models.User, error) {
+	user, err := stores.Users.FindByIdentity(ctx, provider, ident.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	// linking by email is only safe when the provider vouches for the address
+	if ident.Email == "" || !ident.EmailVerified {
+		return nil, errOIDCEmailNotVerified
+	}
+
+	identity := models.ExternalIdentity{
+		Provider: provider,
+		Subject:  ident.Subject,
+		Email:    ident.Email,
+		LinkedAt: time.Now().UTC(),
+	}
+
+	user, err = stores.Users.LinkIdentity(ctx, ident.Email, identity)
+	if err == nil {
+		slog.InfoContext(ctx, "OIDC: linked identity to existing user", "provider", provider, "user_id", user.ID.Hex())
+		return user, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	user = &models.User{
+		ID:         primitive.NewObjectID(),
+		Email:      ident.Email,
+		CreatedAt:  time.Now(),
+		Identities: []models.ExternalIdentity{identity},
+	}
+	if err := stores.Users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	if _, err := createInboxProject(ctx, user.ID); err != nil {
+		slog.ErrorContext(ctx, "OIDC: failed to create Inbox", "user_id", user.ID.Hex(), "err", err)
+	}
+	slog.InfoContext(ctx, "OIDC: created user", "provider", provider, "user_id", user.ID.Hex())
+	return user, nil
+}
+
+var errOIDCEmailNotVerified = errors.New("identity provider did not return a verified email")
+
+// -------- Handlers ----------
+
+// ListOIDCProviders returns the names of the configured identity providers.
+func ListOIDCProviders(c *fiber.Ctx) error {
+	names := make([]string, 0, len(oidcProviders))
+	for name := range oidcProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return c.JSON(fiber.Map{"data": names})
+}
+
+// OIDCLogin starts the authorization code + PKCE flow and redirects to the provider.
+func OIDCLogin(c *fiber.Ctx) error {
+	p, ok := oidcProviders[c.Params("provider")]
+	if !ok {
+		return errNotFound("identity provider")
+	}
+
+	state, err := generateRandomToken(32)
+	if err != nil {
+		return errInternal("could not start login", err)
+	}
+	nonce, err := generateRandomToken(32)
+	if err != nil {
+		return errInternal("could not start login", err)
+	}
+	codeVerifier := oauth2.GenerateVerifier()
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	authURL, err := p.authCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return &Error{Status: fiber.StatusBadGateway, Code: CodeProviderDown, Detail: "identity provider unavailable", Cause: err}
+	}
+
+	now := time.Now().UTC()
+	err = stores.OIDCStates.Save(ctx, &models.OIDCState{
+		ID:           primitive.NewObjectID(),
+		StateHash:    hashToken(state),
+		Provider:     p.name,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(oidcStateTTL),
+	})
+	if err != nil {
+		return errInternal("could not start login", err)
+	}
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback completes the flow: it redeems the code, verifies the ID token and
+// signs the linked user in exactly like Login does.
+func OIDCCallback(c *fiber.Ctx) error {
+	p, ok := oidcProviders[c.Params("provider")]
+	if !ok {
+		return errNotFound("identity provider")
+	}
+	if e := c.Query("error"); e != "" {
+		return newError(fiber.StatusUnauthorized, CodeSSOFailed, "login was not completed at the identity provider")
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		return newError(fiber.StatusBadRequest, CodeValidationFailed, "code and state required")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	// state is single use
+	st, err := stores.OIDCStates.Take(ctx, hashToken(state), p.name)
+	if err != nil || st.ExpiresAt.Before(time.Now()) {
+		return newError(fiber.StatusBadRequest, CodeSSOFailed, "invalid or expired login state")
+	}
+
+	ident, err := p.exchange(ctx, code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		slog.WarnContext(c.UserContext(), "OIDCCallback: exchange failed", "provider", p.name, "err", err)
+		return newError(fiber.StatusUnauthorized, CodeSSOFailed, "could not verify identity")
+	}
+
+	user, err := findOrLinkOIDCUser(ctx, p.name, ident)
+	if err != nil {
+		if errors.Is(err, errOIDCEmailNotVerified) {
+			return newError(fiber.StatusForbidden, CodeEmailUnverified, err.Error())
+		}
+		return errInternal("database error", err)
+	}
+
+	return completeLogin(c, user)
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is a minimal OpenID Connect provider: discovery, JWKS, an authorize
+// endpoint that immediately redirects back with a code, and a token endpoint
+// that enforces PKCE.
+type mockIdP struct {
+	*httptest.Server
+	key *jwtKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+	clientID  string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := parseJWTKey(string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rk)})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockIdP{key: key, codes: map[string]mockGrant{}, Subject: "user-123", Email: "Alice@Example.com", EmailVerified: true}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JWKSet{Keys: []JWK{publicJWK(m.key)}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			http.Error(w, "pkce required", http.StatusBadRequest)
+			return
+		}
+		code, _ := generateRandomToken(16)
+		m.mu.Lock()
+		m.codes[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), clientID: q.Get("client_id")}
+		m.mu.Unlock()
+		redirect, _ := url.Parse(q.Get("redirect_uri"))
+		rq := redirect.Query()
+		rq.Set("code", code)
+		rq.Set("state", q.Get("state"))
+		redirect.RawQuery = rq.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		m.mu.Lock()
+		grant, ok := m.codes[r.Form.Get("code")]
+		delete(m.codes, r.Form.Get("code"))
+		m.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            m.URL,
+			"sub":            m.Subject,
+			"aud":            grant.clientID,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          grant.nonce,
+			"email":          m.Email,
+			"email_verified": m.EmailVerified,
+		})
+		tok.Header["kid"] = m.key.kid
+		idToken, _ := tok.SignedString(m.key.signer)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize follows the provider's authorize redirect and returns code and state.
+func (m *mockIdP) authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func newTestProvider(m *mockIdP) *oidcProvider {
+	return &oidcProvider{
+		name:        "mock",
+		issuer:      m.URL,
+		clientID:    "todoist-clone",
+		redirectURL: "http://localhost:8080/api/auth/oidc/mock/callback",
+		scopes:      []string{"openid", "email"},
+	}
+}
+
+func TestOIDCCodeFlowWithPKCE(t *testing.T) {
+	m := newMockIdP(t)
+	p := newTestProvider(m)
+	ctx := context.Background()
+
+	verifier := "test-verifier-0123456789-0123456789-0123456789"
+	authURL, err := p.authCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := m.authorize(t, authURL)
+	if state != "state-1" {
+		t.Fatalf("state = %q", state)
+	}
+
+	ident, err := p.exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ident.Subject != "user-123" || ident.Email != "alice@example.com" || !ident.EmailVerified {
+		t.Fatalf("unexpected identity %+v", ident)
+	}
+}
+
+func TestOIDCRejectsWrongVerifierAndNonce(t *testing.T) {
+	m := newMockIdP(t)
+	p := newTestProvider(m)
+	ctx := context.Background()
+
+	authURL, _ := p.authCodeURL(ctx, "s", "nonce-1", "right-verifier-0123456789-0123456789-012345")
+	code, _ := m.authorize(t, authURL)
+	if _, err := p.exchange(ctx, code, "wrong-verifier-0123456789-0123456789-012345", "nonce-1"); err == nil {
+		t.Error("exchange succeeded with wrong PKCE verifier")
+	}
+
+	authURL, _ = p.authCodeURL(ctx, "s", "nonce-1", "right-verifier-0123456789-0123456789-012345")
+	code, _ = m.authorize(t, authURL)
+	if _, err := p.exchange(ctx, code, "right-verifier-0123456789-0123456789-012345", "other-nonce"); err == nil {
+		t.Error("exchange succeeded with mismatched nonce")
+	}
+}
+
+func TestOIDCRejectsForeignAudience(t *testing.T) {
+	m := newMockIdP(t)
+	p := newTestProvider(m)
+	ctx := context.Background()
+
+	verifier := "test-verifier-0123456789-0123456789-0123456789"
+	authURL, _ := p.authCodeURL(ctx, "s", "n", verifier)
+	code, _ := m.authorize(t, authURL)
+
+	// same provider, different client: the ID token audience will not match
+	other := newTestProvider(m)
+	other.clientID = "someone-else"
+	if _, err := other.exchange(ctx, code, verifier, "n"); err == nil {
+		t.Error("ID token for another client accepted")
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/store"
+)
+
+// maxPageSize caps pageSize, so one request cannot ask for a whole collection.
+const maxPageSize = 100
+
+// PaginationMeta describes one page of a listing. Page is only set when paging
+// by number and Total only when counted; NextCursor is empty on the last page.
+type PaginationMeta struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"pageSize"`
+	Total      *int64 `json:"total,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// listWindow is the paging part of a list query. Without a cursor parameter a
+// listing pages by number (?page=&pageSize=) and counts the total, as it always
+// has. With ?cursor= it resumes after the cursor (empty for the first page)
+// and counts the total only when asked with ?total=true. Either way every page
+// but the last reports the cursor of the next one in meta.nextCursor.
+type listWindow struct {
+	Page     int
+	PageSize int
+	Cursor   string
+	ByCursor bool
+	Total    bool
+}
+
+// parseWindow reads ?page=&pageSize=&cursor=&total= for a listing. Page sizes
+// above maxPageSize are cut down to it.
+func parseWindow(c *fiber.Ctx, defaultPageSize int) listWindow {
+	w := listWindow{Page: 1, PageSize: defaultPageSize}
+	if v, err := strconv.Atoi(c.Query("page")); err == nil && v > 0 {
+		w.Page = v
+	}
+	if v, err := strconv.Atoi(c.Query("pageSize")); err == nil && v > 0 {
+		w.PageSize = min(v, maxPageSize)
+	}
+	w.ByCursor = c.Context().QueryArgs().Has("cursor")
+	w.Cursor = c.Query("cursor")
+	w.Total = !w.ByCursor
+	if v := c.Query("total"); v != "" {
+		w.Total = v == "true" || v == "1"
+	}
+	return w
+}
+
+// limit fetches one item more than a page, to tell whether another page follows.
+func (w listWindow) limit() int64 {
+	return int64(w.PageSize) + 1
+}
+
+// skip is the offset of a numbered page.
+func (w listWindow) skip() int64 {
+	if w.ByCursor {
+		return 0
+	}
+	return int64((w.Page - 1) * w.PageSize)
+}
+
+// meta describes the page; total is reported only when it was counted.
+func (w listWindow) meta(total int64) PaginationMeta {
+	m := PaginationMeta{PageSize: w.PageSize}
+	if !w.ByCursor {
+		m.Page = w.Page
+	}
+	if w.Total {
+		m.Total = &total
+	}
+	return m
+}
+
+// trimPage drops the extra item fetched by limit and reports whether it was there.
+func trimPage[T any](items []T, pageSize int) ([]T, bool) {
+	if len(items) > pageSize {
+		return items[:pageSize], true
+	}
+	return items, false
+}
+
+var errInvalidCursor = newError(fiber.StatusBadRequest, CodeInvalidQuery, "invalid cursor")
+
+// pageCursor is the content of an opaque cursor. The sort it was issued for is
+// kept so it cannot be replayed against a listing in another order, and each
+// value's type is kept so it compares like the stored field.
+type pageCursor struct {
+	Sort   string        `json:"s"`
+	Values []cursorValue `json:"v"`
+	ID     string        `json:"id"`
+}
+
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v,omitempty"`
+}
+
+// encodeCursor makes the opaque token that resumes a listing in order sort after c.
+func encodeCursor(sort store.Sort, c store.Cursor) string {
+	pc := pageCursor{Sort: sort.String(), ID: c.ID.Hex()}
+	for _, v := range c.Values {
+		cv := cursorValue{Type: "null"}
+		switch v := v.(type) {
+		case time.Time:
+			cv = cursorValue{"time", v.UTC().Format(time.RFC3339Nano)}
+		case int64:
+			cv = cursorValue{"int", strconv.FormatInt(v, 10)}
+		case string:
+			cv = cursorValue{"string", v}
+		case bool:
+			cv = cursorValue{"bool", strconv.FormatBool(v)}
+		}
+		pc.Values = append(pc.Values, cv)
+	}
+	b, _ := json.Marshal(pc)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a token from encodeCursor for a listing in order sort.
+// The empty token starts from the beginning.
+func decodeCursor(token string, sort store.Sort) (*store.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var pc pageCursor
+	if err := json.Unmarshal(raw, &pc); err != nil || pc.Sort != sort.String() || len(pc.Values) != len(sort) {
+		return nil, errInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(pc.ID)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	c := &store.Cursor{Values: make([]any, len(pc.Values)), ID: id}
+	for i, cv := range pc.Values {
+		switch cv.Type {
+		case "null":
+		case "time":
+			c.Values[i], err = time.Parse(time.RFC3339Nano, cv.Value)
+		case "int":
+			c.Values[i], err = strconv.ParseInt(cv.Value, 10, 64)
+		case "string":
+			c.Values[i] = cv.Value
+		case "bool":
+			c.Values[i], err = strconv.ParseBool(cv.Value)
+		default:
+			return nil, errInvalidCursor
+		}
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+	}
+	return c, nil
+}
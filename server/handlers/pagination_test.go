@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/store"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	order, err := store.ParseTaskSort("createdAt,priority,title,completed,-dueDate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &store.Cursor{ID: id, Values: []any{
+		time.Date(2025, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		int64(3),
+		"café, ünïcode & \"quotes\"",
+		true,
+		nil,
+	}}
+	got, err := decodeCursor(encodeCursor(order, *want), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+
+	byTitle := store.Sort{{Field: "title"}}
+	if c, err := decodeCursor("", byTitle); c != nil || err != nil {
+		t.Errorf("empty cursor = %v, %v; want the first page", c, err)
+	}
+	token := encodeCursor(byTitle, store.Cursor{Values: []any{"a"}, ID: id})
+	short := encodeCursor(byTitle, store.Cursor{ID: id})
+	for _, bad := range []string{"%%%", "bm90IGpzb24", token + "x", short} {
+		if _, err := decodeCursor(bad, byTitle); err != errInvalidCursor {
+			t.Errorf("decodeCursor(%q) = %v, want invalid cursor", bad, err)
+		}
+	}
+	if _, err := decodeCursor(token, store.Sort{{Field: "title", Desc: true}}); err != errInvalidCursor {
+		t.Errorf("cursor replayed with another sort: %v", err)
+	}
+}
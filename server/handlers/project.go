@@ -3,27 +3,23 @@ package handlers
 import (
 	"context"
 	"errors"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/Subomi7/todoist-clone/server/db"
+	"github.com/Subomi7/todoist-clone/server/metrics"
 	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-const projectDBtimeout = 8 * time.Second
-
 // DTO
 type CreateProjectDTO struct {
-	Name string `json:"name"`
+	Name string `json:"name" validate:"required,max=100"`
 }
 
 type UpdateProjectDTO struct {
-	Name string `json:"name"`
+	Name string `json:"name" validate:"required,max=100"`
 }
 
 type ProjectResponse struct {
@@ -31,8 +27,8 @@ type ProjectResponse struct {
 }
 
 type ProjectsListResponse struct {
-	Data []*models.Project `json:"data"`
-	Meta models.PaginationMeta `json:"meta"`
+	Data []*models.ProjectWithCount `json:"data"`
+	Meta PaginationMeta             `json:"meta"`
 }
 
 
@@ -61,268 +57,217 @@ func getUserID(c *fiber.Ctx) (primitive.ObjectID, error) {
 func CreateProject(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return errUnauthorized
 	}	
 
 	var dto CreateProjectDTO
-	if err := c.BodyParser(&dto); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error":"invalid body"})
+	if err := parseBody(c, &dto); err != nil {
+		return err
 	}
 	name := strings.TrimSpace(dto.Name)
-	if name == "" {
-		return  c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error":"name is required"})
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	// projects created here live in the personal workspace
+	wsID, err := ensurePersonalWorkspace(ctx, userID)
+	if err != nil {
+		return errInternal("could not resolve workspace", err)
 	}
 
 	now := time.Now().UTC()
 	proj := models.Project{
-		ID:        primitive.NewObjectID(),
-		UserID:    userID,
-		Name:      name,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		WorkspaceID: &wsID,
+		Name:        name,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), projectDBtimeout)
-	defer cancel()
-
-	col := db.ProjectsCol()
-	_, err = col.InsertOne(ctx, proj)
-	if err != nil {
-		if we, ok := err.(mongo.WriteException); ok {
-			for _, e := range we.WriteErrors {
-				if e.Code == 11000 {
-					return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error":"project with this name already exists"})
-				}
-			}
+	if err := stores.Projects.Create(ctx, &proj); err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			return newError(fiber.StatusConflict, CodeProjectExists, "project with this name already exists")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error":"failed to create project"})
+		return errInternal("failed to create project", err)
 	}
+	metrics.ProjectEvents.WithLabelValues("created").Inc()
 	return c.Status(fiber.StatusCreated).JSON(ProjectResponse{Data: &proj})
 }
 
-func GetProjects(c *fiber.Ctx) error {
-	userID, err := getUserID(c)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
-	}
-
-	page := 1
-	pageSize := 10
-	if p := c.Query("page", ""); p != "" {
-		if v, err := strconv.Atoi(p); err == nil && v > 0 {
-			page = v
-		}
-	}
-	if ps := c.Query("pageSize", ""); ps != "" {
-		if v, err := strconv.Atoi(ps); err == nil && v > 0 {
-			pageSize = v
+// projectPageSize is the default page size of project listings.
+const projectPageSize = 10
+
+// listProjects writes one page of the projects matching filter, newest first,
+// by page number or after w's cursor.
+func listProjects(c *fiber.Ctx, ctx context.Context, filter store.ProjectFilter, w listWindow) error {
+	page := store.ProjectPage{Skip: w.skip(), Limit: w.limit(), Count: w.Total}
+	if w.ByCursor {
+		after, err := decodeCursor(w.Cursor, store.ProjectOrder)
+		if err != nil {
+			return err
 		}
+		page.After = after
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), projectDBtimeout)
-	defer cancel()
-
-	col := db.ProjectsCol()
-
-	// Build aggregation pipeline
-	pipeline := mongo.Pipeline{
-		// Only fetch projects belonging to this user
-		{{Key: "$match", Value: bson.M{"userId": userID}}},
-		// Lookup tasks that belong to each project (excluding completed)
-		{
-			{Key: "$lookup", Value: bson.M{
-				"from": "tasks",
-				"let": bson.M{"projId": "$_id"},
-				"pipeline": bson.A{
-					bson.M{
-						"$match": bson.M{
-							"$expr": bson.M{"$and": bson.A{
-								bson.M{"$eq": bson.A{"$projectId", "$$projId"}},
-								bson.M{"$eq": bson.A{"$userId", userID}},
-								bson.M{"$eq": bson.A{"$completed", false}},
-							}},
-						},
-					},
-				},
-				"as": "projectTasks",
-			}},
-		},
-		// Add a taskCount field
-		{{Key: "$addFields", Value: bson.M{"taskCount": bson.M{"$size": "$projectTasks"}}}},
-		// Remove the full tasks array
-		{{Key: "$project", Value: bson.M{"projectTasks": 0}}},
-		// Sort and paginate
-		{{Key: "$sort", Value: bson.M{"created_at": -1}}},
-		{{Key: "$skip", Value: (page - 1) * pageSize}},
-		{{Key: "$limit", Value: pageSize}},
-	}
-
-	cur, err := col.Aggregate(ctx, pipeline)
+	projects, total, err := stores.Projects.List(ctx, filter, page)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to aggregate projects"})
-	}
-	defer cur.Close(ctx)
-
-	// Define a custom struct to hold project + taskCount
-	type ProjectWithCount struct {
-		ID          primitive.ObjectID `bson:"_id" json:"id"`
-		UserID      primitive.ObjectID `bson:"userId" json:"userId"`
-		Name        string             `bson:"name" json:"name"`
-		Description string             `bson:"description" json:"description"`
-		IsSystem    bool               `bson:"is_system" json:"is_system"`
-		CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-		UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
-		TaskCount   int                `bson:"taskCount" json:"taskCount"`
+		return errInternal("failed to aggregate projects", err)
 	}
 
-	var projects []*ProjectWithCount
-	if err := cur.All(ctx, &projects); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to decode aggregated projects"})
+	projects, more := trimPage(projects, w.PageSize)
+	meta := w.meta(total)
+	if more {
+		meta.NextCursor = encodeCursor(store.ProjectOrder, store.ProjectCursor(projects[len(projects)-1].Project))
 	}
+	return c.JSON(ProjectsListResponse{Data: projects, Meta: meta})
+}
 
-	// Count total (without pagination)
-	total, err := col.CountDocuments(ctx, bson.M{"userId": userID})
+// GetProjects lists the user's own projects with open task counts.
+// supports ?page=&pageSize=&cursor=&total=
+func GetProjects(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to count projects"})
+		return errUnauthorized
 	}
 
-	meta := models.PaginationMeta{Page: page, PageSize: pageSize, Total: int(total)}
+	w := parseWindow(c, projectPageSize)
 
-	return c.JSON(fiber.Map{
-		"data": projects,
-		"meta": meta,
-	})
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	// Only fetch projects belonging to this user
+	return listProjects(c, ctx, store.ProjectFilter{UserID: &userID}, w)
 }
 
 
-// GetProject - fetch a single project by id (ensures ownership)
+// GetProject - fetch a single project by id (owned, or in one of the user's workspaces)
 func GetProject(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return errUnauthorized
 	}
 	idParam := c.Params("id")
 	objID, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project id"})
+		return errInvalidID("project")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), projectDBtimeout)
+	ctx, cancel := requestContext(c)
 	defer cancel()
-	col := db.ProjectsCol()
 
-	var proj models.Project
-	if err := col.FindOne(ctx, bson.M{"_id": objID, "userId": userID}).Decode(&proj); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	access, err := accessibleBy(ctx, userID)
+	if err != nil {
+		return errInternal("failed to fetch project", err)
+	}
+
+	proj, err := stores.Projects.Get(ctx, objID, access)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return errNotFound("project")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to fetch project"})
+		return errInternal("failed to fetch project", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(ProjectResponse{Data: &proj})
+	return c.Status(fiber.StatusOK).JSON(ProjectResponse{Data: proj})
 }
 
 // UpdateProject - rename a project (ensures ownership and unique name)
 func UpdateProject(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return errUnauthorized
 	}
 	idParam := c.Params("id")
 	objID, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project id"})
+		return errInvalidID("project")
 	}
 
 	var dto UpdateProjectDTO
-	if err := c.BodyParser(&dto); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	if err := parseBody(c, &dto); err != nil {
+		return err
 	}
 	name := strings.TrimSpace(dto.Name)
-	if name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
-	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), projectDBtimeout)
+	ctx, cancel := requestContext(c)
 	defer cancel()
-	col := db.ProjectsCol()
 
-	res, err := col.UpdateOne(ctx, bson.M{"_id": objID, "userId": userID}, bson.M{"$set": bson.M{"name": name, "updatedAt": time.Now().UTC()}})
+	matched, err := stores.Projects.Rename(ctx, objID, userID, name, time.Now().UTC())
 	if err != nil {
 		// duplicate name?
-		if we, ok := err.(mongo.WriteException); ok {
-			for _, e := range we.WriteErrors {
-				if e.Code == 11000 {
-					return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "project with that name already exists"})
-				}
-			}
+		if errors.Is(err, store.ErrDuplicate) {
+			return newError(fiber.StatusConflict, CodeProjectExists, "project with this name already exists")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update project"})
+		return errInternal("failed to update project", err)
 	}
-	if res.MatchedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	if !matched {
+		return errNotFound("project")
 	}
 
 	// return updated project
-	var proj models.Project
-	if err := col.FindOne(ctx, bson.M{"_id": objID, "userId": userID}).Decode(&proj); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to fetch updated project"})
+	proj, err := stores.Projects.Get(ctx, objID, store.Owner(userID))
+	if err != nil {
+		return errInternal("failed to fetch updated project", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(ProjectResponse{Data: &proj})
+	return c.Status(fiber.StatusOK).JSON(ProjectResponse{Data: proj})
 }
 
 // DeleteProject - reassign tasks to Inbox (NilObjectID) and delete the project
 func DeleteProject(c *fiber.Ctx) error {
     userID, err := getUserID(c)
     if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+        return errUnauthorized
     }
     idParam := c.Params("id")
     objID, err := primitive.ObjectIDFromHex(idParam)
     if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project id"})
+        return errInvalidID("project")
     }
 
-    ctx, cancel := context.WithTimeout(context.Background(), projectDBtimeout)
+    ctx, cancel := requestContext(c)
     defer cancel()
 
-    pcol := db.ProjectsCol()
-
     // ensure project exists & belongs to user
-    var proj models.Project
-    if err := pcol.FindOne(ctx, bson.M{"_id": objID, "userId": userID}).Decode(&proj); err != nil {
-        if err == mongo.ErrNoDocuments {
-            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+    proj, err := stores.Projects.Get(ctx, objID, store.Owner(userID))
+    if err != nil {
+        if errors.Is(err, store.ErrNotFound) {
+            return errNotFound("project")
         }
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to verify project"})
+        return errInternal("failed to verify project", err)
     }
 
     // Block deleting "Inbox"
     if strings.EqualFold(proj.Name, "Inbox") {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot delete Inbox project"})
+        return newError(fiber.StatusBadRequest, CodeInboxProtected, "cannot delete Inbox project")
     }
 
-    // Find the user's Inbox project ID
-    inboxID, err := GetInboxProjectID(ctx, userID)
+    // Reassign tasks from this project to their owners' Inbox; in a shared workspace
+    // project that includes other members' tasks
+    owners, err := stores.Tasks.OwnersInProject(ctx, objID)
     if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not resolve inbox"})
+        return errInternal("failed to reassign tasks", err)
     }
-
-    // Reassign tasks from this project to Inbox
-    tcol := db.TasksCol()
-    if _, err := tcol.UpdateMany(
-        ctx,
-        bson.M{"projectId": objID, "userId": userID},
-        bson.M{"$set": bson.M{"projectId": inboxID, "updatedAt": time.Now().UTC()}},
-    ); err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to reassign tasks"})
+    for _, ownerID := range owners {
+        inboxID, err := GetInboxProjectID(ctx, ownerID)
+        if err != nil {
+            return errInternal("could not resolve inbox", err)
+        }
+        wsID, err := ensurePersonalWorkspace(ctx, ownerID)
+        if err != nil {
+            return errInternal("could not resolve inbox", err)
+        }
+        to := store.TaskMove{ProjectID: inboxID, WorkspaceID: &wsID}
+        if err := stores.Tasks.MoveProjectTasks(ctx, objID, ownerID, to, time.Now().UTC()); err != nil {
+            return errInternal("failed to reassign tasks", err)
+        }
     }
 
     // Delete the project
-    if _, err := pcol.DeleteOne(ctx, bson.M{"_id": objID, "userId": userID}); err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete project"})
+    if err := stores.Projects.Delete(ctx, objID, userID); err != nil {
+        return errInternal("failed to delete project", err)
     }
+    metrics.ProjectEvents.WithLabelValues("deleted").Inc()
 
     return c.SendStatus(fiber.StatusNoContent)
 }
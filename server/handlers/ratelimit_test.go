@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+	"github.com/Subomi7/todoist-clone/server/store"
+)
+
+type failingRateLimits struct{}
+
+func (failingRateLimits) Hit(context.Context, string, time.Time, time.Duration) (int64, error) {
+	return 0, errors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	savedConf, savedStores := conf, stores
+	t.Cleanup(func() { conf, stores = savedConf, savedStores })
+	conf = config.Default()
+	conf.RateLimit.Auth = config.RateLimitPolicy{Limit: 2, Window: time.Hour}
+	conf.RateLimit.Read = config.RateLimitPolicy{Limit: 3, Window: time.Hour}
+	conf.RateLimit.Write = config.RateLimitPolicy{Limit: 1, Window: time.Hour}
+	stores = store.NewMemory()
+
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) }
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/login", RateLimitAuth(), ok)
+	// stands in for JWTMiddleware
+	signedIn := func(c *fiber.Ctx) error {
+		if u := c.Get("X-User"); u != "" {
+			c.Locals("user_id", u)
+		}
+		return c.Next()
+	}
+	app.Get("/tasks", signedIn, RateLimit(), ok)
+	app.Post("/tasks", signedIn, RateLimit(), ok)
+
+	send := func(method, path, user string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for i := 1; i <= 2; i++ {
+		r := send("POST", "/login", "")
+		if r.StatusCode != fiber.StatusNoContent || r.Header.Get(HeaderRateLimitRemaining) != strconv.Itoa(2-i) {
+			t.Fatalf("login %d: status %d remaining %q", i, r.StatusCode, r.Header.Get(HeaderRateLimitRemaining))
+		}
+	}
+	r := send("POST", "/login", "")
+	if r.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("third login: status %d, want 429", r.StatusCode)
+	}
+	if retry, _ := strconv.Atoi(r.Header.Get(fiber.HeaderRetryAfter)); retry < 1 || retry > 3600 {
+		t.Errorf("Retry-After = %q", r.Header.Get(fiber.HeaderRetryAfter))
+	}
+	if got := r.Header.Get(HeaderRateLimitPolicy); got != "2;w=3600" {
+		t.Errorf("RateLimit-Policy = %q", got)
+	}
+	if got := r.Header.Get(fiber.HeaderContentType); got != MIMEProblemJSON {
+		t.Errorf("content type %q", got)
+	}
+
+	// reads and writes have separate quotas
+	if r := send("POST", "/tasks", "alice"); r.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("first write: %d", r.StatusCode)
+	}
+	if r := send("POST", "/tasks", "alice"); r.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("second write: %d, want 429", r.StatusCode)
+	}
+	if r := send("GET", "/tasks", "alice"); r.StatusCode != fiber.StatusNoContent || r.Header.Get(HeaderRateLimitLimit) != "3" {
+		t.Fatalf("read after writes: %d limit %q", r.StatusCode, r.Header.Get(HeaderRateLimitLimit))
+	}
+	// users sharing an IP do not share a quota
+	if r := send("POST", "/tasks", "bob"); r.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("other user's write: %d", r.StatusCode)
+	}
+	// without a user the IP is the key
+	if r := send("POST", "/tasks", ""); r.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("anonymous write: %d", r.StatusCode)
+	}
+
+	// a failing store lets requests through
+	stores.RateLimits = failingRateLimits{}
+	if r := send("POST", "/login", ""); r.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("with a failing store: %d", r.StatusCode)
+	}
+
+	conf.RateLimit.Store = "off"
+	stores = store.NewMemory()
+	for i := 0; i < 5; i++ {
+		if r := send("POST", "/tasks", "alice"); r.StatusCode != fiber.StatusNoContent || r.Header.Get(HeaderRateLimitLimit) != "" {
+			t.Fatalf("disabled limiter: %d", r.StatusCode)
+		}
+	}
+}
+
+func TestMemoryRateLimitsWindows(t *testing.T) {
+	s := store.NewMemoryRateLimits()
+	ctx := context.Background()
+	start := time.Now().Truncate(time.Minute)
+	for want := int64(1); want <= 3; want++ {
+		if n, _ := s.Hit(ctx, "k", start, time.Minute); n != want {
+			t.Fatalf("hit = %d, want %d", n, want)
+		}
+	}
+	if n, _ := s.Hit(ctx, "k", start.Add(time.Minute), time.Minute); n != 1 {
+		t.Fatalf("next window starts at %d, want 1", n)
+	}
+}
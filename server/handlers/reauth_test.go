@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+)
+
+// newSSOApp serves the OIDC login and the routes that need re-authentication,
+// backed by a fresh in-memory store and the mock provider.
+func newSSOApp(t *testing.T, m *mockIdP) *fiber.App {
+	t.Helper()
+	if err := InitKeys(config.JWTConfig{Secret: "test-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	UseStores(store.NewMemory())
+	saved := oidcProviders
+	oidcProviders = map[string]*oidcProvider{"mock": newTestProvider(m)}
+	t.Cleanup(func() { oidcProviders = saved })
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/oidc/:provider/login", OIDCLogin)
+	app.Get("/oidc/:provider/callback", OIDCCallback)
+	app.Delete("/account", JWTMiddleware(), DeleteAccount)
+	app.Post("/mfa/disable", JWTMiddleware(), DisableTOTP)
+	return app
+}
+
+func doJSON(t *testing.T, app *fiber.App, method, path, token, body string) (*http.Response, []byte) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp, raw
+}
+
+// ssoLogin signs in through the mock provider and returns the new user and
+// their access token.
+func ssoLogin(t *testing.T, app *fiber.App, m *mockIdP) (*models.User, string) {
+	t.Helper()
+	resp, _ := doJSON(t, app, "GET", "/oidc/mock/login", "", "")
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("login: status %d", resp.StatusCode)
+	}
+	code, state := m.authorize(t, resp.Header.Get("Location"))
+	resp, raw := doJSON(t, app, "GET", "/oidc/mock/callback?code="+code+"&state="+state, "", "")
+	var tok TokenResponse
+	if resp.StatusCode != fiber.StatusOK || json.Unmarshal(raw, &tok) != nil {
+		t.Fatalf("callback: status %d body %s", resp.StatusCode, raw)
+	}
+	user, err := stores.Users.FindByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.PasswordHash != "" {
+		t.Fatal("SSO user has a password")
+	}
+	return user, tok.AccessToken
+}
+
+func TestDeleteAccountAfterFreshSSOLogin(t *testing.T) {
+	m := newMockIdP(t)
+	app := newSSOApp(t, m)
+	user, fresh := ssoLogin(t, app, m)
+
+	// a session without a recent sign-in, as minted by Refresh
+	stale, _, err := createAccessToken(user.ID, user.Email, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := doJSON(t, app, "DELETE", "/account", stale, `{}`); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("delete with a refreshed session: status %d, want 401", resp.StatusCode)
+	}
+	old, _, _ := createAccessToken(user.ID, user.Email, time.Now().Add(-reauthWindow-time.Minute))
+	if resp, _ := doJSON(t, app, "DELETE", "/account", old, `{}`); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("delete with an old sign-in: status %d, want 401", resp.StatusCode)
+	}
+
+	if resp, raw := doJSON(t, app, "DELETE", "/account", fresh, `{}`); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("delete after a fresh sign-in: status %d body %s", resp.StatusCode, raw)
+	}
+	if _, err := stores.Users.FindByID(context.Background(), user.ID); err == nil {
+		t.Fatal("user still exists")
+	}
+}
+
+func TestDisableTOTPWithCodeForSSOUser(t *testing.T) {
+	m := newMockIdP(t)
+	app := newSSOApp(t, m)
+	user, _ := ssoLogin(t, app, m)
+
+	ctx := context.Background()
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Users.StartTOTP(ctx, user.ID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := stores.Users.EnableTOTP(ctx, user.ID, secret, 0, nil); err != nil || !ok {
+		t.Fatalf("enable totp: %v %v", ok, err)
+	}
+	key, _ := decodeTOTPSecret(secret)
+
+	// no recent sign-in and no password: the code alone is the proof
+	token, _, _ := createAccessToken(user.ID, user.Email, time.Time{})
+	if resp, _ := doJSON(t, app, "POST", "/mfa/disable", token, `{"code":"000000x"}`); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("disable with a bad code: status %d, want 401", resp.StatusCode)
+	}
+	body := `{"code":"` + totpCode(key, time.Now(), totpDigits) + `"}`
+	if resp, raw := doJSON(t, app, "POST", "/mfa/disable", token, body); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("disable with a code: status %d body %s", resp.StatusCode, raw)
+	}
+	if u, _ := stores.Users.FindByID(ctx, user.ID); u.TOTPEnabled {
+		t.Fatal("2FA still enabled")
+	}
+}
@@ -0,0 +1,12 @@
+package handlers
+
+import "github.com/Subomi7/todoist-clone/server/store"
+
+// stores holds the persistence backends of the handlers. Every handler goes
+// through it, so the in-memory stores can stand in for MongoDB in tests.
+var stores store.Stores
+
+// UseStores sets the persistence backends. Must be called before serving requests.
+func UseStores(s store.Stores) {
+	stores = s
+}
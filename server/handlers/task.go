@@ -6,28 +6,24 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 
-	"github.com/Subomi7/todoist-clone/server/db"
+	"github.com/Subomi7/todoist-clone/server/metrics"
 	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// db timeout used across handlers (same as other handlers)
-const dbOpTimeout = 6 * time.Second
-
 // CreateTaskDTO - explicit input payload for creating a task
 type CreateTaskDTO struct {
-	Title       string `json:"title"`
-	Description string `json:"description,omitempty"`
-	DueDate     string `json:"dueDate,omitempty"`   // RFC3339 string (optional)
-	Priority    int    `json:"priority,omitempty"`  // 1=Low,2=Medium,3=High
-	ProjectID   string `json:"projectId,omitempty"` // optional: hex string of project
+	Title       string `json:"title" validate:"required,max=200"`
+	Description string `json:"description,omitempty" validate:"max=2000"`
+	DueDate     string `json:"dueDate,omitempty" validate:"omitempty,rfc3339"`      // RFC3339 string (optional)
+	Priority    int    `json:"priority,omitempty" validate:"omitempty,oneof=1 2 3"` // 1=Low,2=Medium,3=High
+	ProjectID   string `json:"projectId,omitempty" validate:"omitempty,objectid"`   // optional: hex string of project
 }
 
 // CreateTaskResponse
@@ -37,12 +33,6 @@ type CreateTaskResponse struct {
 
 type TaskResponse = CreateTaskResponse
 
-type PaginationMeta struct {
-    Page     int   `json:"page"`
-    PageSize int   `json:"pageSize"`
-    Total    int64 `json:"total"`
-}
-
 type TasksListResponse struct {
     Data []models.Task  `json:"data"`
     Meta PaginationMeta `json:"meta"`
@@ -70,21 +60,12 @@ func getUserIDFromCtx(c *fiber.Ctx) (primitive.ObjectID, error) {
 	return userID, nil
 }
 
-// validatePriority ensures the numeric priority maps to the enum
-func validatePriority(p int) (models.Priority, error) {
-	switch p {
-	case int(models.PriorityLow):
-		return models.PriorityLow, nil
-	case int(models.PriorityMedium):
-		return models.PriorityMedium, nil
-	case int(models.PriorityHigh):
-		return models.PriorityHigh, nil
-	case 0:
-		// default to medium if not set
-		return models.PriorityMedium, nil
-	default:
-		return 0, errors.New("invalid priority")
+// defaultPriority maps an omitted priority to medium; the DTO tags allow only 0-3.
+func defaultPriority(p int) models.Priority {
+	if p == 0 {
+		return models.PriorityMedium
 	}
+	return models.Priority(p)
 }
 
 
@@ -109,57 +90,54 @@ func CreateTask(c *fiber.Ctx) error {
     uidRaw := c.Locals("user_id")
     uidStr, ok := uidRaw.(string)
     if !ok || uidStr == "" {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+        return errUnauthorized
     }
     userID, err := primitive.ObjectIDFromHex(uidStr)
     if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+        return errUnauthorized
     }
 
     var dto CreateTaskDTO
-    if err := c.BodyParser(&dto); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+    if err := parseBody(c, &dto); err != nil {
+        return err
     }
 
+    // the DTO tags have checked the formats already
     title := strings.TrimSpace(dto.Title)
-    if title == "" {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "title is required"})
-    }
-
-    // validate priority
-    priorityVal, err := validatePriority(dto.Priority)
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid priority; allowed: 1 (Low), 2 (Medium), 3 (High)"})
-    }
-
-    // parse due date
+    priorityVal := defaultPriority(dto.Priority)
     var dueDatePtr *time.Time
-    if s := strings.TrimSpace(dto.DueDate); s != "" {
-        parsed, err := time.Parse(time.RFC3339, s)
-        if err != nil {
-            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid dueDate: expected RFC3339 (e.g. 2025-08-01T15:04:05Z)"})
-        }
+    if dto.DueDate != "" {
+        parsed, _ := time.Parse(time.RFC3339, dto.DueDate)
         t := parsed.UTC()
         dueDatePtr = &t
     }
+    var projectID primitive.ObjectID
+    if dto.ProjectID != "" {
+        projectID, _ = primitive.ObjectIDFromHex(dto.ProjectID)
+    }
 
-    ctx, cancel := context.WithTimeout(context.Background(), dbOpTimeout)
+    ctx, cancel := requestContext(c)
     defer cancel()
 
     now := time.Now().UTC()
     var task models.Task
 
-    if strings.TrimSpace(dto.ProjectID) == "" {
+    if projectID.IsZero() {
         // No project provided → resolve Inbox
         inboxID, err := GetInboxProjectID(ctx, userID)
         if err != nil {
-            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not resolve inbox"})
+            return errInternal("could not resolve inbox", err)
+        }
+        wsID, err := ensurePersonalWorkspace(ctx, userID)
+        if err != nil {
+            return errInternal("could not resolve inbox", err)
         }
 
         task = models.Task{
             ID:          primitive.NewObjectID(),
             UserID:      userID,
             ProjectID:   inboxID,   // still stored as project
+            WorkspaceID: &wsID,
             InboxID:     &inboxID,  // explicit marker
             Title:       title,
             Description: strings.TrimSpace(dto.Description),
@@ -170,24 +148,25 @@ func CreateTask(c *fiber.Ctx) error {
             UpdatedAt:   now,
         }
     } else {
-        // Project provided → validate and use
-        projectID, err := primitive.ObjectIDFromHex(dto.ProjectID)
+        // Project provided → check access and use
+        // own projects and projects of the user's workspaces
+        access, err := accessibleBy(ctx, userID)
         if err != nil {
-            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid projectId"})
+            return errInternal("failed to verify project", err)
         }
-
-        var proj models.Project
-        if err := db.ProjectsCol().FindOne(ctx, bson.M{"_id": projectID, "userId": userID}).Decode(&proj); err != nil {
-            if err == mongo.ErrNoDocuments {
-                return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+        proj, err := stores.Projects.Get(ctx, projectID, access)
+        if err != nil {
+            if errors.Is(err, store.ErrNotFound) {
+                return errNotFound("project")
             }
-            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to verify project"})
+            return errInternal("failed to verify project", err)
         }
 
         task = models.Task{
             ID:          primitive.NewObjectID(),
             UserID:      userID,
             ProjectID:   projectID,
+            WorkspaceID: proj.WorkspaceID,
             InboxID:     nil, // not an inbox task
             Title:       title,
             Description: strings.TrimSpace(dto.Description),
@@ -199,191 +178,181 @@ func CreateTask(c *fiber.Ctx) error {
         }
     }
 
-    if _, err := db.TasksCol().InsertOne(ctx, task); err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create task"})
+    if err := stores.Tasks.Create(ctx, &task); err != nil {
+        return errInternal("failed to create task", err)
     }
+    metrics.TaskEvents.WithLabelValues("created").Inc()
     return c.Status(fiber.StatusCreated).JSON(TaskResponse{Data: task})
 }
 
 
 // ListQuery holds query parameters for paginated task listing.
 type ListQuery struct {
-	Page     int
-	PageSize int
+	listWindow
 	Completed *bool
 	Search   string
-	SortBy   string
+	Sort     store.Sort
 }
 
+// maxSearchLength bounds the search text, in characters.
+const maxSearchLength = 100
+
 // parseListQuery parses query parameters from the Fiber context for task listing.
-func parseListQuery(c *fiber.Ctx) ListQuery {
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	if page < 1 {
-		page = 1
-	}
-	pageSize, _ := strconv.Atoi(c.Query("pageSize", "20"))
-	if pageSize < 1 {
-		pageSize = 20
-	}
+// sortBy is a comma-separated list of whitelisted fields, each prefixed with -
+// for descending order; anything else is rejected rather than passed to the store.
+func parseListQuery(c *fiber.Ctx) (ListQuery, error) {
 	var completed *bool
 	if v := c.Query("completed"); v != "" {
 		val := v == "true" || v == "1"
 		completed = &val
 	}
 	search := c.Query("search", "")
-	sortBy := c.Query("sortBy", "-createdAt")
+	if utf8.RuneCountInString(search) > maxSearchLength {
+		return ListQuery{}, newError(fiber.StatusBadRequest, CodeInvalidQuery, "search must be at most "+strconv.Itoa(maxSearchLength)+" characters")
+	}
+	order, err := store.ParseTaskSort(c.Query("sortBy", "-createdAt"))
+	if err != nil {
+		return ListQuery{}, newError(fiber.StatusBadRequest, CodeInvalidQuery, err.Error())
+	}
 	return ListQuery{
-		Page:     page,
-		PageSize: pageSize,
+		listWindow: parseWindow(c, 20),
 		Completed: completed,
 		Search:   search,
-		SortBy:   sortBy,
-	}
+		Sort:     order,
+	}, nil
 }
 
-// buildFilter constructs a MongoDB filter for listing tasks based on user ID and query parameters.
-func buildFilter(uid primitive.ObjectID, q ListQuery, projectID string, inboxOnly bool) bson.M {
-    filter := bson.M{
-        "userId": uid,
-    }
+// buildFilter constructs the task filter for listing tasks based on user ID and query parameters.
+func buildFilter(ctx context.Context, uid primitive.ObjectID, q ListQuery, projectID string, inboxOnly bool) store.TaskFilter {
+    filter := q.filter()
+    filter.UserID = &uid
 
      // if inboxOnly flag, filter by inboxId
     if inboxOnly {
-        ctx, cancel := context.WithTimeout(context.Background(), dbOpTimeout)
-        defer cancel()
-
         inboxID, err := GetInboxProjectID(ctx, uid)
         if err == nil {
-            filter["inboxId"] = inboxID
+            filter.InboxID = &inboxID
         }
     } else if strings.TrimSpace(projectID) != "" {
         // filter by projectId if provided
         if pid, err := primitive.ObjectIDFromHex(projectID); err == nil {
-            filter["projectId"] = pid
-        }
-    }
-
-    if q.Completed != nil {
-        filter["completed"] = *q.Completed
-    }
-    if q.Search != "" {
-        filter["$or"] = []bson.M{
-            {"title": bson.M{"$regex": q.Search, "$options": "i"}},
-            {"description": bson.M{"$regex": q.Search, "$options": "i"}},
+            filter.ProjectID = &pid
         }
     }
     return filter
 }
 
+// filter returns the completed and search conditions of q.
+func (q ListQuery) filter() store.TaskFilter {
+    return store.TaskFilter{Completed: q.Completed, Search: q.Search}
+}
+
 
 // GetTasks returns a paginated list of tasks for the authenticated user.
-// supports ?page=&pageSize=&completed=&search=&sortBy=
+// supports ?page=&pageSize=&cursor=&total=&completed=&search=&sortBy=
 func GetTasks(c *fiber.Ctx) error {
     uid, err := getUserIDFromCtx(c)
     if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+        return errUnauthorized
+    }
+    q, err := parseListQuery(c)
+    if err != nil {
+        return err
     }
-    q := parseListQuery(c)
     projectID := c.Query("projectId", "")
 	inboxOnly := c.Query("inbox") == "true"
 
 	if inboxOnly && projectID != "" {
-    return c.Status(fiber.StatusBadRequest).
-        JSON(fiber.Map{"error": "cannot filter by both inbox and projectId"})
+    return newError(fiber.StatusBadRequest, CodeInvalidQuery, "cannot filter by both inbox and projectId")
 }
 
-    filter := buildFilter(uid, q, projectID, inboxOnly)
-
-    ctx, cancel := context.WithTimeout(context.Background(), dbOpTimeout)
+    ctx, cancel := requestContext(c)
     defer cancel()
 
-    col := db.TasksCol()
+    filter := buildFilter(ctx, uid, q, projectID, inboxOnly)
 
-    total, err := col.CountDocuments(ctx, filter)
-    if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to count tasks"})
-    }
+    return listTasks(c, ctx, filter, q)
+}
 
-    findOpts := options.Find()
-    if q.SortBy != "" {
-        order := 1
-        sortField := q.SortBy
-        if strings.HasPrefix(q.SortBy, "-") {
-            order = -1
-            sortField = strings.TrimPrefix(q.SortBy, "-")
+// listTasks writes one sorted page of the tasks matching filter, by page
+// number or after q's cursor.
+func listTasks(c *fiber.Ctx, ctx context.Context, filter store.TaskFilter, q ListQuery) error {
+    page := store.TaskPage{
+        Sort:  q.Sort,
+        Skip:  q.skip(),
+        Limit: q.limit(),
+        Count: q.Total,
+    }
+    if q.ByCursor {
+        after, err := decodeCursor(q.Cursor, q.Sort)
+        if err != nil {
+            return err
         }
-        findOpts.SetSort(bson.D{{Key: sortField, Value: order}})
+        page.After = after
     }
-    skip := int64((q.Page - 1) * q.PageSize)
-    limit := int64(q.PageSize)
-    findOpts.SetSkip(skip)
-    findOpts.SetLimit(limit)
-
-    cursor, err := col.Find(ctx, filter, findOpts)
+    tasks, total, err := stores.Tasks.List(ctx, filter, page)
     if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to fetch tasks"})
-    }
-    defer cursor.Close(ctx)
-
-    var tasks []models.Task
-    if err := cursor.All(ctx, &tasks); err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to decode tasks"})
+        return errInternal("failed to fetch tasks", err)
     }
 
-    meta := PaginationMeta{
-        Page:     q.Page,
-        PageSize: q.PageSize,
-        Total:    total,
+    tasks, more := trimPage(tasks, q.PageSize)
+    meta := q.meta(total)
+    if more {
+        meta.NextCursor = encodeCursor(q.Sort, store.TaskCursor(tasks[len(tasks)-1], q.Sort))
     }
     return c.JSON(TasksListResponse{Data: tasks, Meta: meta})
 }
 
 
 
-// GetTask returns one task by id for the authenticated user.
+// GetTask returns one task by id: the user's own, or one in a workspace they belong to.
 func GetTask(c *fiber.Ctx) error {
 	type TaskResponse struct {
     Data *models.Task `json:"data"`
 }
 	uid, err := getUserIDFromCtx(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return errUnauthorized
 	}
 
 	idParam := c.Params("id")
 	objID, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid task id"})
+		return errInvalidID("task")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), dbOpTimeout)
+	ctx, cancel := requestContext(c)
 	defer cancel()
-	col := db.TasksCol()
 
-	var task models.Task
-	err = col.FindOne(ctx, bson.M{"_id": objID, "userId": uid}).Decode(&task)
+	access, err := accessibleBy(ctx, uid)
+	if err != nil {
+		return errInternal("failed to fetch task", err)
+	}
+
+	task, err := stores.Tasks.Get(ctx, objID, access)
 	if err != nil {
 		// differentiate not found vs other errors
-		if err == mongo.ErrNoDocuments {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "task not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			return errNotFound("task")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to fetch task"})
+		return errInternal("failed to fetch task", err)
 	}
 
-	return c.JSON(TaskResponse{Data: &task})
+	return c.JSON(TaskResponse{Data: task})
 }
 
-// UpdateTask updates a task fields (title/description/completed) for the authenticated user.
-func UpdateTask(c *fiber.Ctx) error {
-	type UpdateTaskDTO struct {
-    Title       *string `json:"title,omitempty"`
-    Description *string `json:"description,omitempty"`
-    Completed   *bool   `json:"completed,omitempty"`
-    DueDate     *time.Time          `json:"dueDate,omitempty"`
-	Priority    *models.Priority    `json:"priority,omitempty"`
+// UpdateTaskDTO - fields of a task to change; omitted fields stay as they are
+type UpdateTaskDTO struct {
+	Title       *string             `json:"title,omitempty" validate:"notblank,max=200"`
+	Description *string             `json:"description,omitempty" validate:"max=2000"`
+	Completed   *bool               `json:"completed,omitempty"`
+	DueDate     *time.Time          `json:"dueDate,omitempty"`
+	Priority    *models.Priority    `json:"priority,omitempty" validate:"oneof=1 2 3"`
 	ProjectID   *primitive.ObjectID `json:"projectId,omitempty"`
 }
 
+// UpdateTask updates a task fields (title/description/completed) of a task the user can access.
+func UpdateTask(c *fiber.Ctx) error {
 type TaskResponse struct {
     Data *models.Task `json:"data"`
 }
@@ -391,97 +360,106 @@ type TaskResponse struct {
 
 	uid, err := getUserIDFromCtx(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return errUnauthorized
 	}
 
 	idParam := c.Params("id")
 	objID, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid task id"})
+		return errInvalidID("task")
 	}
 
 	var dto UpdateTaskDTO
-	if err := c.BodyParser(&dto); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	if err := parseBody(c, &dto); err != nil {
+		return err
 	}
 
 	// build update doc only with provided fields
-	set := bson.M{"updatedAt": time.Now().UTC()}
+	update := store.TaskUpdate{
+		Completed: dto.Completed,
+		DueDate:   dto.DueDate,
+		Priority:  dto.Priority,
+		UpdatedAt: time.Now().UTC(),
+	}
 	if dto.Title != nil {
 		title := strings.TrimSpace(*dto.Title)
-		if title == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "title cannot be empty"})
-		}
-		set["title"] = title
+		update.Title = &title
 	}
 	if dto.Description != nil {
-		set["description"] = strings.TrimSpace(*dto.Description)
+		desc := strings.TrimSpace(*dto.Description)
+		update.Description = &desc
 	}
-	if dto.Completed != nil {
-		set["completed"] = *dto.Completed
-	}
-	if dto.DueDate != nil {
-		set["dueDate"] = dto.DueDate
-	}
-	if dto.Priority != nil {
-		set["priority"] = *dto.Priority
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	access, err := accessibleBy(ctx, uid)
+	if err != nil {
+		return errInternal("failed to update task", err)
 	}
+
+	// moving a task also moves it into the target project's workspace
 	if dto.ProjectID != nil {
-		set["projectId"] = dto.ProjectID
+		proj, err := stores.Projects.Get(ctx, *dto.ProjectID, access)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return errNotFound("project")
+			}
+			return errInternal("failed to verify project", err)
+		}
+		update.Move = &store.TaskMove{ProjectID: proj.ID, WorkspaceID: proj.WorkspaceID}
 	}
 
 
 	// if no fields to update
-	if len(set) == 1 { // only updatedAt present
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no update fields provided"})
+	if update.Title == nil && update.Description == nil && update.Completed == nil &&
+		update.DueDate == nil && update.Priority == nil && update.Move == nil {
+		return newError(fiber.StatusBadRequest, CodeValidationFailed, "no update fields provided")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), dbOpTimeout)
-	defer cancel()
-	col := db.TasksCol()
-
-	res, err := col.UpdateOne(ctx, bson.M{"_id": objID, "userId": uid}, bson.M{"$set": set})
+	updated, err := stores.Tasks.Update(ctx, objID, access, update)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update task"})
-	}
-	if res.MatchedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "task not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			return errNotFound("task")
+		}
+		return errInternal("failed to update task", err)
 	}
-
-	// return updated resource (fetch fresh)
-	var updated models.Task
-	if err := col.FindOne(ctx, bson.M{"_id": objID, "userId": uid}).Decode(&updated); err != nil {
-		// shouldn't usually happen; return generic message
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to fetch updated task"})
+	if update.Completed != nil && *update.Completed {
+		metrics.TaskEvents.WithLabelValues("completed").Inc()
 	}
 
-	return c.JSON(TaskResponse{Data: &updated})
+	return c.JSON(TaskResponse{Data: updated})
 }
 
-// DeleteTask removes a task for the authenticated user.
+// DeleteTask removes a task the authenticated user can access.
 func DeleteTask(c *fiber.Ctx) error {
 	uid, err := getUserIDFromCtx(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return errUnauthorized
 	}
 
 	idParam := c.Params("id")
 	objID, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid task id"})
+		return errInvalidID("task")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), dbOpTimeout)
+	ctx, cancel := requestContext(c)
 	defer cancel()
-	col := db.TasksCol()
 
-	res, err := col.DeleteOne(ctx, bson.M{"_id": objID, "userId": uid})
+	access, err := accessibleBy(ctx, uid)
+	if err != nil {
+		return errInternal("failed to delete task", err)
+	}
+
+	deleted, err := stores.Tasks.Delete(ctx, objID, access)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete task"})
+		return errInternal("failed to delete task", err)
 	}
-	if res.DeletedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "task not found"})
+	if !deleted {
+		return errNotFound("task")
 	}
+	metrics.TaskEvents.WithLabelValues("deleted").Inc()
 
 	return c.SendStatus(fiber.StatusNoContent) // 204 No Content on successful delete
 }
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+// Personal access token scopes. "read" grants GET access to every resource;
+// "<resource>:write" grants full access to that resource.
+const (
+	ScopeRead          = "read"
+	ScopeTasksWrite    = "tasks:write"
+	ScopeProjectsWrite = "projects:write"
+)
+
+var validScopes = map[string]bool{
+	ScopeRead:          true,
+	ScopeTasksWrite:    true,
+	ScopeProjectsWrite: true,
+}
+
+const (
+	personalTokenPrefix  = "tdc_" // lets JWTMiddleware tell personal tokens apart from JWTs
+	maxPersonalTokens    = 50
+	maxTokenNameLength   = 100
+	maxTokenLifetimeDays = 365
+)
+
+// -------- DTOs ----------
+type CreatePersonalTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"` // 0 = never expires
+}
+
+type PersonalTokenResponse struct {
+	Data  *models.PersonalAccessToken `json:"data"`
+	Token string                      `json:"token,omitempty"` // plain token, only returned on creation
+}
+
+type PersonalTokensListResponse struct {
+	Data []models.PersonalAccessToken `json:"data"`
+}
+
+// -------- helpers ----------
+
+// authenticatePersonalToken resolves a personal access token to its owner and scopes.
+func authenticatePersonalToken(ctx context.Context, tokenPlain string) (*models.PersonalAccessToken, error) {
+	pat, err := stores.Tokens.FindPersonal(ctx, hashToken(tokenPlain))
+	if err != nil {
+		return nil, err
+	}
+	if pat.ExpiresAt != nil && pat.ExpiresAt.Before(time.Now()) {
+		return nil, store.ErrNotFound
+	}
+	// tokens of disabled accounts stop working without being deleted
+	owner, err := stores.Users.FindByID(ctx, pat.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if owner.Disabled {
+		return nil, store.ErrNotFound
+	}
+
+	// best effort; a failed bookkeeping write should not fail the request
+	if err := stores.Tokens.TouchPersonal(ctx, pat.ID, time.Now().UTC()); err != nil {
+		slog.ErrorContext(ctx, "failed to update personal token last use", "err", err)
+	}
+	return pat, nil
+}
+
+// tokenScopes returns the scopes of the personal token used for this request.
+// ok is false when the request was authenticated with a regular session JWT.
+func tokenScopes(c *fiber.Ctx) (scopes []string, ok bool) {
+	scopes, ok = c.Locals("scopes").([]string)
+	return scopes, ok
+}
+
+func hasScope(scopes []string, want string) bool {
+	for _, s := range scopes {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope restricts personal access tokens on a route or group to the scopes
+// covering resource ("tasks", "projects"). Session JWTs are not restricted.
+// Must be layered after JWTMiddleware.
+func RequireScope(resource string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scopes, ok := tokenScopes(c)
+		if !ok {
+			return c.Next()
+		}
+		write := resource + ":write"
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead:
+			if hasScope(scopes, ScopeRead) || hasScope(scopes, write) {
+				return c.Next()
+			}
+		default:
+			if hasScope(scopes, write) {
+				return c.Next()
+			}
+		}
+		// RFC 6750 section 3.1 names the missing scope in the challenge
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="`+write+`"`)
+		return newError(fiber.StatusForbidden, CodeInsufficientScope, "requires the "+write+" scope")
+	}
+}
+
+// SessionOnly rejects personal access tokens and impersonation tokens, for endpoints
+// that manage the account itself (2FA, token management) or who may reach shared
+// data (workspace members, workspace deletion). Must be layered after JWTMiddleware.
+func SessionOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := tokenScopes(c); ok {
+			return newError(fiber.StatusForbidden, CodeForbidden, "personal access tokens cannot be used for this endpoint")
+		}
+		// an administrator impersonating a user must not manage that user's credentials
+		if _, ok := c.Locals("impersonator_id").(string); ok {
+			return newError(fiber.StatusForbidden, CodeForbidden, "not available while impersonating")
+		}
+		return c.Next()
+	}
+}
+
+// -------- Handlers ----------
+
+// CreatePersonalToken issues a new personal access token. The plain token is only returned here.
+func CreatePersonalToken(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+
+	var req CreatePersonalTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidBody
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errField("name", validate.Required, "name is required")
+	}
+	if len(name) > maxTokenNameLength {
+		return errField("name", validate.TooLong, "name too long")
+	}
+	if len(req.Scopes) == 0 {
+		return errField("scopes", validate.Required, "at least one scope is required")
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		s = strings.TrimSpace(s)
+		if !validScopes[s] {
+			return errField("scopes", validate.Invalid, "invalid scope: "+s)
+		}
+		if !hasScope(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxTokenLifetimeDays {
+		return errField("expires_in_days", validate.Invalid, "expires_in_days must be between 0 and 365")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	count, err := stores.Tokens.CountPersonal(ctx, userID)
+	if err != nil {
+		return errInternal("database error", err)
+	}
+	if count >= maxPersonalTokens {
+		return newError(fiber.StatusConflict, CodeLimitReached, "too many personal access tokens")
+	}
+
+	random, err := generateRandomToken(32)
+	if err != nil {
+		return errInternal("could not create token", err)
+	}
+	plain := personalTokenPrefix + random
+
+	now := time.Now().UTC()
+	pat := models.PersonalAccessToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(plain),
+		Prefix:    plain[:len(personalTokenPrefix)+6],
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if req.ExpiresInDays > 0 {
+		exp := now.Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		pat.ExpiresAt = &exp
+	}
+
+	if err := stores.Tokens.CreatePersonal(ctx, &pat); err != nil {
+		return errInternal("could not save token", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(PersonalTokenResponse{Data: &pat, Token: plain})
+}
+
+// ListPersonalTokens returns the user's personal access tokens (without secrets).
+func ListPersonalTokens(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	tokens, err := stores.Tokens.ListPersonal(ctx, userID)
+	if err != nil {
+		return errInternal("failed to fetch tokens", err)
+	}
+	return c.JSON(PersonalTokensListResponse{Data: tokens})
+}
+
+// RevokePersonalToken deletes one of the user's personal access tokens.
+func RevokePersonalToken(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return errInvalidID("token")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	deleted, err := stores.Tokens.DeletePersonal(ctx, objID, userID)
+	if err != nil {
+		return errInternal("failed to revoke token", err)
+	}
+	if !deleted {
+		return errNotFound("token")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 4226 Appendix D.
+func TestHOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range want {
+		if got := hotp(key, uint64(counter), 6); got != code {
+			t.Errorf("hotp(counter=%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+// RFC 6238 Appendix B (SHA1).
+func TestTOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, time.Unix(tt.unix, 0), 8); got != tt.code {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	key, _ := decodeTOTPSecret(secret)
+	now := time.Unix(1111111111, 0)
+	code := totpCode(key, now, totpDigits)
+
+	step, ok := validateTOTP(secret, code, now, 0)
+	if !ok || step != totpStep(now) {
+		t.Fatalf("valid code rejected: step=%d ok=%v", step, ok)
+	}
+	// one step of clock skew is tolerated
+	if _, ok := validateTOTP(secret, code, now.Add(totpPeriod), 0); !ok {
+		t.Error("code from previous step rejected")
+	}
+	if _, ok := validateTOTP(secret, code, now.Add(3*totpPeriod), 0); ok {
+		t.Error("stale code accepted")
+	}
+	// replay of an already used step
+	if _, ok := validateTOTP(secret, code, now, step); ok {
+		t.Error("replayed code accepted")
+	}
+	if _, ok := validateTOTP(secret, "12345", now, 0); ok {
+		t.Error("short code accepted")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("JBSWY3DPEHPK3PXP", "alice@example.com")
+	for _, part := range []string{"otpauth://totp/", "secret=JBSWY3DPEHPK3PXP", "issuer=" + issuer, "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("uri %q missing %q", uri, part)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	plain, hashes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plain) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, %d hashes", len(plain), len(hashes))
+	}
+	for i, code := range plain {
+		if hashToken(normalizeRecoveryCode(" "+strings.ToUpper(code)+" ")) != hashes[i] {
+			t.Errorf("recovery code %q does not match its hash", code)
+		}
+	}
+}
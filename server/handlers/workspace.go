@@ -0,0 +1,554 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/metrics"
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/store"
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+var workspaceRoleRank = map[string]int{
+	models.WorkspaceRoleMember: 1,
+	models.WorkspaceRoleAdmin:  2,
+	models.WorkspaceRoleOwner:  3,
+}
+
+// -------- DTOs ----------
+
+type WorkspaceDTO struct {
+	Name string `json:"name"`
+}
+
+type AddWorkspaceMemberDTO struct {
+	Email string `json:"email"`
+	Role  string `json:"role,omitempty"` // "member" (default) or "admin"
+}
+
+type UpdateWorkspaceMemberDTO struct {
+	Role string `json:"role"`
+}
+
+// WorkspaceView is a workspace together with the caller's role in it.
+type WorkspaceView struct {
+	*models.Workspace
+	Role string `json:"role"`
+}
+
+type WorkspaceMemberView struct {
+	UserID   string    `json:"userId"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// -------- helpers ----------
+
+// ensurePersonalWorkspace returns the user's personal workspace, creating it (and the
+// owner membership) on first use. Safe to call concurrently.
+func ensurePersonalWorkspace(ctx context.Context, userID primitive.ObjectID) (primitive.ObjectID, error) {
+	ws, err := stores.Workspaces.EnsurePersonal(ctx, userID, models.PersonalWorkspaceName, time.Now().UTC())
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return ws.ID, nil
+}
+
+// accessibleBy selects the projects and tasks the user may access: their own, and
+// everything in workspaces they are a member of.
+func accessibleBy(ctx context.Context, userID primitive.ObjectID) (store.Access, error) {
+	memberships, err := stores.Workspaces.MembershipsOf(ctx, userID)
+	if err != nil {
+		return store.Access{}, err
+	}
+	access := store.Owner(userID)
+	for _, m := range memberships {
+		access.WorkspaceIDs = append(access.WorkspaceIDs, m.WorkspaceID)
+	}
+	return access, nil
+}
+
+// loadWorkspaceAs resolves the :id workspace and the caller's membership, requiring at
+// least minRole.
+func loadWorkspaceAs(c *fiber.Ctx, ctx context.Context, minRole string) (*models.Workspace, *models.WorkspaceMember, error) {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return nil, nil, errUnauthorized
+	}
+	wsID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, nil, errInvalidID("workspace")
+	}
+
+	// non-members get 404 so workspace ids cannot be probed
+	member, err := stores.Workspaces.GetMember(ctx, wsID, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, errNotFound("workspace")
+		}
+		return nil, nil, errInternal("failed to fetch workspace", err)
+	}
+	ws, err := stores.Workspaces.Get(ctx, wsID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, errNotFound("workspace")
+		}
+		return nil, nil, errInternal("failed to fetch workspace", err)
+	}
+	if workspaceRoleRank[member.Role] < workspaceRoleRank[minRole] {
+		return nil, nil, newError(fiber.StatusForbidden, CodeForbidden, "insufficient workspace role")
+	}
+	return ws, member, nil
+}
+
+// deleteWorkspaceData removes a workspace with its projects, tasks and memberships.
+func deleteWorkspaceData(ctx context.Context, wsID primitive.ObjectID) error {
+	if err := stores.Tasks.DeleteByWorkspace(ctx, wsID); err != nil {
+		return err
+	}
+	if err := stores.Projects.DeleteByWorkspace(ctx, wsID); err != nil {
+		return err
+	}
+	return stores.Workspaces.Delete(ctx, wsID)
+}
+
+// releaseWorkspacesOwnedBy is used by account deletion: shared workspaces are handed
+// to the longest-standing admin (else member), workspaces nobody else uses are deleted.
+func releaseWorkspacesOwnedBy(ctx context.Context, ownerID primitive.ObjectID) error {
+	owned, err := stores.Workspaces.OwnedBy(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	for _, ws := range owned {
+		heir, err := stores.Workspaces.Successor(ctx, ws.ID, ownerID)
+		if ws.Personal || errors.Is(err, store.ErrNotFound) {
+			if err := deleteWorkspaceData(ctx, ws.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := stores.Workspaces.SetOwner(ctx, ws.ID, heir.UserID, time.Now().UTC()); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "releaseWorkspacesOwnedBy: transferred workspace", "workspace_id", ws.ID.Hex(), "user_id", heir.UserID.Hex())
+	}
+	return nil
+}
+
+// handOverSharedProjects gives the projects user created in shared workspaces
+// to each workspace's owner, so deleting the account does not take other
+// members' tasks down with them. It runs after releaseWorkspacesOwnedBy, so
+// workspaces the user owned go to their heir.
+func handOverSharedProjects(ctx context.Context, user *models.User) error {
+	memberships, err := stores.Workspaces.MembershipsOf(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, m := range memberships {
+		ws, err := stores.Workspaces.Get(ctx, m.WorkspaceID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if ws.Personal || ws.OwnerID == user.ID {
+			continue
+		}
+		projects, _, err := stores.Projects.List(ctx, store.ProjectFilter{UserID: &user.ID, WorkspaceID: &ws.ID}, store.ProjectPage{})
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for _, p := range projects {
+			err := stores.Projects.Reassign(ctx, p.ID, ws.OwnerID, p.Name, now)
+			if errors.Is(err, store.ErrDuplicate) {
+				// the owner already has a project by that name here
+				err = stores.Projects.Reassign(ctx, p.ID, ws.OwnerID, p.Name+" ("+user.Email+")", now)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// -------- Handlers ----------
+
+// CreateWorkspace creates a shared workspace owned by the authenticated user.
+func CreateWorkspace(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	var dto WorkspaceDTO
+	if err := c.BodyParser(&dto); err != nil {
+		return errInvalidBody
+	}
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return errField("name", validate.Required, "name is required")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	now := time.Now().UTC()
+	ws := models.Workspace{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		OwnerID:   userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	member := models.WorkspaceMember{
+		ID:          primitive.NewObjectID(),
+		WorkspaceID: ws.ID,
+		UserID:      userID,
+		Role:        models.WorkspaceRoleOwner,
+		JoinedAt:    now,
+	}
+	if err := stores.Workspaces.Create(ctx, &ws, &member); err != nil {
+		return errInternal("failed to create workspace", err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": WorkspaceView{Workspace: &ws, Role: member.Role}})
+}
+
+// ListWorkspaces returns every workspace the authenticated user belongs to.
+func ListWorkspaces(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	// make sure the personal workspace exists even for accounts created before workspaces
+	if _, err := ensurePersonalWorkspace(ctx, userID); err != nil {
+		return errInternal("failed to fetch workspaces", err)
+	}
+
+	memberships, err := stores.Workspaces.MembershipsOf(ctx, userID)
+	if err != nil {
+		return errInternal("failed to fetch workspaces", err)
+	}
+	roles := make(map[primitive.ObjectID]string, len(memberships))
+	ids := make([]primitive.ObjectID, 0, len(memberships))
+	for _, m := range memberships {
+		roles[m.WorkspaceID] = m.Role
+		ids = append(ids, m.WorkspaceID)
+	}
+
+	// personal workspace first, then by name
+	workspaces, err := stores.Workspaces.List(ctx, ids)
+	if err != nil {
+		return errInternal("failed to fetch workspaces", err)
+	}
+
+	views := make([]WorkspaceView, 0, len(workspaces))
+	for i := range workspaces {
+		views = append(views, WorkspaceView{Workspace: &workspaces[i], Role: roles[workspaces[i].ID]})
+	}
+	return c.JSON(fiber.Map{"data": views})
+}
+
+// GetWorkspace returns one workspace the caller belongs to.
+func GetWorkspace(c *fiber.Ctx) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, member, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleMember)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"data": WorkspaceView{Workspace: ws, Role: member.Role}})
+}
+
+// UpdateWorkspace renames a workspace. Requires the admin role.
+func UpdateWorkspace(c *fiber.Ctx) error {
+	var dto WorkspaceDTO
+	if err := c.BodyParser(&dto); err != nil {
+		return errInvalidBody
+	}
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return errField("name", validate.Required, "name is required")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, member, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleAdmin)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if err := stores.Workspaces.Rename(ctx, ws.ID, name, now); err != nil {
+		return errInternal("failed to update workspace", err)
+	}
+	ws.Name, ws.UpdatedAt = name, now
+	return c.JSON(fiber.Map{"data": WorkspaceView{Workspace: ws, Role: member.Role}})
+}
+
+// DeleteWorkspace deletes a shared workspace with all its projects and tasks. Owner only.
+func DeleteWorkspace(c *fiber.Ctx) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, _, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+	if ws.Personal {
+		return newError(fiber.StatusBadRequest, CodeNotAllowed, "cannot delete personal workspace")
+	}
+	if err := deleteWorkspaceData(ctx, ws.ID); err != nil {
+		return errInternal("failed to delete workspace", err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListWorkspaceMembers returns the members of a workspace with their roles.
+func ListWorkspaceMembers(c *fiber.Ctx) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, _, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleMember)
+	if err != nil {
+		return err
+	}
+
+	members, err := stores.Workspaces.Members(ctx, ws.ID)
+	if err != nil {
+		return errInternal("failed to fetch members", err)
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(members))
+	for _, m := range members {
+		userIDs = append(userIDs, m.UserID)
+	}
+	users, err := stores.Users.FindByIDs(ctx, userIDs)
+	if err != nil {
+		return errInternal("failed to fetch members", err)
+	}
+	emails := make(map[primitive.ObjectID]string, len(users))
+	for _, u := range users {
+		emails[u.ID] = u.Email
+	}
+
+	views := make([]WorkspaceMemberView, 0, len(members))
+	for _, m := range members {
+		views = append(views, WorkspaceMemberView{UserID: m.UserID.Hex(), Email: emails[m.UserID], Role: m.Role, JoinedAt: m.JoinedAt})
+	}
+	return c.JSON(fiber.Map{"data": views})
+}
+
+// AddWorkspaceMember adds an existing user, looked up by email. Requires the admin role.
+func AddWorkspaceMember(c *fiber.Ctx) error {
+	var dto AddWorkspaceMemberDTO
+	if err := c.BodyParser(&dto); err != nil {
+		return errInvalidBody
+	}
+	email := strings.TrimSpace(strings.ToLower(dto.Email))
+	if email == "" {
+		return errField("email", validate.Required, "email is required")
+	}
+	role := dto.Role
+	if role == "" {
+		role = models.WorkspaceRoleMember
+	}
+	if role != models.WorkspaceRoleMember && role != models.WorkspaceRoleAdmin {
+		return errField("role", validate.Invalid, `role must be "member" or "admin"`)
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, _, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleAdmin)
+	if err != nil {
+		return err
+	}
+	if ws.Personal {
+		return newError(fiber.StatusBadRequest, CodeNotAllowed, "personal workspace cannot be shared")
+	}
+
+	user, err := findUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return errNotFound("user")
+		}
+		return errInternal("failed to fetch user", err)
+	}
+
+	member := models.WorkspaceMember{
+		ID:          primitive.NewObjectID(),
+		WorkspaceID: ws.ID,
+		UserID:      user.ID,
+		Role:        role,
+		JoinedAt:    time.Now().UTC(),
+	}
+	if err := stores.Workspaces.AddMember(ctx, &member); err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			return newError(fiber.StatusConflict, CodeAlreadyMember, "user is already a member")
+		}
+		return errInternal("failed to add member", err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": WorkspaceMemberView{
+		UserID: user.ID.Hex(), Email: user.Email, Role: member.Role, JoinedAt: member.JoinedAt,
+	}})
+}
+
+// UpdateWorkspaceMember changes a member's role. Requires the admin role; the owner's
+// role cannot be changed.
+func UpdateWorkspaceMember(c *fiber.Ctx) error {
+	var dto UpdateWorkspaceMemberDTO
+	if err := c.BodyParser(&dto); err != nil {
+		return errInvalidBody
+	}
+	if dto.Role != models.WorkspaceRoleMember && dto.Role != models.WorkspaceRoleAdmin {
+		return errField("role", validate.Invalid, `role must be "member" or "admin"`)
+	}
+	targetID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return errInvalidID("user")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, _, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	updated, err := stores.Workspaces.SetMemberRole(ctx, ws.ID, targetID, dto.Role)
+	if err != nil {
+		return errInternal("failed to update member", err)
+	}
+	if !updated {
+		return errNotFound("member")
+	}
+	return c.JSON(fiber.Map{"message": "member updated"})
+}
+
+// RemoveWorkspaceMember removes a member. Admins can remove others; any member can
+// remove themselves to leave. The owner cannot be removed.
+func RemoveWorkspaceMember(c *fiber.Ctx) error {
+	targetID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return errInvalidID("user")
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, member, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleMember)
+	if err != nil {
+		return err
+	}
+	if member.UserID != targetID && workspaceRoleRank[member.Role] < workspaceRoleRank[models.WorkspaceRoleAdmin] {
+		return newError(fiber.StatusForbidden, CodeForbidden, "insufficient workspace role")
+	}
+
+	removed, err := stores.Workspaces.RemoveMember(ctx, ws.ID, targetID)
+	if err != nil {
+		return errInternal("failed to remove member", err)
+	}
+	if !removed {
+		return errNotFound("member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetWorkspaceProjects lists the projects of a workspace with open task counts.
+// supports ?page=&pageSize=&cursor=&total=
+func GetWorkspaceProjects(c *fiber.Ctx) error {
+	w := parseWindow(c, projectPageSize)
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, _, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleMember)
+	if err != nil {
+		return err
+	}
+
+	return listProjects(c, ctx, store.ProjectFilter{WorkspaceID: &ws.ID}, w)
+}
+
+// CreateWorkspaceProject creates a project inside a workspace. Any member may create projects.
+func CreateWorkspaceProject(c *fiber.Ctx) error {
+	userID, err := getUserIDFromCtx(c)
+	if err != nil {
+		return errUnauthorized
+	}
+	var dto CreateProjectDTO
+	if err := parseBody(c, &dto); err != nil {
+		return err
+	}
+	name := strings.TrimSpace(dto.Name)
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, _, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleMember)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	proj := models.Project{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		WorkspaceID: &ws.ID,
+		Name:        name,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := stores.Projects.Create(ctx, &proj); err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			return newError(fiber.StatusConflict, CodeProjectExists, "project with this name already exists")
+		}
+		return errInternal("failed to create project", err)
+	}
+	metrics.ProjectEvents.WithLabelValues("created").Inc()
+	return c.Status(fiber.StatusCreated).JSON(ProjectResponse{Data: &proj})
+}
+
+// GetWorkspaceTasks returns a paginated list of every member's tasks in a workspace.
+// supports ?page=&pageSize=&cursor=&total=&completed=&search=&sortBy=&projectId=
+func GetWorkspaceTasks(c *fiber.Ctx) error {
+	q, err := parseListQuery(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	ws, _, err := loadWorkspaceAs(c, ctx, models.WorkspaceRoleMember)
+	if err != nil {
+		return err
+	}
+
+	filter := q.filter()
+	filter.WorkspaceID = &ws.ID
+	if pid, err := primitive.ObjectIDFromHex(c.Query("projectId")); err == nil {
+		filter.ProjectID = &pid
+	}
+	return listTasks(c, ctx, filter, q)
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+)
+
+func captureLogs(t *testing.T, level string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(New(config.LogConfig{Level: level, Format: "json"}, &buf))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestRedactsAtInfo(t *testing.T) {
+	buf := captureLogs(t, "debug")
+
+	slog.Info("login failed for jane@example.com",
+		"email", "jane@example.com",
+		"refresh_token", "abc123",
+		"err", errors.New("no user jane@example.com"),
+		"link", "https://app.example.com/unlock?token=s3cr3t",
+		"auth", "Bearer eyJhbGciOi.eyJzdWIi.sig",
+		"user_id", "64b000000000000000000001",
+	)
+	out := buf.String()
+	for _, leak := range []string{"jane@example.com", "abc123", "s3cr3t", "eyJhbGciOi"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("info log leaks %q: %s", leak, out)
+		}
+	}
+	if !strings.Contains(out, "64b000000000000000000001") {
+		t.Errorf("info log lost a non-sensitive value: %s", out)
+	}
+}
+
+func TestDebugKeepsEmailsButNotSecrets(t *testing.T) {
+	buf := captureLogs(t, "debug")
+
+	slog.Debug("login attempt", "email", "jane@example.com", "password", "hunter2")
+	out := buf.String()
+	if !strings.Contains(out, "jane@example.com") {
+		t.Errorf("debug log should keep the email: %s", out)
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("debug log leaks the password: %s", out)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	buf := captureLogs(t, "warn")
+
+	slog.Info("hidden")
+	slog.Warn("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Errorf("warn level output: %s", out)
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	buf := captureLogs(t, "info")
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/", func(c *fiber.Ctx) error {
+		slog.InfoContext(c.UserContext(), "in handler")
+		return c.SendString(RequestID(c.UserContext()))
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"propagated", "req-42.abc", true},
+		{"rejected", "bad id\nwith newline", false},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.incoming != "" {
+			req.Header.Set(RequestIDHeader, tt.incoming)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := resp.Header.Get(RequestIDHeader)
+		if id == "" || (tt.keep && id != tt.incoming) || (!tt.keep && id == tt.incoming) {
+			t.Errorf("%s: response %s = %q", tt.name, RequestIDHeader, id)
+		}
+		// both the handler record and the access record carry the ID
+		if n := strings.Count(buf.String(), `"request_id":"`+id+`"`); n != 2 {
+			t.Errorf("%s: request_id logged %d times, want 2:\n%s", tt.name, n, buf.String())
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// incoming IDs are reused only when they cannot smuggle anything into the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+
+// Middleware gives every request an ID, echoes it in the X-Request-ID response
+// header, attaches it to c.UserContext() for handler logs, and writes one
+// access log record per request. A caller-supplied X-Request-ID is kept when
+// it is well formed.
+//
+// Errors from later handlers are passed to the app's error handler here, as
+// Fiber's logger middleware does, so the logged status is the one sent.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		id := c.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = utils.UUIDv4()
+		}
+		c.Set(RequestIDHeader, id)
+		ctx := WithRequestID(c.UserContext(), id)
+		c.SetUserContext(ctx)
+
+		if err := c.Next(); err != nil {
+			if herr := c.App().ErrorHandler(c, err); herr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		// the path only: query strings can carry codes and tokens
+		slog.Log(ctx, level, "request",
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", status,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"ip", c.IP(),
+		)
+		return nil
+	}
+}
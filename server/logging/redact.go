@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// JWTs and bearer credentials, wherever they appear in a string
+	tokenPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*|(?i:bearer\s+)[A-Za-z0-9._~+/\-]+=*`)
+	// credentials passed in URLs, such as emailed unlock links
+	queryPattern = regexp.MustCompile(`(?i)\b(token|code|state)=[^&\s]+`)
+)
+
+// secretKeyParts mark attributes whose values are credentials at every level.
+var secretKeyParts = []string{"password", "token", "secret", "authorization", "cookie", "otp", "recovery"}
+
+// piiKeys mark attributes holding personal data, which only debug records keep.
+var piiKeys = map[string]bool{"email": true, "to": true}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == "code" {
+		return true
+	}
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr hides credentials always and personal data unless keepPII is set.
+// Values are matched by key and, for strings and errors, by content.
+func redactAttr(a slog.Attr, keepPII bool) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		out := make([]slog.Attr, len(attrs))
+		for i, ga := range attrs {
+			out[i] = redactAttr(ga, keepPII)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(out...)}
+	}
+	if isSecretKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	if piiKeys[strings.ToLower(a.Key)] && !keepPII {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactString(a.Value.String(), keepPII))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, redactString(err.Error(), keepPII))
+		}
+	}
+	return a
+}
+
+// redactString masks tokens and, unless keepPII is set, email addresses.
+func redactString(s string, keepPII bool) string {
+	s = tokenPattern.ReplaceAllString(s, redacted)
+	s = queryPattern.ReplaceAllString(s, "${1}="+redacted)
+	if !keepPII {
+		s = emailPattern.ReplaceAllString(s, "[email]")
+	}
+	return s
+}
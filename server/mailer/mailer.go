@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+)
+
+var settings config.SMTPConfig
+
+// Configure sets the SMTP server used by Send. Must be called once at startup.
+func Configure(cfg config.SMTPConfig) {
+	settings = cfg
+}
+
+// Send delivers a plain-text email over SMTP.
+//
+// Without an SMTP address (local development) the message is written to the
+// log instead of being sent.
+func Send(to, subject, body string) error {
+	addr := settings.Addr
+	if addr == "" {
+		// debug only: the body can hold one-time links
+		slog.Debug("mailer: SMTP_ADDR not set, not sending", "to", to, "subject", subject, "body", body)
+		return nil
+	}
+
+	from := settings.From
+	if from == "" {
+		return fmt.Errorf("SMTP_FROM not set")
+	}
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid header value")
+	}
+
+	var auth smtp.Auth
+	if user := settings.Username; user != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP_ADDR: %w", err)
+		}
+		auth = smtp.PlainAuth("", user, settings.Password, host)
+	}
+
+	msg := "From: " + from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	return smtp.SendMail(addr, auth, from, []string{to}, []byte(msg))
+}
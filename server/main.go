@@ -1,11 +1,20 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/Subomi7/todoist-clone/server/app"
 )
 
 func main() {
-	
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := app.RunMigrateCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	err := app.SetupAndRunApp()
 	if err != nil {
 		panic(err)
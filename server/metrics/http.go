@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute labels requests that no route handled, whatever their path.
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Middleware records the count and latency of every request. Register it
+// first, before recover, so panics are counted as 500s.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		self := c.Route()
+		// write the error response here, as logging does, so the status
+		// recorded is the one the error handler chose
+		if err := c.Next(); err != nil {
+			if herr := c.App().ErrorHandler(c, err); herr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		labels := prometheus.Labels{
+			"method": c.Method(),
+			"route":  routeLabel(c, self),
+			"status": strconv.Itoa(c.Response().StatusCode()),
+		}
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
+
+// routeLabel returns the template of the route that handled the request, such
+// as /api/tasks/:id, never the raw path. self is the middleware's own route,
+// which is still current when nothing after it matched.
+func routeLabel(c *fiber.Ctx, self *fiber.Route) string {
+	route := c.Route()
+	if route == self {
+		return unmatchedRoute
+	}
+	return route.Path
+}
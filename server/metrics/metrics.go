@@ -0,0 +1,114 @@
+// Package metrics collects Prometheus metrics for HTTP traffic, MongoDB
+// commands, background jobs and domain events, and serves them on /metrics.
+//
+// Label values are always drawn from small fixed sets (route templates,
+// collection and command names, event names) so series stay bounded no matter
+// what clients send.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "todoist"
+
+// sessionCountTimeout bounds the query behind todoist_active_sessions during a scrape.
+const sessionCountTimeout = 2 * time.Second
+
+var (
+	registry = prometheus.NewRegistry()
+	factory  = promauto.With(registry)
+)
+
+var (
+	// TaskEvents counts task changes by event: created, completed or deleted.
+	TaskEvents = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "task_events_total",
+		Help:      "Tasks created, completed and deleted.",
+	}, []string{"event"})
+
+	// ProjectEvents counts project changes by event: created or deleted.
+	ProjectEvents = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "project_events_total",
+		Help:      "Projects created and deleted.",
+	}, []string{"event"})
+
+	jobRuns = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "background_job_runs_total",
+		Help:      "Background job runs by job and result.",
+	}, []string{"job", "result"})
+
+	jobDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "background_job_duration_seconds",
+		Help:      "Background job run time.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"job"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves every registered metric in the Prometheus text format.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
+
+// ObserveJob records one run of a background job that started at start.
+func ObserveJob(job string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	jobRuns.WithLabelValues(job, result).Inc()
+	jobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+}
+
+// CountSessionsWith reports todoist_active_sessions by calling count on every
+// scrape. Call it once at startup.
+func CountSessionsWith(count func(ctx context.Context) (int64, error)) {
+	registry.MustRegister(sessionCollector{count: count})
+}
+
+var activeSessionsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "active_sessions"),
+	"Signed-in sessions: refresh tokens that are neither expired nor revoked.",
+	nil, nil,
+)
+
+type sessionCollector struct {
+	count func(ctx context.Context) (int64, error)
+}
+
+func (sessionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSessionsDesc
+}
+
+// Collect leaves the gauge out of the scrape when the count fails, so a slow
+// database never fails the whole scrape.
+func (s sessionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionCountTimeout)
+	defer cancel()
+	n, err := s.count(ctx)
+	if err != nil {
+		slog.Error("metrics: counting active sessions", "err", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(activeSessionsDesc, prometheus.GaugeValue, float64(n))
+}
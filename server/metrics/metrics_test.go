@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errGone = errors.New("gone")
+
+func scrape(t *testing.T, app *fiber.App) string {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMiddlewareLabelsRouteTemplates(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		// like the API's handler, which maps store errors to statuses
+		if errors.Is(err, errGone) {
+			return c.SendStatus(fiber.StatusGone)
+		}
+		return fiber.DefaultErrorHandler(c, err)
+	}})
+	app.Use(Middleware())
+	app.Get("/metrics", Handler())
+	app.Get("/items/:id", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	guarded := app.Group("/private", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	})
+	guarded.Get("/", func(c *fiber.Ctx) error { return nil })
+	app.Get("/gone", func(c *fiber.Ctx) error { return errGone })
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/3", "/nope/123", "/private/x", "/gone"} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := scrape(t, app)
+	for _, want := range []string{
+		`todoist_http_requests_total{method="GET",route="/items/:id",status="200"} 3`,
+		`todoist_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`todoist_http_requests_total{method="GET",route="/private",status="401"} 1`,
+		`todoist_http_requests_total{method="GET",route="/gone",status="410"} 1`,
+		`todoist_http_request_duration_seconds_count{method="GET",route="/items/:id",status="200"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %s", want)
+		}
+	}
+	for _, raw := range []string{"/items/1", "/nope/123"} {
+		if strings.Contains(out, `route="`+raw+`"`) {
+			t.Errorf("raw path %s used as a route label", raw)
+		}
+	}
+}
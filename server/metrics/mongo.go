@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	mongoDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_command_duration_seconds",
+		Help:      "MongoDB command latency by collection and command.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"collection", "command"})
+
+	mongoErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongo_command_errors_total",
+		Help:      "Failed MongoDB commands by collection and command.",
+	}, []string{"collection", "command"})
+)
+
+// ObserveMongoCommand records one finished MongoDB command. The db package
+// calls it from the driver's command monitor.
+func ObserveMongoCommand(collection, command string, d time.Duration, failed bool) {
+	mongoDuration.WithLabelValues(collection, command).Observe(d.Seconds())
+	if failed {
+		mongoErrors.WithLabelValues(collection, command).Inc()
+	}
+}
@@ -0,0 +1,195 @@
+// Package migrations evolves the MongoDB schema: indexes and document shapes.
+//
+// Migrations run in version order and each one is recorded in the
+// schema_migrations collection once applied, so it never runs twice. Up
+// functions must still be idempotent: a migration interrupted before it was
+// recorded is re-run from the start.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Subomi7/todoist-clone/server/db"
+)
+
+// Migration is one schema change. Versions are never reused or reordered.
+type Migration struct {
+	Version     int
+	Name        string
+	Description string
+	// Pending, when set, reports how many documents Up would change. Dry runs show it.
+	Pending func(ctx context.Context) (int64, error)
+	Up      func(ctx context.Context) error
+}
+
+// Status is a migration together with when it was applied, if it was.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type record struct {
+	Version    int       `bson:"_id"`
+	Name       string    `bson:"name"`
+	AppliedAt  time.Time `bson:"applied_at"`
+	DurationMS int64     `bson:"duration_ms"`
+}
+
+// historyStore keeps the records of applied migrations.
+type historyStore interface {
+	list(ctx context.Context) ([]record, error)
+	// add records r; a record another instance added first is not an error.
+	add(ctx context.Context, r record) error
+}
+
+// history is the schema_migrations collection; tests replace it.
+var history historyStore = mongoHistory{}
+
+type mongoHistory struct{}
+
+func (mongoHistory) list(ctx context.Context) ([]record, error) {
+	cur, err := db.MigrationsCol().Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (mongoHistory) add(ctx context.Context, r record) error {
+	// another instance may have applied the same migration concurrently
+	if _, err := db.MigrationsCol().InsertOne(ctx, r); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+// Statuses reports every known migration in version order.
+func Statuses(ctx context.Context) ([]Status, error) {
+	records, err := history.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+
+	statuses := make([]Status, 0, len(all))
+	for _, m := range sorted() {
+		r, ok := applied[m.Version]
+		statuses = append(statuses, Status{Migration: m, Applied: ok, AppliedAt: r.AppliedAt})
+	}
+	return statuses, nil
+}
+
+// Pending returns how many known migrations have not been applied yet.
+func Pending(ctx context.Context) (int, error) {
+	statuses, err := Statuses(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range statuses {
+		if !s.Applied {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Up applies every pending migration in order and returns how many ran. With
+// dryRun set nothing is changed; the pending migrations are only reported to w.
+func Up(ctx context.Context, dryRun bool, w io.Writer) (int, error) {
+	statuses, err := Statuses(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("read migration status: %w", err)
+	}
+
+	n := 0
+	for _, s := range statuses {
+		if s.Applied {
+			continue
+		}
+		if dryRun {
+			line := fmt.Sprintf("would apply %04d %s: %s", s.Version, s.Name, s.Description)
+			if s.Pending != nil {
+				count, err := s.Pending(ctx)
+				if err != nil {
+					return n, fmt.Errorf("migration %04d %s: %w", s.Version, s.Name, err)
+				}
+				line += fmt.Sprintf(" (%d documents)", count)
+			}
+			fmt.Fprintln(w, line)
+			n++
+			continue
+		}
+
+		start := time.Now()
+		if err := s.Up(ctx); err != nil {
+			return n, fmt.Errorf("migration %04d %s: %w", s.Version, s.Name, err)
+		}
+		rec := record{
+			Version:    s.Version,
+			Name:       s.Name,
+			AppliedAt:  time.Now().UTC(),
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		if err := history.add(ctx, rec); err != nil {
+			return n, fmt.Errorf("record migration %04d %s: %w", s.Version, s.Name, err)
+		}
+		fmt.Fprintf(w, "applied %04d %s in %s\n", s.Version, s.Name, time.Since(start).Round(time.Millisecond))
+		n++
+	}
+	return n, nil
+}
+
+// Run applies pending migrations at startup, logging each one.
+func Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	n, err := Up(ctx, false, log.Writer())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		slog.Info("migrations applied", "count", n)
+	}
+	return nil
+}
+
+// PrintStatus writes one line per migration: applied (with date) or pending.
+func PrintStatus(ctx context.Context, w io.Writer) error {
+	statuses, err := Statuses(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		state := "pending"
+		if s.Applied {
+			state = "applied " + s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d %-28s %s\n", s.Version, s.Name, state)
+	}
+	return nil
+}
+
+func sorted() []Migration {
+	ms := append([]Migration(nil), all...)
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms
+}
@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeHistory struct{ records []record }
+
+func (h *fakeHistory) list(context.Context) ([]record, error) {
+	return append([]record(nil), h.records...), nil
+}
+
+func (h *fakeHistory) add(_ context.Context, r record) error {
+	for _, x := range h.records {
+		if x.Version == r.Version {
+			return nil
+		}
+	}
+	h.records = append(h.records, r)
+	return nil
+}
+
+// useMigrations swaps in ms and a history holding the applied versions, and
+// returns the history along with the order in which Up functions ran.
+func useMigrations(t *testing.T, ms []Migration, applied ...int) (*fakeHistory, *[]int) {
+	t.Helper()
+	h := &fakeHistory{}
+	for _, v := range applied {
+		h.records = append(h.records, record{Version: v, AppliedAt: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)})
+	}
+	var ran []int
+	for i := range ms {
+		v, up := ms[i].Version, ms[i].Up
+		ms[i].Up = func(ctx context.Context) error {
+			ran = append(ran, v)
+			if up != nil {
+				return up(ctx)
+			}
+			return nil
+		}
+	}
+	savedAll, savedHistory := all, history
+	all, history = ms, h
+	t.Cleanup(func() { all, history = savedAll, savedHistory })
+	return h, &ran
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "first", Description: "already applied"},
+		{Version: 2, Name: "second", Description: "counts its documents", Pending: func(context.Context) (int64, error) { return 3, nil }},
+		{Version: 3, Name: "third", Description: "no count"},
+	}
+}
+
+func TestDryRunAppliesNothing(t *testing.T) {
+	h, ran := useMigrations(t, testMigrations(), 1)
+
+	var out bytes.Buffer
+	n, err := Up(context.Background(), true, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(*ran) != 0 || len(h.records) != 1 {
+		t.Fatalf("dry run: reported %d, ran %v, %d records", n, *ran, len(h.records))
+	}
+	want := "would apply 0002 second: counts its documents (3 documents)\nwould apply 0003 third: no count\n"
+	if out.String() != want {
+		t.Fatalf("dry run output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestUpAppliesPendingInOrder(t *testing.T) {
+	h, ran := useMigrations(t, testMigrations(), 1)
+	ctx := context.Background()
+
+	var status bytes.Buffer
+	if err := PrintStatus(ctx, &status); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(status.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "applied 2025-08-01T00:00:00Z") || !strings.HasSuffix(lines[1], "pending") || !strings.HasSuffix(lines[2], "pending") {
+		t.Fatalf("status before Up:\n%s", status.String())
+	}
+	if pending, err := Pending(ctx); err != nil || pending != 2 {
+		t.Fatalf("Pending = %d, %v", pending, err)
+	}
+
+	var out bytes.Buffer
+	n, err := Up(ctx, false, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(*ran) != 2 || (*ran)[0] != 2 || (*ran)[1] != 3 || len(h.records) != 3 {
+		t.Fatalf("Up: applied %d, ran %v, %d records", n, *ran, len(h.records))
+	}
+	if !strings.HasPrefix(out.String(), "applied 0002 second in ") || !strings.Contains(out.String(), "\napplied 0003 third in ") {
+		t.Fatalf("Up output:\n%s", out.String())
+	}
+
+	status.Reset()
+	if err := PrintStatus(ctx, &status); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(status.String(), "pending") {
+		t.Fatalf("status after Up:\n%s", status.String())
+	}
+	if n, err := Up(ctx, false, &out); err != nil || n != 0 || len(*ran) != 2 {
+		t.Fatalf("second Up: applied %d, ran %v, %v", n, *ran, err)
+	}
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	ms := testMigrations()
+	ms[1].Up = func(context.Context) error { return errors.New("boom") }
+	h, ran := useMigrations(t, ms, 1)
+
+	n, err := Up(context.Background(), false, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "0002 second: boom") {
+		t.Fatalf("Up error = %v", err)
+	}
+	if n != 0 || len(*ran) != 1 || len(h.records) != 1 {
+		t.Fatalf("after failure: applied %d, ran %v, %d records", n, *ran, len(h.records))
+	}
+}
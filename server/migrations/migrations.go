@@ -0,0 +1,274 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Subomi7/todoist-clone/server/db"
+)
+
+// all lists every migration. Append new ones with the next version; never edit
+// or remove a migration that has shipped.
+var all = []Migration{
+	{
+		Version:     1,
+		Name:        "create_indexes",
+		Description: "create the indexes previously built at startup",
+		Up:          createIndexes,
+	},
+	{
+		Version:     2,
+		Name:        "project_timestamps",
+		Description: "rename project created_at/updated_at to createdAt/updatedAt, matching tasks",
+		Pending: func(ctx context.Context) (int64, error) {
+			return db.ProjectsCol().CountDocuments(ctx, snakeCaseTimestamps)
+		},
+		Up: renameProjectTimestamps,
+	},
+	{
+		Version:     3,
+		Name:        "personal_workspaces",
+		Description: "move projects and tasks that predate workspaces into their owner's personal workspace",
+		Pending: func(ctx context.Context) (int64, error) {
+			p, err := db.ProjectsCol().CountDocuments(ctx, withoutWorkspace)
+			if err != nil {
+				return 0, err
+			}
+			t, err := db.TasksCol().CountDocuments(ctx, withoutWorkspace)
+			return p + t, err
+		},
+		Up: migratePersonalWorkspaces,
+	},
+	{
+		Version:     4,
+		Name:        "rate_limit_indexes",
+		Description: "index rate limit counters by key and window, and expire them after their window",
+		Up:          createRateLimitIndexes,
+	},
+	{
+		Version:     5,
+		Name:        "listing_indexes",
+		Description: "index the default task and project orders so cursor pages seek instead of skipping",
+		Up:          createListingIndexes,
+	},
+	{
+		Version:     6,
+		Name:        "project_names_per_workspace",
+		Description: "make project names unique per workspace instead of per user",
+		Up:          scopeProjectNamesByWorkspace,
+	},
+}
+
+var (
+	snakeCaseTimestamps = bson.M{"$or": bson.A{
+		bson.M{"created_at": bson.M{"$exists": true}},
+		bson.M{"updated_at": bson.M{"$exists": true}},
+	}}
+	withoutWorkspace = bson.M{"workspaceId": bson.M{"$exists": false}}
+)
+
+func createIndexes(ctx context.Context) error {
+	indexes := []struct {
+		collection string
+		models     []mongo.IndexModel
+	}{
+		{"users", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// external identities are unique across users; the partial filter skips password-only users
+			{
+				Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+				Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+					"identities.subject": bson.M{"$exists": true},
+				}),
+			},
+		}},
+		{"refresh_tokens", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		}},
+		{"personal_access_tokens", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		}},
+		{"login_attempts", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "unlock_token_hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		}},
+		{"oidc_states", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "state_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		}},
+		{"oauth_clients", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "client_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "owner_id", Value: 1}}},
+		}},
+		{"oauth_codes", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "code_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		}},
+		{"oauth_tokens", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "grant_id", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		}},
+		{"oauth_consents", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "client_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		}},
+		{"admin_audit_log", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "target_user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		}},
+		// one personal workspace per user; memberships are unique per workspace
+		{"workspaces", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "owner_id", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"personal": true})},
+		}},
+		{"workspace_members", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		}},
+		{"projects", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "workspaceId", Value: 1}}},
+		}},
+		{"tasks", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "userId", Value: 1}}},
+			{Keys: bson.D{{Key: "projectId", Value: 1}}},
+			{Keys: bson.D{{Key: "workspaceId", Value: 1}}},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "projectId", Value: 1}}},
+		}},
+	}
+
+	// creating an index that already exists with the same options is a no-op
+	for _, ix := range indexes {
+		if _, err := db.GetCollection(ix.collection).Indexes().CreateMany(ctx, ix.models); err != nil {
+			return fmt.Errorf("create %s indexes: %w", ix.collection, err)
+		}
+	}
+	return nil
+}
+
+func createRateLimitIndexes(ctx context.Context) error {
+	_, err := db.RateLimitsCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "window_start", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("create rate_limits indexes: %w", err)
+	}
+	return nil
+}
+
+func createListingIndexes(ctx context.Context) error {
+	newestFirst := func(scope string) mongo.IndexModel {
+		return mongo.IndexModel{Keys: bson.D{{Key: scope, Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}}
+	}
+	if _, err := db.TasksCol().Indexes().CreateMany(ctx, []mongo.IndexModel{newestFirst("userId"), newestFirst("workspaceId")}); err != nil {
+		return fmt.Errorf("create tasks listing indexes: %w", err)
+	}
+	if _, err := db.ProjectsCol().Indexes().CreateMany(ctx, []mongo.IndexModel{newestFirst("userId"), newestFirst("workspaceId")}); err != nil {
+		return fmt.Errorf("create projects listing indexes: %w", err)
+	}
+	return nil
+}
+
+// renameProjectTimestamps moves project timestamps to the camelCase fields tasks
+// use. UpdateProject used to write updatedAt next to updated_at, so an existing
+// updatedAt is the most recent value and wins.
+func renameProjectTimestamps(ctx context.Context) error {
+	_, err := db.ProjectsCol().UpdateMany(ctx, snakeCaseTimestamps, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"createdAt": bson.M{"$ifNull": bson.A{"$createdAt", "$created_at"}},
+			"updatedAt": bson.M{"$ifNull": bson.A{"$updatedAt", "$updated_at"}},
+		}}},
+		{{Key: "$unset", Value: bson.A{"created_at", "updated_at"}}},
+	})
+	return err
+}
+
+// migratePersonalWorkspaces gives every user owning unassigned projects or tasks a
+// personal workspace and moves that data into it.
+func migratePersonalWorkspaces(ctx context.Context) error {
+	ids, err := db.ProjectsCol().Distinct(ctx, "userId", withoutWorkspace)
+	if err != nil {
+		return err
+	}
+	taskOwners, err := db.TasksCol().Distinct(ctx, "userId", withoutWorkspace)
+	if err != nil {
+		return err
+	}
+	ids = append(ids, taskOwners...)
+
+	seen := map[primitive.ObjectID]bool{}
+	for _, v := range ids {
+		userID, ok := v.(primitive.ObjectID)
+		if !ok || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		wsID, err := ensurePersonalWorkspace(ctx, userID)
+		if err != nil {
+			return err
+		}
+		unassigned := bson.M{"userId": userID, "workspaceId": bson.M{"$exists": false}}
+		if _, err := db.ProjectsCol().UpdateMany(ctx, unassigned, bson.M{"$set": bson.M{"workspaceId": wsID}}); err != nil {
+			return err
+		}
+		if _, err := db.TasksCol().UpdateMany(ctx, unassigned, bson.M{"$set": bson.M{"workspaceId": wsID}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensurePersonalWorkspace upserts userID's personal workspace and owner
+// membership as they were shaped when personal_workspaces shipped. It does not
+// go through the store, so later changes there cannot change what this
+// migration writes.
+func ensurePersonalWorkspace(ctx context.Context, userID primitive.ObjectID) (primitive.ObjectID, error) {
+	now := time.Now().UTC()
+	var ws struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err := db.WorkspacesCol().FindOneAndUpdate(ctx,
+		bson.M{"owner_id": userID, "personal": true},
+		bson.M{"$setOnInsert": bson.M{"name": "Personal", "created_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&ws)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	_, err = db.WorkspaceMembersCol().UpdateOne(ctx,
+		bson.M{"workspace_id": ws.ID, "user_id": userID},
+		bson.M{"$setOnInsert": bson.M{"role": "owner", "joined_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	return ws.ID, err
+}
+
+// scopeProjectNamesByWorkspace replaces the per-user unique name index from
+// create_indexes, so the same name can be used in two workspaces.
+func scopeProjectNamesByWorkspace(ctx context.Context) error {
+	_, err := db.ProjectsCol().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "workspaceId", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("create projects name index: %w", err)
+	}
+	// a rerun after a failure part way finds the old index already gone
+	var cmdErr mongo.CommandError
+	if _, err := db.ProjectsCol().Indexes().DropOne(ctx, "userId_1_name_1"); err != nil && !(errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound") {
+		return fmt.Errorf("drop projects userId_1_name_1 index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import "testing"
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	names := map[string]bool{}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Errorf("migration %q has version %d, want %d", m.Name, m.Version, i+1)
+		}
+		if m.Name == "" || names[m.Name] {
+			t.Errorf("migration %d: missing or duplicate name %q", m.Version, m.Name)
+		}
+		names[m.Name] = true
+		if m.Up == nil {
+			t.Errorf("migration %d %s has no Up", m.Version, m.Name)
+		}
+	}
+}
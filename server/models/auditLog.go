@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminAuditEntry records an administrative action for later review.
+type AdminAuditEntry struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	AdminID      primitive.ObjectID  `bson:"admin_id" json:"admin_id"`
+	Action       string              `bson:"action" json:"action"`
+	TargetUserID *primitive.ObjectID `bson:"target_user_id,omitempty" json:"target_user_id,omitempty"`
+	Details      string              `bson:"details,omitempty" json:"details,omitempty"`
+	IP           string              `bson:"ip" json:"ip"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LoginAttempt tracks consecutive failed logins for one key, e.g. "email:<address>"
+// or "ip:<address>". Documents expire through a TTL index on ExpiresAt.
+type LoginAttempt struct {
+	Key             string    `bson:"key" json:"key"`
+	Failures        int       `bson:"failures" json:"failures"`
+	LastFailure     time.Time `bson:"last_failure" json:"last_failure"`
+	LockedUntil     time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	UnlockTokenHash string    `bson:"unlock_token_hash,omitempty" json:"-"`
+	ExpiresAt       time.Time `bson:"expires_at" json:"expires_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OAuthClient is a third-party application registered to access users' data
+// through the OAuth2 authorization code flow. Public clients (no secret) rely
+// on PKCE alone.
+type OAuthClient struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ClientID     string             `bson:"client_id" json:"client_id"`
+	SecretHash   string             `bson:"secret_hash,omitempty" json:"-"`
+	Name         string             `bson:"name" json:"name"`
+	RedirectURIs []string           `bson:"redirect_uris" json:"redirect_uris"`
+	Scopes       []string           `bson:"scopes" json:"scopes"` // scopes the client may request
+	Confidential bool               `bson:"confidential" json:"confidential"`
+	OwnerID      primitive.ObjectID `bson:"owner_id" json:"owner_id"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// OAuthAuthCode is a single-use authorization code bound to the PKCE challenge
+// and redirect URI of the authorization request.
+type OAuthAuthCode struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CodeHash      string             `bson:"code_hash" json:"-"`
+	ClientID      string             `bson:"client_id" json:"client_id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RedirectURI   string             `bson:"redirect_uri" json:"redirect_uri"`
+	Scopes        []string           `bson:"scopes" json:"scopes"`
+	CodeChallenge string             `bson:"code_challenge" json:"-"`
+	GrantID       primitive.ObjectID `bson:"grant_id,omitempty" json:"-"` // set when redeemed
+	UsedAt        *time.Time         `bson:"used_at,omitempty" json:"-"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt     time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// OAuthToken is an opaque access or refresh token issued to a client. Tokens
+// from the same authorization share a GrantID so they can be revoked together.
+type OAuthToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	Kind      string             `bson:"kind" json:"kind"` // "access" or "refresh"
+	GrantID   primitive.ObjectID `bson:"grant_id" json:"grant_id"`
+	ClientID  string             `bson:"client_id" json:"client_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Scopes    []string           `bson:"scopes" json:"scopes"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// OAuthConsent records which scopes a user already granted to a client.
+type OAuthConsent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ClientID  string             `bson:"client_id" json:"client_id"`
+	Scopes    []string           `bson:"scopes" json:"scopes"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OIDCState is the server-side half of an in-flight OpenID Connect login. It is
+// looked up by the hash of the state parameter and deleted on first use.
+type OIDCState struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	StateHash    string             `bson:"state_hash" json:"-"`
+	Provider     string             `bson:"provider" json:"provider"`
+	Nonce        string             `bson:"nonce" json:"-"`
+	CodeVerifier string             `bson:"code_verifier" json:"-"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt    time.Time          `bson:"expires_at" json:"expires_at"`
+}
@@ -9,9 +9,15 @@ import (
 type Project struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	WorkspaceID *primitive.ObjectID `bson:"workspaceId,omitempty" json:"workspaceId,omitempty"`
 	Name        string              `bson:"name" json:"name"`
 	Description string              `bson:"description" json:"description"`
 	IsSystem    bool                `bson:"is_system" json:"is_system"`
-	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
-}
\ No newline at end of file
+	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+// ProjectWithCount is a project plus the number of its open tasks.
+type ProjectWithCount struct {
+	Project   `bson:",inline"`
+	TaskCount int `bson:"taskCount" json:"taskCount"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RateLimitCounter counts the requests of one key, e.g. "write:user:<id>", in
+// the fixed window starting at WindowStart. Documents expire through a TTL
+// index on ExpiresAt once their window is over.
+type RateLimitCounter struct {
+	Key         string    `bson:"key" json:"key"`
+	WindowStart time.Time `bson:"window_start" json:"window_start"`
+	Count       int64     `bson:"count" json:"count"`
+	ExpiresAt   time.Time `bson:"expires_at" json:"expires_at"`
+}
@@ -6,9 +6,43 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User roles. Documents created before roles existed have no role and are treated as RoleUser.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Email       string             `json:"email" bson:"email"`
 	PasswordHash string             `json:"passwordHash" bson:"password_hash"`
 	CreatedAt   time.Time         `json:"createdAt" bson:"created_at"`
+	Role        string            `json:"role" bson:"role,omitempty"`
+
+	// Disabled accounts cannot sign in or refresh their session.
+	Disabled   bool       `json:"disabled" bson:"disabled,omitempty"`
+	DisabledAt *time.Time `json:"disabledAt,omitempty" bson:"disabled_at,omitempty"`
+
+	// TOTP two-factor authentication. TOTPSecret is set on enrollment and only
+	// takes effect once the user confirms it with a valid code (TOTPEnabled).
+	TOTPSecret        string   `json:"-" bson:"totp_secret,omitempty"`
+	TOTPEnabled       bool     `json:"totpEnabled" bson:"totp_enabled"`
+	TOTPLastStep      uint64   `json:"-" bson:"totp_last_step,omitempty"`
+	RecoveryCodeHashes []string `json:"-" bson:"recovery_code_hashes,omitempty"`
+
+	// Identities links external OpenID Connect accounts to this user.
+	Identities []ExternalIdentity `json:"identities,omitempty" bson:"identities,omitempty"`
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// ExternalIdentity is an account at an OIDC provider, identified by the
+// provider's stable subject identifier rather than the (mutable) email.
+type ExternalIdentity struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"subject" bson:"subject"`
+	Email    string    `json:"email" bson:"email"`
+	LinkedAt time.Time `json:"linkedAt" bson:"linked_at"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Workspace roles, from most to least privileged.
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
+// PersonalWorkspaceName is the name given to every user's personal workspace.
+const PersonalWorkspaceName = "Personal"
+
+// Workspace owns projects and is shared by its members. Every user has exactly one
+// personal workspace, which cannot be shared or deleted.
+type Workspace struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Personal  bool               `bson:"personal" json:"personal"`
+	OwnerID   primitive.ObjectID `bson:"owner_id" json:"owner_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WorkspaceMember grants a user a role in a workspace.
+type WorkspaceMember struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role        string             `bson:"role" json:"role"`
+	JoinedAt    time.Time          `bson:"joined_at" json:"joined_at"`
+}
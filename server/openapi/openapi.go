@@ -0,0 +1,326 @@
+// Package openapi builds an OpenAPI 3 document from the app's route table and
+// a description of each operation, and serves it with a small docs page.
+//
+// Paths and path parameters come from the registered Fiber routes; request and
+// response schemas are reflected from the DTO and model types named in each
+// Operation, including the constraints of their validate tags. Drift reports
+// routes without a description and descriptions without a route, so a test can
+// keep the two in step.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Version is the OpenAPI version of the generated document.
+const Version = "3.0.3"
+
+// Operation describes one route. Body and Response hold zero values of the
+// types exchanged (or Data/OneOf descriptions); nil means no body.
+type Operation struct {
+	Summary     string
+	Tag         string // defaults to the first path segment after /api
+	Public      bool   // callable without a bearer token
+	Query       []Param
+	Body        any
+	Form        bool // Body is sent as application/x-www-form-urlencoded
+	Status      int  // success status, 200 when zero
+	Response    any
+	ContentType string // success media type, application/json when empty
+	Error       any    // error body (application/json) for routes that do not use Spec.Error
+}
+
+// Param is a query parameter.
+type Param struct {
+	Name        string
+	Type        string // string, integer or boolean
+	Description string
+}
+
+// QueryOf lists the fields of the struct v that carry a query tag, for
+// handlers that read their parameters with c.QueryParser.
+func QueryOf(v any) []Param {
+	t := reflect.TypeOf(v)
+	var params []Param
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("query"); name != "" {
+			params = append(params, Param{Name: name, Type: newSchemas().typeSchema(t.Field(i).Type).Type})
+		}
+	}
+	return params
+}
+
+// Info is the document's info object.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Spec is everything the document is built from besides the route table.
+type Spec struct {
+	Info       Info
+	Operations map[string]Operation // keyed by Key(method, path)
+	Error      any                  // body of error responses (application/problem+json) unless the Operation names its own
+}
+
+// Document is the generated OpenAPI document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Key names a route the way Operations are keyed: "GET /api/tasks/:id". Group
+// routes registered as "/" lose their trailing slash.
+func Key(method, path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return method + " " + path
+}
+
+var documented = map[string]bool{
+	fiber.MethodGet: true, fiber.MethodPost: true, fiber.MethodPut: true,
+	fiber.MethodPatch: true, fiber.MethodDelete: true,
+}
+
+// routeKeys returns the keys of the app's handler routes, without the HEAD
+// routes Fiber adds for every GET.
+func routeKeys(routes []fiber.Route) map[string]fiber.Route {
+	keys := map[string]fiber.Route{}
+	for _, r := range routes {
+		if documented[r.Method] {
+			keys[Key(r.Method, r.Path)] = r
+		}
+	}
+	return keys
+}
+
+// Drift lists every route without an Operation and every Operation without a
+// route, sorted; empty when the spec matches the app.
+func (s *Spec) Drift(routes []fiber.Route) []string {
+	keys := routeKeys(routes)
+	var drift []string
+	for key := range keys {
+		if _, ok := s.Operations[key]; !ok {
+			drift = append(drift, "undocumented route "+key)
+		}
+	}
+	for key := range s.Operations {
+		if _, ok := keys[key]; !ok {
+			drift = append(drift, "documented route does not exist: "+key)
+		}
+	}
+	sort.Strings(drift)
+	return drift
+}
+
+// Build generates the document for routes. Routes without an Operation are
+// still listed, with their path parameters only.
+func (s *Spec) Build(routes []fiber.Route) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    s.Info,
+		Paths:   map[string]map[string]*operation{},
+		Components: components{
+			SecuritySchemes: map[string]securityScheme{
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT or personal access token"},
+			},
+		},
+	}
+	reg := newSchemas()
+	var errorSchema *Schema
+	if s.Error != nil {
+		errorSchema = reg.of(s.Error)
+	}
+
+	routeByKey := routeKeys(routes)
+	keys := make([]string, 0, len(routeByKey))
+	for key := range routeByKey {
+		keys = append(keys, key)
+	}
+	// a fixed order keeps schema names stable when two types share a name
+	sort.Strings(keys)
+	for _, key := range keys {
+		r, op := routeByKey[key], s.Operations[key]
+		path, params := openAPIPath(r)
+		out := &operation{
+			OperationID: operationID(r.Method, path),
+			Summary:     op.Summary,
+			Tags:        []string{op.Tag},
+			Parameters:  params,
+			Responses:   map[string]response{},
+		}
+		if op.Tag == "" {
+			out.Tags = []string{defaultTag(path)}
+		}
+		if !op.Public {
+			out.Security = []map[string][]string{{"bearer": {}}}
+		}
+		for _, q := range op.Query {
+			out.Parameters = append(out.Parameters, parameter{
+				Name: q.Name, In: "query", Description: q.Description, Schema: &Schema{Type: q.Type},
+			})
+		}
+		if op.Body != nil {
+			media := fiber.MIMEApplicationJSON
+			if op.Form {
+				media = fiber.MIMEApplicationForm
+			}
+			out.RequestBody = &requestBody{Required: true, Content: map[string]mediaType{media: {Schema: reg.of(op.Body)}}}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = fiber.StatusOK
+		}
+		ok := response{Description: http.StatusText(status)}
+		if op.Response != nil {
+			media := op.ContentType
+			if media == "" {
+				media = fiber.MIMEApplicationJSON
+			}
+			ok.Content = map[string]mediaType{media: {Schema: reg.of(op.Response)}}
+		}
+		out.Responses[strconv.Itoa(status)] = ok
+		if op.Error != nil {
+			out.Responses["default"] = response{
+				Description: "Error",
+				Content:     map[string]mediaType{fiber.MIMEApplicationJSON: {Schema: reg.of(op.Error)}},
+			}
+		} else if errorSchema != nil {
+			out.Responses["default"] = response{
+				Description: "Error",
+				Content:     map[string]mediaType{"application/problem+json": {Schema: errorSchema}},
+			}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*operation{}
+		}
+		doc.Paths[path][strings.ToLower(r.Method)] = out
+	}
+	doc.Components.Schemas = reg.byName
+	return doc
+}
+
+// openAPIPath turns "/api/tasks/:id" into "/api/tasks/{id}" plus its parameters.
+func openAPIPath(r fiber.Route) (string, []parameter) {
+	path := r.Path
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	var params []parameter
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") {
+			name := strings.TrimSuffix(seg[1:], "?")
+			segs[i] = "{" + name + "}"
+			params = append(params, parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// defaultTag groups /api/tasks/{id} under "tasks" and /healthz under "healthz".
+func defaultTag(path string) string {
+	segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if segs[0] == "api" && len(segs) > 1 {
+		return segs[1]
+	}
+	return strings.TrimPrefix(segs[0], ".")
+}
+
+// operationID derives a stable id: "get_api_tasks_id".
+func operationID(method, path string) string {
+	id := strings.ToLower(method) + path
+	id = strings.NewReplacer("/", "_", "{", "", "}", "", ".", "", "-", "_").Replace(id)
+	return id
+}
+
+//go:embed docs.html
+var docsPage []byte
+
+// Handler serves the document as JSON. It is built from the app's routes on
+// the first request, once every route has been registered.
+func (s *Spec) Handler() fiber.Handler {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(c *fiber.Ctx) error {
+		once.Do(func() {
+			body, err = json.Marshal(s.Build(c.App().GetRoutes(true)))
+		})
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return c.Send(body)
+	}
+}
+
+// DocsHandler serves a self-contained page that renders the document found at
+// specURL; it loads nothing from third-party hosts.
+func DocsHandler(specURL string) fiber.Handler {
+	page := strings.Replace(string(docsPage), "{{SPEC_URL}}", specURL, 1)
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.SendString(page)
+	}
+}
@@ -0,0 +1,165 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type meta struct {
+	Page int `json:"page"`
+}
+
+type item struct {
+	ID       primitive.ObjectID `json:"id"`
+	Title    string             `json:"title" validate:"required,max=20"`
+	Priority int                `json:"priority,omitempty" validate:"omitempty,oneof=1 2 3"`
+	Due      *time.Time         `json:"dueDate,omitempty"`
+	Tags     []string           `json:"tags"`
+	Secret   string             `json:"-"`
+	internal string
+}
+
+type itemView struct {
+	*item
+	Role string `json:"role"`
+}
+
+type list struct {
+	Data []itemView `json:"data"`
+	Meta meta       `json:"meta"`
+}
+
+func noop(c *fiber.Ctx) error { return nil }
+
+func newApp() *fiber.App {
+	app := fiber.New()
+	g := app.Group("/api/items")
+	g.Get("/", noop)
+	g.Post("/", noop)
+	g.Get("/:id", noop)
+	return app
+}
+
+var spec = &Spec{
+	Info: Info{Title: "test", Version: "1"},
+	Operations: map[string]Operation{
+		"GET /api/items":     {Response: list{}, Error: meta{}},
+		"POST /api/items":    {Body: item{}, Status: fiber.StatusCreated, Response: Data(item{})},
+		"GET /api/items/:id": {Public: true, Response: Data(itemView{})},
+	},
+	Error: Message{},
+}
+
+func TestDrift(t *testing.T) {
+	app := newApp()
+	if drift := spec.Drift(app.GetRoutes(true)); len(drift) != 0 {
+		t.Fatalf("unexpected drift: %v", drift)
+	}
+
+	app.Delete("/api/items/:id", noop)
+	stale := &Spec{Operations: map[string]Operation{"GET /api/items": {}, "GET /api/items/:id": {}, "POST /api/items": {}, "PUT /api/items/:id": {}}}
+	want := []string{"documented route does not exist: PUT /api/items/:id", "undocumented route DELETE /api/items/:id"}
+	if drift := stale.Drift(app.GetRoutes(true)); !reflect.DeepEqual(drift, want) {
+		t.Fatalf("drift = %q, want %q", drift, want)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	doc := spec.Build(newApp().GetRoutes(true))
+	// round-trip through JSON to check the served shape
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Parameters []struct{ Name, In string } `json:"parameters"`
+			Security   []map[string][]string       `json:"security"`
+			Responses  map[string]json.RawMessage  `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]*Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.OpenAPI != Version {
+		t.Errorf("openapi = %q", out.OpenAPI)
+	}
+	get := out.Paths["/api/items/{id}"]["get"]
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].In != "path" {
+		t.Errorf("path parameters = %+v", get.Parameters)
+	}
+	if get.Security != nil {
+		t.Errorf("public operation has security %v", get.Security)
+	}
+	post := out.Paths["/api/items"]["post"]
+	if post.Security == nil {
+		t.Error("operation without Public has no security")
+	}
+	if _, ok := post.Responses["201"]; !ok {
+		t.Errorf("responses = %v, want 201", post.Responses)
+	}
+	if _, ok := post.Responses["default"]; !ok {
+		t.Error("no default error response")
+	}
+	var def struct {
+		Content map[string]json.RawMessage `json:"content"`
+	}
+	json.Unmarshal(post.Responses["default"], &def)
+	if _, ok := def.Content["application/problem+json"]; !ok {
+		t.Errorf("default error content = %v, want application/problem+json", def.Content)
+	}
+	var own struct {
+		Content map[string]json.RawMessage `json:"content"`
+	}
+	json.Unmarshal(out.Paths["/api/items"]["get"].Responses["default"], &own)
+	if _, ok := own.Content[fiber.MIMEApplicationJSON]; !ok || len(own.Content) != 1 {
+		t.Errorf("operation error body not used: %v", own.Content)
+	}
+
+	s := out.Components.Schemas["item"]
+	if s == nil {
+		t.Fatalf("schemas = %v", out.Components.Schemas)
+	}
+	if !reflect.DeepEqual(s.Required, []string{"title"}) {
+		t.Errorf("required = %v", s.Required)
+	}
+	if p := s.Properties["title"]; p.MaxLength == nil || *p.MaxLength != 20 {
+		t.Errorf("title = %+v", p)
+	}
+	if p := s.Properties["priority"]; !reflect.DeepEqual(p.Enum, []any{float64(1), float64(2), float64(3)}) {
+		t.Errorf("priority enum = %v", p.Enum)
+	}
+	if p := s.Properties["id"]; p.Type != "string" || p.Pattern != objectIDPattern {
+		t.Errorf("id = %+v", p)
+	}
+	if p := s.Properties["dueDate"]; p.Format != "date-time" {
+		t.Errorf("dueDate = %+v", p)
+	}
+	for _, hidden := range []string{"Secret", "-", "internal"} {
+		if _, ok := s.Properties[hidden]; ok {
+			t.Errorf("property %q should not be documented", hidden)
+		}
+	}
+	// embedded structs are flattened into the outer object
+	if v := out.Components.Schemas["itemView"]; v == nil || v.Properties["title"] == nil || v.Properties["role"] == nil {
+		t.Errorf("itemView = %+v", v)
+	}
+}
+
+func TestKey(t *testing.T) {
+	for path, want := range map[string]string{"/api/items/": "GET /api/items", "/": "GET /", "/api/items/:id": "GET /api/items/:id"} {
+		if got := Key(fiber.MethodGet, path); got != want {
+			t.Errorf("Key(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+// Schema is an OpenAPI 3.0 schema object, limited to what Go types need.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	MinLength            *int64             `json:"minLength,omitempty"`
+	MaxLength            *int64             `json:"maxLength,omitempty"`
+	Minimum              *int64             `json:"minimum,omitempty"`
+	Maximum              *int64             `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+const objectIDPattern = "^[0-9a-f]{24}$"
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// envelope is the {"data": ...} wrapper handlers build with fiber.Map.
+type envelope struct{ data any }
+
+// Data describes a response written as fiber.Map{"data": v}.
+func Data(v any) any { return envelope{data: v} }
+
+// oneOf describes a response that takes one of several shapes.
+type oneOf []any
+
+// OneOf describes a response that is one of vs, such as tokens or an MFA challenge.
+func OneOf(vs ...any) any { return oneOf(vs) }
+
+// Message is the body of handlers that answer fiber.Map{"message": "..."}.
+type Message struct {
+	Message string `json:"message"`
+}
+
+// schemas collects the named schemas referenced by the document.
+type schemas struct {
+	byName map[string]*Schema
+	names  map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{byName: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// of returns the schema of a Data/OneOf description or of a Go value's type.
+func (s *schemas) of(v any) *Schema {
+	switch v := v.(type) {
+	case envelope:
+		return &Schema{Type: "object", Properties: map[string]*Schema{"data": s.of(v.data)}, Required: []string{"data"}}
+	case oneOf:
+		out := &Schema{}
+		for _, alt := range v {
+			out.OneOf = append(out.OneOf, s.of(alt))
+		}
+		return out
+	}
+	return s.typeSchema(reflect.TypeOf(v))
+}
+
+func (s *schemas) typeSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: objectIDPattern}
+	case rawJSONType:
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.register(t)}
+	}
+	// interfaces: any JSON value
+	return &Schema{}
+}
+
+// register adds the named struct t to the components once and returns its name.
+func (s *schemas) register(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := s.byName[name]; taken {
+		// the same name in two packages: models.PaginationMeta and handlers.PaginationMeta
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = string(unicode.ToUpper(rune(pkg[0]))) + pkg[1:] + name
+	}
+	s.names[t] = name
+	s.byName[name] = nil // reserve before recursing, for self-referencing types
+	s.byName[name] = s.structSchema(t)
+	return name
+}
+
+func (s *schemas) structSchema(t reflect.Type) *Schema {
+	out := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(out, t)
+	return out
+}
+
+func (s *schemas) addFields(out *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && tag == "" {
+			// embedded structs are flattened by encoding/json
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.addFields(out, ft)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		name := validate.JSONName(sf)
+		prop := s.typeSchema(sf.Type)
+		for _, r := range validate.Rules(sf) {
+			if r.Name == validate.Required {
+				out.Required = append(out.Required, name)
+			}
+			constrain(prop, r)
+		}
+		out.Properties[name] = prop
+	}
+}
+
+// constrain copies one validate rule onto the property's schema.
+func constrain(p *Schema, r validate.Rule) {
+	n, _ := strconv.ParseInt(r.Arg, 10, 64)
+	switch r.Name {
+	case "min":
+		if p.Type == "string" {
+			p.MinLength = &n
+		} else {
+			p.Minimum = &n
+		}
+	case "max":
+		if p.Type == "string" {
+			p.MaxLength = &n
+		} else {
+			p.Maximum = &n
+		}
+	case "maxbytes":
+		// JSON Schema counts characters; a byte limit can only be described
+		p.Description = "at most " + r.Arg + " bytes of UTF-8"
+	case "notblank", validate.Required:
+		if p.Type == "string" {
+			one := int64(1)
+			p.MinLength = &one
+		}
+	case "oneof":
+		for _, opt := range strings.Fields(r.Arg) {
+			if p.Type == "integer" {
+				v, _ := strconv.ParseInt(opt, 10, 64)
+				p.Enum = append(p.Enum, v)
+			} else {
+				p.Enum = append(p.Enum, opt)
+			}
+		}
+	case "email":
+		p.Format = "email"
+	case "rfc3339":
+		p.Format = "date-time"
+	case "objectid":
+		p.Pattern = objectIDPattern
+	}
+}
@@ -0,0 +1,179 @@
+package router_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+	"github.com/Subomi7/todoist-clone/server/handlers"
+)
+
+type adminUser struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Disabled bool   `json:"disabled"`
+}
+
+// findUser looks a user up through the admin search and returns their id.
+func findUser(t *testing.T, app *fiber.App, admin, email string) string {
+	t.Helper()
+	var res struct{ Data []adminUser }
+	expectStatus(t, call(t, app, "GET", "/api/admin/users?search="+email, admin, nil, &res), fiber.StatusOK)
+	if len(res.Data) != 1 || res.Data[0].Email != email {
+		t.Fatalf("search %s = %+v", email, res.Data)
+	}
+	return res.Data[0].ID
+}
+
+// expectCode checks the status and problem code of an error response.
+func expectCode(t *testing.T, app *fiber.App, method, path, token string, status int, code string) {
+	t.Helper()
+	var p handlers.Problem
+	expectStatus(t, call(t, app, method, path, token, nil, &p), status)
+	if p.Code != code {
+		t.Fatalf("%s %s: code %q, want %q", method, path, p.Code, code)
+	}
+}
+
+// jwtClaims decodes the payload of a JWT without verifying it.
+func jwtClaims(t *testing.T, token string, out interface{}) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("not a JWT: %q", token)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func auditActions(t *testing.T, app *fiber.App, admin, userID string) []string {
+	t.Helper()
+	var res struct {
+		Data []struct {
+			AdminID string `json:"admin_id"`
+			Action  string `json:"action"`
+		}
+	}
+	expectStatus(t, call(t, app, "GET", "/api/admin/audit?userId="+userID, admin, nil, &res), fiber.StatusOK)
+	var actions []string
+	for _, e := range res.Data {
+		actions = append(actions, e.Action+" by "+e.AdminID)
+	}
+	return actions
+}
+
+func TestRequireAdmin(t *testing.T) {
+	app := newTestApp(t)
+	user := signUp(t, app, "alice@example.com")
+	admin := signUpAdmin(t, app, "root@example.com")
+	aliceID := findUser(t, app, admin, "alice@example.com")
+
+	for _, path := range []string{"/api/admin/users", "/api/admin/users/" + aliceID, "/api/admin/audit", "/api/admin/stats"} {
+		expectCode(t, app, "GET", path, user, fiber.StatusForbidden, "forbidden")
+	}
+	expectCode(t, app, "POST", "/api/admin/users/"+aliceID+"/disable", user, fiber.StatusForbidden, "forbidden")
+	expectCode(t, app, "GET", "/api/admin/users", "", fiber.StatusUnauthorized, "unauthorized")
+}
+
+// Registration does not prove the address, so claiming one listed in
+// ADMIN_EMAILS must not grant the admin role.
+func TestRegisterNeverGrantsAdmin(t *testing.T) {
+	cfg := config.Default()
+	cfg.AdminEmails = []string{"root@example.com"}
+	handlers.UseConfig(cfg)
+	t.Cleanup(func() { handlers.UseConfig(config.Default()) })
+	app := newTestApp(t)
+
+	squatter := signUp(t, app, "root@example.com")
+	expectCode(t, app, "GET", "/api/admin/users", squatter, fiber.StatusForbidden, "forbidden")
+}
+
+func TestAdminDisableEnable(t *testing.T) {
+	app := newTestApp(t)
+	user := signUp(t, app, "alice@example.com")
+	admin := signUpAdmin(t, app, "root@example.com")
+	aliceID := findUser(t, app, admin, "alice@example.com")
+	rootID := findUser(t, app, admin, "root@example.com")
+
+	expectCode(t, app, "POST", "/api/admin/users/"+rootID+"/disable", admin, fiber.StatusBadRequest, "not_allowed")
+
+	var got struct{ Data adminUser }
+	expectStatus(t, call(t, app, "POST", "/api/admin/users/"+aliceID+"/disable", admin, nil, &got), fiber.StatusOK)
+	if !got.Data.Disabled {
+		t.Fatalf("disable: %+v", got.Data)
+	}
+	// the session issued before the disable stops working at once
+	expectCode(t, app, "GET", "/api/tasks", user, fiber.StatusForbidden, "account_disabled")
+	creds := fiber.Map{"email": "alice@example.com", "password": "correct horse"}
+	expectStatus(t, call(t, app, "POST", "/api/auth/login", "", creds, nil), fiber.StatusForbidden)
+	expectCode(t, app, "POST", "/api/admin/users/"+aliceID+"/impersonate", admin, fiber.StatusBadRequest, "account_disabled")
+
+	expectStatus(t, call(t, app, "POST", "/api/admin/users/"+aliceID+"/enable", admin, nil, &got), fiber.StatusOK)
+	if got.Data.Disabled {
+		t.Fatalf("enable: %+v", got.Data)
+	}
+	expectStatus(t, call(t, app, "POST", "/api/auth/login", "", creds, nil), fiber.StatusOK)
+
+	want := []string{"user.enable by " + rootID, "user.disable by " + rootID}
+	if got := auditActions(t, app, admin, aliceID); strings.Join(got, ";") != strings.Join(want, ";") {
+		t.Fatalf("audit = %v, want %v", got, want)
+	}
+	var page struct {
+		Data []json.RawMessage `json:"data"`
+		Meta meta              `json:"meta"`
+	}
+	expectStatus(t, call(t, app, "GET", "/api/admin/audit?userId="+aliceID+"&page=2&pageSize=1", admin, nil, &page), fiber.StatusOK)
+	if len(page.Data) != 1 || page.Meta.Page != 2 || page.Meta.PageSize != 1 || page.Meta.Total != 2 {
+		t.Fatalf("audit page 2 = %d entries, meta %+v", len(page.Data), page.Meta)
+	}
+}
+
+func TestAdminImpersonate(t *testing.T) {
+	app := newTestApp(t)
+	user := signUp(t, app, "alice@example.com")
+	admin := signUpAdmin(t, app, "root@example.com")
+	aliceID := findUser(t, app, admin, "alice@example.com")
+	rootID := findUser(t, app, admin, "root@example.com")
+	createTask(t, app, user, fiber.Map{"title": "Alice's task"})
+
+	var tok tokenResponse
+	expectStatus(t, call(t, app, "POST", "/api/admin/users/"+aliceID+"/impersonate", admin, nil, &tok), fiber.StatusOK)
+	var claims struct {
+		UserID string `json:"user_id"`
+		Act    struct {
+			Sub string `json:"sub"`
+		} `json:"act"`
+	}
+	jwtClaims(t, tok.AccessToken, &claims)
+	if claims.UserID != aliceID || claims.Act.Sub != rootID {
+		t.Fatalf("impersonation claims = %+v, want user %s acted on by %s", claims, aliceID, rootID)
+	}
+	if tok.RefreshToken != "" {
+		t.Fatal("impersonation issued a refresh token")
+	}
+
+	// the token acts as Alice, with none of the administrator's rights
+	var tasks taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks", tok.AccessToken, nil, &tasks), fiber.StatusOK)
+	if tasks.Meta.Total != 1 {
+		t.Fatalf("impersonated task list: %+v", tasks)
+	}
+	expectCode(t, app, "GET", "/api/admin/users", tok.AccessToken, fiber.StatusForbidden, "forbidden")
+
+	if got := auditActions(t, app, admin, aliceID); len(got) != 1 || got[0] != "user.impersonate by "+rootID {
+		t.Fatalf("audit = %v", got)
+	}
+
+	// disabling Alice ends the impersonation session too
+	expectStatus(t, call(t, app, "POST", "/api/admin/users/"+aliceID+"/disable", admin, nil, nil), fiber.StatusOK)
+	expectCode(t, app, "GET", "/api/tasks", tok.AccessToken, fiber.StatusForbidden, "account_disabled")
+}
@@ -0,0 +1,121 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/handlers"
+	"github.com/Subomi7/todoist-clone/server/models"
+	"github.com/Subomi7/todoist-clone/server/openapi"
+)
+
+// Spec is the OpenAPI description of the routes in SetupRoutes, served at
+// /api/openapi.json. Every route needs an entry in Spec.Operations; the router
+// tests fail on any route that is added, removed or renamed without one.
+var Spec = &openapi.Spec{
+	Info: openapi.Info{
+		Title:       "Todoist clone API",
+		Version:     "1.0.0",
+		Description: "Tasks, projects and workspaces. Errors are application/problem+json with a stable code.",
+	},
+	Operations: operations,
+	Error:      handlers.Problem{},
+}
+
+type refreshTokenBody struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type registerResponse struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+var (
+	pageQuery = []openapi.Param{
+		{Name: "page", Type: "integer", Description: "1-based page number"},
+		{Name: "pageSize", Type: "integer", Description: "items per page, at most 100"},
+	}
+	cursorQuery = append([]openapi.Param{
+		{Name: "cursor", Type: "string", Description: "resume after meta.nextCursor of the previous page; empty for the first page"},
+		{Name: "total", Type: "boolean", Description: "count all matches; on by default without a cursor"},
+	}, pageQuery...)
+	taskQuery = append([]openapi.Param{
+		{Name: "completed", Type: "boolean"},
+		{Name: "search", Type: "string", Description: "case-insensitive substring of the title or description, matched literally; at most 100 characters"},
+		{Name: "sortBy", Type: "string", Description: "comma-separated fields to sort on, each prefixed with - for descending: createdAt, updatedAt, dueDate, priority, title, completed (default -createdAt)"},
+		{Name: "projectId", Type: "string"},
+	}, cursorQuery...)
+)
+
+var operations = map[string]openapi.Operation{
+	"GET /.well-known/jwks.json": {Summary: "Public keys that verify access tokens", Public: true, Response: handlers.JWKSet{}},
+	"GET /healthz":               {Summary: "Liveness probe", Public: true, Response: map[string]string{}},
+	"GET /readyz":                {Summary: "Readiness probe; 503 with the same body when not ready", Public: true, Response: handlers.ReadinessResponse{}, Error: handlers.ReadinessResponse{}},
+	"GET /metrics":               {Summary: "Prometheus metrics", Public: true, Response: "", ContentType: fiber.MIMETextPlain},
+	"GET /api/openapi.json":      {Summary: "This document", Tag: "docs", Public: true, Response: map[string]any{}},
+	"GET /api/docs":              {Summary: "API reference page", Public: true, Response: "", ContentType: fiber.MIMETextHTML},
+
+	"POST /api/auth/register":               {Summary: "Create an account", Public: true, Body: handlers.RegisterRequest{}, Status: fiber.StatusCreated, Response: registerResponse{}},
+	"POST /api/auth/login":                  {Summary: "Log in; answers with a challenge when two-factor authentication is on", Public: true, Body: handlers.LoginRequest{}, Response: openapi.OneOf(handlers.TokenResponse{}, handlers.MFAChallengeResponse{})},
+	"POST /api/auth/refresh":                {Summary: "Rotate the refresh token (body or cookie)", Public: true, Body: refreshTokenBody{}, Response: handlers.TokenResponse{}},
+	"POST /api/auth/logout":                 {Summary: "Revoke the refresh token (body or cookie)", Public: true, Body: refreshTokenBody{}, Response: openapi.Message{}},
+	"POST /api/auth/unlock":                 {Summary: "Unlock an account with the emailed token", Public: true, Body: handlers.UnlockRequest{}, Response: openapi.Message{}},
+	"GET /api/auth/oidc/providers":          {Summary: "Configured identity providers", Public: true, Response: openapi.Data([]string{})},
+	"GET /api/auth/oidc/:provider/login":    {Summary: "Redirect to the identity provider", Public: true, Status: fiber.StatusFound},
+	"GET /api/auth/oidc/:provider/callback": {Summary: "Finish an identity provider login", Public: true, Query: []openapi.Param{{Name: "code", Type: "string"}, {Name: "state", Type: "string"}, {Name: "error", Type: "string"}}, Response: openapi.OneOf(handlers.TokenResponse{}, handlers.MFAChallengeResponse{})},
+	"POST /api/auth/mfa/verify":             {Summary: "Exchange an MFA challenge and code for tokens", Public: true, Body: handlers.MFAVerifyRequest{}, Response: handlers.TokenResponse{}},
+	"POST /api/auth/mfa/enroll":             {Summary: "Start TOTP enrollment", Response: handlers.MFAEnrollResponse{}},
+	"POST /api/auth/mfa/confirm":            {Summary: "Confirm TOTP enrollment and get recovery codes", Body: handlers.MFACodeRequest{}, Response: handlers.RecoveryCodesResponse{}},
+	"POST /api/auth/mfa/disable":            {Summary: "Turn two-factor authentication off", Body: handlers.MFADisableRequest{}, Response: openapi.Message{}},
+	"POST /api/auth/tokens":                 {Summary: "Create a personal access token", Body: handlers.CreatePersonalTokenRequest{}, Status: fiber.StatusCreated, Response: handlers.PersonalTokenResponse{}},
+	"GET /api/auth/tokens":                  {Summary: "List personal access tokens", Response: handlers.PersonalTokensListResponse{}},
+	"DELETE /api/auth/tokens/:id":           {Summary: "Revoke a personal access token", Status: fiber.StatusNoContent},
+
+	"POST /api/oauth/token":               {Summary: "OAuth 2.0 token endpoint", Public: true, Body: handlers.TokenRequest{}, Form: true, Response: handlers.OAuthTokenResponse{}, Error: handlers.OAuthErrorResponse{}},
+	"POST /api/oauth/revoke":              {Summary: "OAuth 2.0 token revocation", Public: true, Body: handlers.TokenRequest{}, Form: true, Error: handlers.OAuthErrorResponse{}},
+	"POST /api/oauth/introspect":          {Summary: "OAuth 2.0 token introspection", Public: true, Body: handlers.TokenRequest{}, Form: true, Response: handlers.IntrospectionResponse{}, Error: handlers.OAuthErrorResponse{}},
+	"GET /api/oauth/authorize":            {Summary: "Describe an authorization request for the consent screen", Query: openapi.QueryOf(handlers.AuthorizeRequest{}), Response: handlers.ConsentResponse{}},
+	"POST /api/oauth/authorize":           {Summary: "Approve or deny an authorization request", Body: handlers.AuthorizeRequest{}, Response: handlers.AuthorizeResponse{}},
+	"POST /api/oauth/clients":             {Summary: "Register an OAuth client", Body: handlers.CreateOAuthClientRequest{}, Status: fiber.StatusCreated, Response: handlers.OAuthClientResponse{}},
+	"GET /api/oauth/clients":              {Summary: "List your OAuth clients", Response: openapi.Data([]models.OAuthClient{})},
+	"DELETE /api/oauth/clients/:clientId": {Summary: "Delete an OAuth client", Status: fiber.StatusNoContent},
+
+	"GET /api/account/export": {Summary: "Download all account data", Response: []byte{}, ContentType: "application/zip"},
+	"DELETE /api/account":     {Summary: "Delete the account and all its data", Body: handlers.DeleteAccountRequest{}, Status: fiber.StatusNoContent},
+
+	"POST /api/tasks":       {Summary: "Create a task", Body: handlers.CreateTaskDTO{}, Status: fiber.StatusCreated, Response: handlers.TaskResponse{}},
+	"GET /api/tasks":        {Summary: "List tasks", Query: append([]openapi.Param{{Name: "inbox", Type: "boolean", Description: "only tasks in the Inbox"}}, taskQuery...), Response: handlers.TasksListResponse{}},
+	"GET /api/tasks/:id":    {Summary: "Get a task", Response: handlers.TaskResponse{}},
+	"PUT /api/tasks/:id":    {Summary: "Update a task; omitted fields are unchanged", Body: handlers.UpdateTaskDTO{}, Response: handlers.TaskResponse{}},
+	"DELETE /api/tasks/:id": {Summary: "Delete a task", Status: fiber.StatusNoContent},
+
+	"POST /api/projects":       {Summary: "Create a project", Body: handlers.CreateProjectDTO{}, Status: fiber.StatusCreated, Response: handlers.ProjectResponse{}},
+	"GET /api/projects":        {Summary: "List projects with their task counts", Query: cursorQuery, Response: handlers.ProjectsListResponse{}},
+	"GET /api/projects/:id":    {Summary: "Get a project", Response: handlers.ProjectResponse{}},
+	"PUT /api/projects/:id":    {Summary: "Rename a project", Body: handlers.UpdateProjectDTO{}, Response: handlers.ProjectResponse{}},
+	"DELETE /api/projects/:id": {Summary: "Delete a project; its tasks move to their owners' Inbox", Status: fiber.StatusNoContent},
+
+	"POST /api/workspaces":                       {Summary: "Create a workspace", Body: handlers.WorkspaceDTO{}, Status: fiber.StatusCreated, Response: openapi.Data(handlers.WorkspaceView{})},
+	"GET /api/workspaces":                        {Summary: "List your workspaces", Response: openapi.Data([]handlers.WorkspaceView{})},
+	"GET /api/workspaces/:id":                    {Summary: "Get a workspace", Response: openapi.Data(handlers.WorkspaceView{})},
+	"PUT /api/workspaces/:id":                    {Summary: "Rename a workspace", Body: handlers.WorkspaceDTO{}, Response: openapi.Data(handlers.WorkspaceView{})},
+	"DELETE /api/workspaces/:id":                 {Summary: "Delete a workspace", Status: fiber.StatusNoContent},
+	"GET /api/workspaces/:id/members":            {Summary: "List members", Response: openapi.Data([]handlers.WorkspaceMemberView{})},
+	"POST /api/workspaces/:id/members":           {Summary: "Add a member by email", Body: handlers.AddWorkspaceMemberDTO{}, Status: fiber.StatusCreated, Response: openapi.Data(handlers.WorkspaceMemberView{})},
+	"PUT /api/workspaces/:id/members/:userId":    {Summary: "Change a member's role", Body: handlers.UpdateWorkspaceMemberDTO{}, Response: openapi.Message{}},
+	"DELETE /api/workspaces/:id/members/:userId": {Summary: "Remove a member", Status: fiber.StatusNoContent},
+	"GET /api/workspaces/:id/projects":           {Summary: "List the workspace's projects", Query: cursorQuery, Response: handlers.ProjectsListResponse{}},
+	"POST /api/workspaces/:id/projects":          {Summary: "Create a project in the workspace", Body: handlers.CreateProjectDTO{}, Status: fiber.StatusCreated, Response: handlers.ProjectResponse{}},
+	"GET /api/workspaces/:id/tasks":              {Summary: "List the workspace's tasks", Query: taskQuery, Response: handlers.TasksListResponse{}},
+
+	"POST /api/admin/users/unlock":          {Summary: "Unlock an account by email", Body: handlers.AdminUnlockRequest{}, Response: openapi.Message{}},
+	"GET /api/admin/users":                  {Summary: "List users", Query: append([]openapi.Param{{Name: "search", Type: "string"}, {Name: "role", Type: "string"}, {Name: "disabled", Type: "boolean"}}, pageQuery...), Response: handlers.AdminUsersListResponse{}},
+	"GET /api/admin/users/:id":              {Summary: "Get a user", Response: openapi.Data(handlers.AdminUserView{})},
+	"POST /api/admin/users/:id/disable":     {Summary: "Disable a user and revoke their sessions", Response: openapi.Data(handlers.AdminUserView{})},
+	"POST /api/admin/users/:id/enable":      {Summary: "Re-enable a user", Response: openapi.Data(handlers.AdminUserView{})},
+	"POST /api/admin/users/:id/logout":      {Summary: "Revoke every session of a user", Response: openapi.Message{}},
+	"POST /api/admin/users/:id/role":        {Summary: "Change a user's role", Body: handlers.AdminSetRoleRequest{}, Response: openapi.Data(handlers.AdminUserView{})},
+	"POST /api/admin/users/:id/impersonate": {Summary: "Get a short-lived access token as a user", Response: handlers.TokenResponse{}},
+	"GET /api/admin/audit":                  {Summary: "Audit log, newest first", Query: append([]openapi.Param{{Name: "userId", Type: "string"}}, pageQuery...), Response: openapi.Data([]models.AdminAuditEntry{})},
+	"GET /api/admin/stats":                  {Summary: "User and content counts", Response: openapi.Data(handlers.AdminStats{})},
+}
@@ -2,27 +2,105 @@ package router
 
 import (
 	"github.com/Subomi7/todoist-clone/server/handlers"
+	"github.com/Subomi7/todoist-clone/server/metrics"
+	"github.com/Subomi7/todoist-clone/server/openapi"
 	"github.com/gofiber/fiber/v2"
 )
 
+// SetupRoutes registers every route. Auth and OAuth endpoints are rate limited
+// per IP; the authenticated groups per user, with separate read and write quotas.
 func SetupRoutes(app *fiber.App) {
+	app.Get("/.well-known/jwks.json", handlers.JWKS)
+
+	// probes stay outside /api so they never need credentials
+	app.Get("/healthz", handlers.Healthz)
+	app.Get("/readyz", handlers.Readyz)
+	app.Get("/metrics", metrics.Handler())
+
 	api := app.Group("/api")
+	api.Get("/openapi.json", Spec.Handler())
+	api.Get("/docs", openapi.DocsHandler("/api/openapi.json"))
 
-	auth := api.Group("/auth")
+	auth := api.Group("/auth", handlers.RateLimitAuth())
 	auth.Post("/register", handlers.Register)
 	auth.Post("/login", handlers.Login)
+	auth.Post("/refresh", handlers.Refresh)
+	auth.Post("/logout", handlers.Logout)
+	auth.Post("/unlock", handlers.UnlockAccount)
+	auth.Get("/oidc/providers", handlers.ListOIDCProviders)
+	auth.Get("/oidc/:provider/login", handlers.OIDCLogin)
+	auth.Get("/oidc/:provider/callback", handlers.OIDCCallback)
+	// registered before the JWT-protected mfa group so the challenge exchange stays public
+	auth.Post("/mfa/verify", handlers.VerifyMFA)
+
+	mfa := auth.Group("/mfa", handlers.JWTMiddleware(), handlers.SessionOnly())
+	mfa.Post("/enroll", handlers.EnrollTOTP)
+	mfa.Post("/confirm", handlers.ConfirmTOTP)
+	mfa.Post("/disable", handlers.DisableTOTP)
+
+	tokens := auth.Group("/tokens", handlers.JWTMiddleware(), handlers.SessionOnly())
+	tokens.Post("/", handlers.CreatePersonalToken)
+	tokens.Get("/", handlers.ListPersonalTokens)
+	tokens.Delete("/:id", handlers.RevokePersonalToken)
+
+	oauth := api.Group("/oauth", handlers.RateLimitAuth())
+	oauth.Post("/token", handlers.OAuthTokenEndpoint)
+	oauth.Post("/revoke", handlers.OAuthRevoke)
+	oauth.Post("/introspect", handlers.OAuthIntrospect)
 
-	taskGroup := api.Group("/tasks", handlers.JWTMiddleware())
+	consent := oauth.Group("/authorize", handlers.JWTMiddleware(), handlers.SessionOnly())
+	consent.Get("/", handlers.GetAuthorization)
+	consent.Post("/", handlers.Authorize)
+
+	oauthClients := oauth.Group("/clients", handlers.JWTMiddleware(), handlers.SessionOnly())
+	oauthClients.Post("/", handlers.CreateOAuthClient)
+	oauthClients.Get("/", handlers.ListOAuthClients)
+	oauthClients.Delete("/:clientId", handlers.DeleteOAuthClient)
+
+	account := api.Group("/account", handlers.JWTMiddleware(), handlers.RateLimit(), handlers.SessionOnly())
+	account.Get("/export", handlers.ExportAccount)
+	account.Delete("/", handlers.DeleteAccount)
+
+	taskGroup := api.Group("/tasks", handlers.JWTMiddleware(), handlers.RateLimit(), handlers.RequireScope("tasks"))
 	taskGroup.Post("/", handlers.CreateTask)
 	taskGroup.Get("/", handlers.GetTasks)
 	taskGroup.Get("/:id", handlers.GetTask)
 	taskGroup.Put("/:id", handlers.UpdateTask)
 	taskGroup.Delete("/:id", handlers.DeleteTask)
 
-	projects := api.Group("/projects", handlers.JWTMiddleware())
+	projects := api.Group("/projects", handlers.JWTMiddleware(), handlers.RateLimit(), handlers.RequireScope("projects"))
 	projects.Post("/", handlers.CreateProject)
 	projects.Get("/", handlers.GetProjects)
 	projects.Get("/:id", handlers.GetProject)
 	projects.Put("/:id", handlers.UpdateProject)
 	projects.Delete("/:id", handlers.DeleteProject)
+
+	// guarded per route: deleting a workspace and managing its members need a
+	// session, and the task listing falls under the tasks scope
+	workspaces := api.Group("/workspaces", handlers.JWTMiddleware(), handlers.RateLimit())
+	projectScope, sessionOnly := handlers.RequireScope("projects"), handlers.SessionOnly()
+	workspaces.Post("/", projectScope, handlers.CreateWorkspace)
+	workspaces.Get("/", projectScope, handlers.ListWorkspaces)
+	workspaces.Get("/:id", projectScope, handlers.GetWorkspace)
+	workspaces.Put("/:id", projectScope, handlers.UpdateWorkspace)
+	workspaces.Delete("/:id", sessionOnly, handlers.DeleteWorkspace)
+	workspaces.Get("/:id/members", sessionOnly, handlers.ListWorkspaceMembers)
+	workspaces.Post("/:id/members", sessionOnly, handlers.AddWorkspaceMember)
+	workspaces.Put("/:id/members/:userId", sessionOnly, handlers.UpdateWorkspaceMember)
+	workspaces.Delete("/:id/members/:userId", sessionOnly, handlers.RemoveWorkspaceMember)
+	workspaces.Get("/:id/projects", projectScope, handlers.GetWorkspaceProjects)
+	workspaces.Post("/:id/projects", projectScope, handlers.CreateWorkspaceProject)
+	workspaces.Get("/:id/tasks", handlers.RequireScope("tasks"), handlers.GetWorkspaceTasks)
+
+	admin := api.Group("/admin", handlers.JWTMiddleware(), handlers.RateLimit(), handlers.SessionOnly(), handlers.RequireAdmin())
+	admin.Post("/users/unlock", handlers.AdminUnlockAccount)
+	admin.Get("/users", handlers.AdminListUsers)
+	admin.Get("/users/:id", handlers.AdminGetUser)
+	admin.Post("/users/:id/disable", handlers.AdminDisableUser)
+	admin.Post("/users/:id/enable", handlers.AdminEnableUser)
+	admin.Post("/users/:id/logout", handlers.AdminForceLogout)
+	admin.Post("/users/:id/role", handlers.AdminSetRole)
+	admin.Post("/users/:id/impersonate", handlers.AdminImpersonate)
+	admin.Get("/audit", handlers.AdminAuditLog)
+	admin.Get("/stats", handlers.AdminGetStats)
 }
@@ -0,0 +1,819 @@
+package router_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Subomi7/todoist-clone/server/config"
+	"github.com/Subomi7/todoist-clone/server/handlers"
+	"github.com/Subomi7/todoist-clone/server/router"
+	"github.com/Subomi7/todoist-clone/server/store"
+	"github.com/Subomi7/todoist-clone/server/validate"
+)
+
+// newTestApp builds the full API on a fresh in-memory store.
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+	if err := handlers.InitKeys(config.JWTConfig{Secret: "test-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	handlers.UseStores(store.NewMemory())
+
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	router.SetupRoutes(app)
+	return app
+}
+
+// call sends a JSON request, authenticated when token is set, and decodes the
+// JSON response into out when out is non-nil.
+func call(t *testing.T, app *fiber.App, method, path, token string, body, out interface{}) *http.Response {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, raw, err)
+		}
+	}
+	return resp
+}
+
+func expectStatus(t *testing.T, resp *http.Response, want int) {
+	t.Helper()
+	if resp.StatusCode != want {
+		t.Fatalf("%s %s: status %d, want %d", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, want)
+	}
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type task struct {
+	ID          string `json:"id"`
+	UserID      string `json:"userId"`
+	ProjectID   string `json:"projectId"`
+	InboxID     string `json:"inboxId"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Completed   bool   `json:"completed"`
+}
+
+type project struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TaskCount int    `json:"taskCount"`
+}
+
+type meta struct {
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor"`
+}
+
+type taskList struct {
+	Data []task `json:"data"`
+	Meta meta   `json:"meta"`
+}
+
+type projectList struct {
+	Data []project `json:"data"`
+	Meta meta      `json:"meta"`
+}
+
+// signUp registers and logs in a user, returning the access token.
+func signUp(t *testing.T, app *fiber.App, email string) string {
+	t.Helper()
+	creds := fiber.Map{"email": email, "password": "correct horse"}
+	expectStatus(t, call(t, app, "POST", "/api/auth/register", "", creds, nil), fiber.StatusCreated)
+	var tok tokenResponse
+	expectStatus(t, call(t, app, "POST", "/api/auth/login", "", creds, &tok), fiber.StatusOK)
+	if tok.AccessToken == "" {
+		t.Fatal("login returned no access token")
+	}
+	return tok.AccessToken
+}
+
+func createTask(t *testing.T, app *fiber.App, token string, body fiber.Map) task {
+	t.Helper()
+	var res struct{ Data task }
+	expectStatus(t, call(t, app, "POST", "/api/tasks", token, body, &res), fiber.StatusCreated)
+	return res.Data
+}
+
+func createProject(t *testing.T, app *fiber.App, token, name string) project {
+	t.Helper()
+	var res struct{ Data project }
+	expectStatus(t, call(t, app, "POST", "/api/projects", token, fiber.Map{"name": name}, &res), fiber.StatusCreated)
+	return res.Data
+}
+
+func TestRegisterLoginRefresh(t *testing.T) {
+	app := newTestApp(t)
+	creds := fiber.Map{"email": "Alice@Example.com", "password": "correct horse"}
+
+	expectStatus(t, call(t, app, "POST", "/api/auth/register", "", creds, nil), fiber.StatusCreated)
+	var dup handlers.Problem
+	resp := call(t, app, "POST", "/api/auth/register", "", creds, &dup)
+	expectStatus(t, resp, fiber.StatusConflict)
+	if resp.Header.Get("Content-Type") != handlers.MIMEProblemJSON || dup.Code != handlers.CodeEmailTaken {
+		t.Fatalf("duplicate email: %s %+v", resp.Header.Get("Content-Type"), dup)
+	}
+	var invalid handlers.Problem
+	expectStatus(t, call(t, app, "POST", "/api/auth/register", "", fiber.Map{"email": "bob", "password": "short"}, &invalid), fiber.StatusBadRequest)
+	if invalid.Code != handlers.CodeValidationFailed || len(invalid.Errors) != 2 || invalid.Errors[0].Field != "email" || invalid.Errors[1].Code != validate.TooShort {
+		t.Fatalf("field errors: %+v", invalid)
+	}
+
+	expectStatus(t, call(t, app, "POST", "/api/auth/login", "", fiber.Map{"email": "alice@example.com", "password": "wrong password"}, nil), fiber.StatusUnauthorized)
+	expectStatus(t, call(t, app, "POST", "/api/auth/login", "", fiber.Map{"email": "nobody@example.com", "password": "correct horse"}, nil), fiber.StatusUnauthorized)
+
+	var tok tokenResponse
+	resp = call(t, app, "POST", "/api/auth/login", "", creds, &tok)
+	expectStatus(t, resp, fiber.StatusOK)
+	var refresh string
+	for _, c := range resp.Cookies() {
+		if c.Name == handlers.RefreshCookieName {
+			refresh = c.Value
+		}
+	}
+	if tok.AccessToken == "" || refresh == "" {
+		t.Fatalf("login: access token %q, refresh cookie %q", tok.AccessToken, refresh)
+	}
+
+	expectStatus(t, call(t, app, "GET", "/api/tasks", "", nil, nil), fiber.StatusUnauthorized)
+	expectStatus(t, call(t, app, "GET", "/api/tasks", "not-a-token", nil, nil), fiber.StatusUnauthorized)
+	expectStatus(t, call(t, app, "GET", "/api/tasks", tok.AccessToken, nil, nil), fiber.StatusOK)
+
+	// refresh tokens rotate: the old one stops working once exchanged
+	var rotated tokenResponse
+	expectStatus(t, call(t, app, "POST", "/api/auth/refresh", "", fiber.Map{"refresh_token": refresh}, &rotated), fiber.StatusOK)
+	if rotated.AccessToken == "" || rotated.RefreshToken == "" || rotated.RefreshToken == refresh {
+		t.Fatalf("refresh: got %+v", rotated)
+	}
+	expectStatus(t, call(t, app, "GET", "/api/tasks", rotated.AccessToken, nil, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "POST", "/api/auth/refresh", "", fiber.Map{"refresh_token": refresh}, nil), fiber.StatusUnauthorized)
+
+	expectStatus(t, call(t, app, "POST", "/api/auth/logout", "", fiber.Map{"refresh_token": rotated.RefreshToken}, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "POST", "/api/auth/refresh", "", fiber.Map{"refresh_token": rotated.RefreshToken}, nil), fiber.StatusUnauthorized)
+}
+
+func TestRegisterPasswordByteLimit(t *testing.T) {
+	app := newTestApp(t)
+
+	// 25 characters but 75 bytes: past bcrypt's 72 byte limit
+	var tooLong handlers.Problem
+	long := fiber.Map{"email": "alice@example.com", "password": strings.Repeat("日", 25)}
+	expectStatus(t, call(t, app, "POST", "/api/auth/register", "", long, &tooLong), fiber.StatusBadRequest)
+	if len(tooLong.Errors) != 1 || tooLong.Errors[0].Field != "password" || tooLong.Errors[0].Code != validate.TooLong {
+		t.Fatalf("field errors: %+v", tooLong)
+	}
+
+	creds := fiber.Map{"email": "alice@example.com", "password": strings.Repeat("日", 24)}
+	expectStatus(t, call(t, app, "POST", "/api/auth/register", "", creds, nil), fiber.StatusCreated)
+	expectStatus(t, call(t, app, "POST", "/api/auth/login", "", creds, nil), fiber.StatusOK)
+}
+
+func TestTaskCRUD(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+
+	expectStatus(t, call(t, app, "POST", "/api/tasks", token, fiber.Map{"title": "  "}, nil), fiber.StatusBadRequest)
+	expectStatus(t, call(t, app, "POST", "/api/tasks", token, fiber.Map{"title": "x", "priority": 7}, nil), fiber.StatusBadRequest)
+	expectStatus(t, call(t, app, "POST", "/api/tasks", token, fiber.Map{"title": "x", "dueDate": "tomorrow"}, nil), fiber.StatusBadRequest)
+	expectStatus(t, call(t, app, "POST", "/api/tasks", token, fiber.Map{"title": strings.Repeat("x", 201)}, nil), fiber.StatusBadRequest)
+
+	var invalid handlers.Problem
+	expectStatus(t, call(t, app, "POST", "/api/tasks", token, fiber.Map{"title": "x", "userId": "someone-else"}, &invalid), fiber.StatusBadRequest)
+	if len(invalid.Errors) != 1 || invalid.Errors[0].Field != "userId" || invalid.Errors[0].Code != validate.Unknown {
+		t.Fatalf("unknown field: %+v", invalid)
+	}
+	invalid = handlers.Problem{}
+	expectStatus(t, call(t, app, "POST", "/api/tasks", token, fiber.Map{"title": "", "priority": 9, "projectId": "nope"}, &invalid), fiber.StatusBadRequest)
+	if len(invalid.Errors) != 3 {
+		t.Fatalf("want every field error at once, got %+v", invalid)
+	}
+
+	created := createTask(t, app, token, fiber.Map{"title": "Buy milk", "description": "2 litres", "priority": 2})
+	if created.ID == "" || created.Title != "Buy milk" || created.Completed {
+		t.Fatalf("create: got %+v", created)
+	}
+
+	var got struct{ Data task }
+	expectStatus(t, call(t, app, "GET", "/api/tasks/"+created.ID, token, nil, &got), fiber.StatusOK)
+	if got.Data != created {
+		t.Fatalf("get: got %+v, want %+v", got.Data, created)
+	}
+
+	var updated struct{ Data task }
+	expectStatus(t, call(t, app, "PUT", "/api/tasks/"+created.ID, token, fiber.Map{"title": "Buy oat milk", "completed": true}, &updated), fiber.StatusOK)
+	if updated.Data.Title != "Buy oat milk" || !updated.Data.Completed || updated.Data.Description != "2 litres" {
+		t.Fatalf("update: got %+v", updated.Data)
+	}
+	expectStatus(t, call(t, app, "PUT", "/api/tasks/"+created.ID, token, fiber.Map{}, nil), fiber.StatusBadRequest)
+	expectStatus(t, call(t, app, "PUT", "/api/tasks/"+created.ID, token, fiber.Map{"title": ""}, nil), fiber.StatusBadRequest)
+	expectStatus(t, call(t, app, "PUT", "/api/tasks/"+created.ID, token, fiber.Map{"priority": 4}, nil), fiber.StatusBadRequest)
+	expectStatus(t, call(t, app, "PUT", "/api/tasks/"+created.ID, token, fiber.Map{"priority": "high"}, nil), fiber.StatusBadRequest)
+
+	expectStatus(t, call(t, app, "GET", "/api/tasks/not-an-id", token, nil, nil), fiber.StatusBadRequest)
+	expectStatus(t, call(t, app, "DELETE", "/api/tasks/"+created.ID, token, nil, nil), fiber.StatusNoContent)
+	expectStatus(t, call(t, app, "GET", "/api/tasks/"+created.ID, token, nil, nil), fiber.StatusNotFound)
+	expectStatus(t, call(t, app, "DELETE", "/api/tasks/"+created.ID, token, nil, nil), fiber.StatusNotFound)
+}
+
+func TestProjectCRUD(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+
+	work := createProject(t, app, token, "Work")
+	expectStatus(t, call(t, app, "POST", "/api/projects", token, fiber.Map{"name": "Work"}, nil), fiber.StatusConflict)
+	expectStatus(t, call(t, app, "POST", "/api/projects", token, fiber.Map{"name": ""}, nil), fiber.StatusBadRequest)
+	home := createProject(t, app, token, "Home")
+
+	createTask(t, app, token, fiber.Map{"title": "open", "projectId": work.ID})
+	done := createTask(t, app, token, fiber.Map{"title": "done", "projectId": work.ID})
+	expectStatus(t, call(t, app, "PUT", "/api/tasks/"+done.ID, token, fiber.Map{"completed": true}, nil), fiber.StatusOK)
+
+	var list projectList
+	expectStatus(t, call(t, app, "GET", "/api/projects", token, nil, &list), fiber.StatusOK)
+	counts := map[string]int{}
+	for _, p := range list.Data {
+		counts[p.Name] = p.TaskCount
+	}
+	if list.Meta.Total != 3 || counts["Work"] != 1 || counts["Home"] != 0 || counts["Inbox"] != 0 {
+		t.Fatalf("list: total %d, open task counts %v", list.Meta.Total, counts)
+	}
+
+	var got struct{ Data project }
+	expectStatus(t, call(t, app, "GET", "/api/projects/"+work.ID, token, nil, &got), fiber.StatusOK)
+	if got.Data.Name != "Work" {
+		t.Fatalf("get: got %+v", got.Data)
+	}
+
+	var renamed struct{ Data project }
+	expectStatus(t, call(t, app, "PUT", "/api/projects/"+work.ID, token, fiber.Map{"name": "Office"}, &renamed), fiber.StatusOK)
+	if renamed.Data.Name != "Office" {
+		t.Fatalf("rename: got %+v", renamed.Data)
+	}
+	expectStatus(t, call(t, app, "PUT", "/api/projects/"+home.ID, token, fiber.Map{"name": "Office"}, nil), fiber.StatusConflict)
+
+	expectStatus(t, call(t, app, "DELETE", "/api/projects/"+home.ID, token, nil, nil), fiber.StatusNoContent)
+	expectStatus(t, call(t, app, "GET", "/api/projects/"+home.ID, token, nil, nil), fiber.StatusNotFound)
+}
+
+func TestProjectNamesArePerWorkspace(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+
+	var team struct{ Data struct{ ID string } }
+	expectStatus(t, call(t, app, "POST", "/api/workspaces", token, fiber.Map{"name": "Team"}, &team), fiber.StatusCreated)
+	teamProjects := "/api/workspaces/" + team.Data.ID + "/projects"
+
+	personal := createProject(t, app, token, "Work")
+	var shared struct{ Data project }
+	expectStatus(t, call(t, app, "POST", teamProjects, token, fiber.Map{"name": "Work"}, &shared), fiber.StatusCreated)
+	if shared.Data.ID == personal.ID {
+		t.Fatalf("both workspaces got project %s", shared.Data.ID)
+	}
+	expectStatus(t, call(t, app, "POST", teamProjects, token, fiber.Map{"name": "Work"}, nil), fiber.StatusConflict)
+
+	// a team project called Inbox does not stand in for the personal Inbox
+	inbox := createTask(t, app, token, fiber.Map{"title": "before"}).InboxID
+	var teamInbox struct{ Data project }
+	expectStatus(t, call(t, app, "POST", teamProjects, token, fiber.Map{"name": "Inbox"}, &teamInbox), fiber.StatusCreated)
+	if got := createTask(t, app, token, fiber.Map{"title": "after"}).InboxID; got != inbox || got == teamInbox.Data.ID {
+		t.Fatalf("task went to Inbox %s, personal Inbox is %s", got, inbox)
+	}
+}
+
+func TestDeletingWorkspaceOwnerKeepsSharedWork(t *testing.T) {
+	app := newTestApp(t)
+	alice := signUp(t, app, "alice@example.com")
+	bob := signUp(t, app, "bob@example.com")
+
+	var team struct{ Data struct{ ID string } }
+	expectStatus(t, call(t, app, "POST", "/api/workspaces", alice, fiber.Map{"name": "Team"}, &team), fiber.StatusCreated)
+	base := "/api/workspaces/" + team.Data.ID
+	expectStatus(t, call(t, app, "POST", base+"/members", alice, fiber.Map{"email": "bob@example.com"}, nil), fiber.StatusCreated)
+
+	var work struct{ Data project }
+	expectStatus(t, call(t, app, "POST", base+"/projects", alice, fiber.Map{"name": "Work"}, &work), fiber.StatusCreated)
+	bobs := createTask(t, app, bob, fiber.Map{"title": "Bob's task", "projectId": work.Data.ID})
+
+	expectStatus(t, call(t, app, "DELETE", "/api/account", alice, fiber.Map{"password": "correct horse"}, nil), fiber.StatusNoContent)
+
+	// Bob inherits the workspace, and Alice's project with his task in it
+	var projects projectList
+	expectStatus(t, call(t, app, "GET", base+"/projects", bob, nil, &projects), fiber.StatusOK)
+	if projects.Meta.Total != 1 || projects.Data[0].ID != work.Data.ID {
+		t.Fatalf("heir's projects: %+v", projects)
+	}
+	var tasks taskList
+	expectStatus(t, call(t, app, "GET", base+"/tasks", bob, nil, &tasks), fiber.StatusOK)
+	if tasks.Meta.Total != 1 || tasks.Data[0].ID != bobs.ID || tasks.Data[0].ProjectID != work.Data.ID {
+		t.Fatalf("heir's tasks: %+v", tasks)
+	}
+	expectStatus(t, call(t, app, "PUT", "/api/projects/"+work.Data.ID, bob, fiber.Map{"name": "Ours"}, nil), fiber.StatusOK)
+}
+
+func TestOwnershipIsolation(t *testing.T) {
+	app := newTestApp(t)
+	alice := signUp(t, app, "alice@example.com")
+	bob := signUp(t, app, "bob@example.com")
+
+	proj := createProject(t, app, alice, "Secret plans")
+	tk := createTask(t, app, alice, fiber.Map{"title": "Private", "projectId": proj.ID})
+
+	// other users' resources look exactly like missing ones
+	expectStatus(t, call(t, app, "GET", "/api/tasks/"+tk.ID, bob, nil, nil), fiber.StatusNotFound)
+	expectStatus(t, call(t, app, "PUT", "/api/tasks/"+tk.ID, bob, fiber.Map{"title": "Mine now"}, nil), fiber.StatusNotFound)
+	expectStatus(t, call(t, app, "DELETE", "/api/tasks/"+tk.ID, bob, nil, nil), fiber.StatusNotFound)
+	expectStatus(t, call(t, app, "GET", "/api/projects/"+proj.ID, bob, nil, nil), fiber.StatusNotFound)
+	expectStatus(t, call(t, app, "PUT", "/api/projects/"+proj.ID, bob, fiber.Map{"name": "Mine now"}, nil), fiber.StatusNotFound)
+	expectStatus(t, call(t, app, "DELETE", "/api/projects/"+proj.ID, bob, nil, nil), fiber.StatusNotFound)
+	expectStatus(t, call(t, app, "POST", "/api/tasks", bob, fiber.Map{"title": "Sneaky", "projectId": proj.ID}, nil), fiber.StatusNotFound)
+
+	bobsTask := createTask(t, app, bob, fiber.Map{"title": "Bob's"})
+	expectStatus(t, call(t, app, "PUT", "/api/tasks/"+bobsTask.ID, bob, fiber.Map{"projectId": proj.ID}, nil), fiber.StatusNotFound)
+
+	var tasks taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks", bob, nil, &tasks), fiber.StatusOK)
+	if tasks.Meta.Total != 1 || tasks.Data[0].ID != bobsTask.ID {
+		t.Fatalf("bob's tasks: %+v", tasks)
+	}
+	var projects projectList
+	expectStatus(t, call(t, app, "GET", "/api/projects", bob, nil, &projects), fiber.StatusOK)
+	for _, p := range projects.Data {
+		if p.ID == proj.ID {
+			t.Fatal("bob's project list contains alice's project")
+		}
+	}
+
+	var still struct{ Data task }
+	expectStatus(t, call(t, app, "GET", "/api/tasks/"+tk.ID, alice, nil, &still), fiber.StatusOK)
+	if still.Data.Title != "Private" {
+		t.Fatalf("alice's task changed: %+v", still.Data)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+
+	for i := 1; i <= 5; i++ {
+		createTask(t, app, token, fiber.Map{"title": fmt.Sprintf("task %d", i)})
+		createProject(t, app, token, fmt.Sprintf("project %d", i))
+	}
+
+	seen := map[string]bool{}
+	for page, want := range []int{2, 2, 1, 0} {
+		var list taskList
+		expectStatus(t, call(t, app, "GET", fmt.Sprintf("/api/tasks?page=%d&pageSize=2&sortBy=title", page+1), token, nil, &list), fiber.StatusOK)
+		if len(list.Data) != want || list.Meta.Total != 5 || list.Meta.Page != page+1 || list.Meta.PageSize != 2 {
+			t.Fatalf("tasks page %d: %d items, meta %+v", page+1, len(list.Data), list.Meta)
+		}
+		for i, tk := range list.Data {
+			if wantTitle := fmt.Sprintf("task %d", page*2+i+1); tk.Title != wantTitle {
+				t.Fatalf("tasks page %d: item %d is %q, want %q", page+1, i, tk.Title, wantTitle)
+			}
+			seen[tk.ID] = true
+		}
+	}
+	if len(seen) != 5 {
+		t.Fatalf("pages returned %d distinct tasks, want 5", len(seen))
+	}
+
+	var desc taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks?pageSize=1&sortBy=-title", token, nil, &desc), fiber.StatusOK)
+	if len(desc.Data) != 1 || desc.Data[0].Title != "task 5" {
+		t.Fatalf("descending sort: %+v", desc.Data)
+	}
+
+	// the Inbox created at sign-up counts as a project
+	var projects projectList
+	expectStatus(t, call(t, app, "GET", "/api/projects?page=3&pageSize=2", token, nil, &projects), fiber.StatusOK)
+	if len(projects.Data) != 2 || projects.Meta.Total != 6 {
+		t.Fatalf("projects page 3: %d items, meta %+v", len(projects.Data), projects.Meta)
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+	for i := 1; i <= 5; i++ {
+		createTask(t, app, token, fiber.Map{"title": fmt.Sprintf("task %d", i)})
+		createProject(t, app, token, fmt.Sprintf("project %d", i))
+	}
+
+	var first struct {
+		Data []task
+		Meta map[string]any
+	}
+	expectStatus(t, call(t, app, "GET", "/api/tasks?cursor=&pageSize=2", token, nil, &first), fiber.StatusOK)
+	if len(first.Data) != 2 || first.Data[0].Title != "task 5" || first.Data[1].Title != "task 4" {
+		t.Fatalf("first page: %+v", first.Data)
+	}
+	if _, ok := first.Meta["total"]; ok {
+		t.Fatalf("cursor page counted the total without ?total=true: %v", first.Meta)
+	}
+	next, _ := first.Meta["nextCursor"].(string)
+	if next == "" {
+		t.Fatalf("first page has no next cursor: %v", first.Meta)
+	}
+
+	// a task added between pages must neither shift nor repeat what follows
+	createTask(t, app, token, fiber.Map{"title": "task 6"})
+	var titles []string
+	for next != "" {
+		var list taskList
+		expectStatus(t, call(t, app, "GET", "/api/tasks?pageSize=2&total=true&cursor="+next, token, nil, &list), fiber.StatusOK)
+		if list.Meta.Total != 6 {
+			t.Fatalf("total = %d, want 6", list.Meta.Total)
+		}
+		for _, tk := range list.Data {
+			titles = append(titles, tk.Title)
+		}
+		next = list.Meta.NextCursor
+	}
+	if want := []string{"task 3", "task 2", "task 1"}; fmt.Sprint(titles) != fmt.Sprint(want) {
+		t.Fatalf("later pages = %q, want %q", titles, want)
+	}
+
+	var byTitle taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks?cursor=&pageSize=2&sortBy=title", token, nil, &byTitle), fiber.StatusOK)
+	expectStatus(t, call(t, app, "GET", "/api/tasks?cursor="+byTitle.Meta.NextCursor, token, nil, nil), fiber.StatusBadRequest)
+	expectStatus(t, call(t, app, "GET", "/api/tasks?cursor=not-a-cursor", token, nil, nil), fiber.StatusBadRequest)
+
+	// numbered pages keep their total and offer a cursor to continue from
+	var numbered taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks?page=1&pageSize=4", token, nil, &numbered), fiber.StatusOK)
+	if numbered.Meta.Total != 6 || numbered.Meta.NextCursor == "" {
+		t.Fatalf("numbered page meta %+v", numbered.Meta)
+	}
+
+	// the Inbox created at sign-up counts as a project
+	seen := map[string]bool{}
+	for cursor, pages := "", 0; pages == 0 || cursor != ""; pages++ {
+		var list projectList
+		expectStatus(t, call(t, app, "GET", "/api/projects?pageSize=4&cursor="+cursor, token, nil, &list), fiber.StatusOK)
+		for _, p := range list.Data {
+			seen[p.ID] = true
+		}
+		cursor = list.Meta.NextCursor
+	}
+	if len(seen) != 6 {
+		t.Fatalf("project pages returned %d distinct projects, want 6", len(seen))
+	}
+}
+
+func TestMultiKeySort(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+	createTask(t, app, token, fiber.Map{"title": "b", "priority": 1, "dueDate": "2030-01-02T00:00:00Z"})
+	createTask(t, app, token, fiber.Map{"title": "a", "priority": 3, "dueDate": "2030-01-01T00:00:00Z"})
+	createTask(t, app, token, fiber.Map{"title": "c", "priority": 3, "dueDate": "2030-01-02T00:00:00Z"})
+	createTask(t, app, token, fiber.Map{"title": "d", "priority": 3})
+
+	// tasks without a due date sort first, as in MongoDB
+	var titles []string
+	for cursor, pages := "", 0; pages == 0 || cursor != ""; pages++ {
+		var list taskList
+		expectStatus(t, call(t, app, "GET", "/api/tasks?sortBy=-priority,dueDate&pageSize=1&cursor="+cursor, token, nil, &list), fiber.StatusOK)
+		for _, tk := range list.Data {
+			titles = append(titles, tk.Title)
+		}
+		cursor = list.Meta.NextCursor
+	}
+	if want := []string{"d", "a", "c", "b"}; fmt.Sprint(titles) != fmt.Sprint(want) {
+		t.Fatalf("sortBy=-priority,dueDate: %q, want %q", titles, want)
+	}
+}
+
+func TestListQueryRejectsUnsafeInput(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+	createTask(t, app, token, fiber.Map{"title": "plain task"})
+	createTask(t, app, token, fiber.Map{"title": "price (a+)+$ total", "description": "literal pattern"})
+
+	for _, sortBy := range []string{
+		"password", "userId", "$where", "title.$", "-", "title,", ",title", "title,-title", "priority,,dueDate",
+		"createdAt,updatedAt,dueDate,priority,title,completed,createdAt",
+	} {
+		expectStatus(t, call(t, app, "GET", "/api/tasks?sortBy="+url.QueryEscape(sortBy), token, nil, nil), fiber.StatusBadRequest)
+	}
+
+	// search text is matched literally, never as a pattern
+	for search, want := range map[string]int{
+		"(a+)+$": 1,
+		".*":     0,
+		"[":      0,
+		"PRICE":  1,
+		"task":   1,
+	} {
+		var list taskList
+		expectStatus(t, call(t, app, "GET", "/api/tasks?search="+url.QueryEscape(search), token, nil, &list), fiber.StatusOK)
+		if len(list.Data) != want {
+			t.Errorf("search %q matched %d tasks, want %d", search, len(list.Data), want)
+		}
+	}
+	expectStatus(t, call(t, app, "GET", "/api/tasks?search="+strings.Repeat("a", 101), token, nil, nil), fiber.StatusBadRequest)
+
+	var capped taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks?pageSize=1000000", token, nil, &capped), fiber.StatusOK)
+	if capped.Meta.PageSize != 100 {
+		t.Fatalf("pageSize = %d, want it capped at 100", capped.Meta.PageSize)
+	}
+}
+
+func TestInbox(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+
+	first := createTask(t, app, token, fiber.Map{"title": "Inbox task"})
+	second := createTask(t, app, token, fiber.Map{"title": "Another inbox task"})
+	if first.InboxID == "" || first.ProjectID != first.InboxID || second.ProjectID != first.ProjectID {
+		t.Fatalf("inbox tasks: %+v, %+v", first, second)
+	}
+
+	work := createProject(t, app, token, "Work")
+	inWork := createTask(t, app, token, fiber.Map{"title": "Work task", "projectId": work.ID})
+	if inWork.InboxID != "" {
+		t.Fatalf("project task has inbox marker: %+v", inWork)
+	}
+
+	var inbox taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks?inbox=true", token, nil, &inbox), fiber.StatusOK)
+	if inbox.Meta.Total != 2 {
+		t.Fatalf("inbox listing: %+v", inbox)
+	}
+	var byProject taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks?projectId="+work.ID, token, nil, &byProject), fiber.StatusOK)
+	if byProject.Meta.Total != 1 || byProject.Data[0].ID != inWork.ID {
+		t.Fatalf("project listing: %+v", byProject)
+	}
+	expectStatus(t, call(t, app, "GET", "/api/tasks?inbox=true&projectId="+work.ID, token, nil, nil), fiber.StatusBadRequest)
+
+	// exactly one Inbox, and it cannot be deleted
+	var projects projectList
+	expectStatus(t, call(t, app, "GET", "/api/projects", token, nil, &projects), fiber.StatusOK)
+	inboxes := 0
+	for _, p := range projects.Data {
+		if p.Name == "Inbox" {
+			inboxes++
+			if p.ID != first.InboxID {
+				t.Fatalf("Inbox project %s, tasks point at %s", p.ID, first.InboxID)
+			}
+		}
+	}
+	if inboxes != 1 {
+		t.Fatalf("found %d Inbox projects, want 1", inboxes)
+	}
+	expectStatus(t, call(t, app, "DELETE", "/api/projects/"+first.InboxID, token, nil, nil), fiber.StatusBadRequest)
+}
+
+func TestDeleteProjectReassignsTasksToInbox(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+
+	inbox := createTask(t, app, token, fiber.Map{"title": "Inbox task"}).InboxID
+	work := createProject(t, app, token, "Work")
+	a := createTask(t, app, token, fiber.Map{"title": "a", "projectId": work.ID})
+	b := createTask(t, app, token, fiber.Map{"title": "b", "projectId": work.ID})
+
+	expectStatus(t, call(t, app, "DELETE", "/api/projects/"+work.ID, token, nil, nil), fiber.StatusNoContent)
+	expectStatus(t, call(t, app, "GET", "/api/projects/"+work.ID, token, nil, nil), fiber.StatusNotFound)
+
+	for _, id := range []string{a.ID, b.ID} {
+		var got struct{ Data task }
+		expectStatus(t, call(t, app, "GET", "/api/tasks/"+id, token, nil, &got), fiber.StatusOK)
+		if got.Data.ProjectID != inbox {
+			t.Fatalf("task %s in project %s after delete, want Inbox %s", id, got.Data.ProjectID, inbox)
+		}
+	}
+	var left taskList
+	expectStatus(t, call(t, app, "GET", "/api/tasks?projectId="+work.ID, token, nil, &left), fiber.StatusOK)
+	if left.Meta.Total != 0 {
+		t.Fatalf("%d tasks still in deleted project", left.Meta.Total)
+	}
+}
+
+// TestOpenAPIMatchesRoutes fails when a route is added, removed or renamed
+// without updating router.Spec.
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	app := newTestApp(t)
+	for _, d := range router.Spec.Drift(app.GetRoutes(true)) {
+		t.Error(d)
+	}
+
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					MaxLength int `json:"maxLength"`
+				} `json:"properties"`
+				Required []string `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	expectStatus(t, call(t, app, "GET", "/api/openapi.json", "", nil, &doc), fiber.StatusOK)
+	if !strings.HasPrefix(doc.OpenAPI, "3.0.") {
+		t.Fatalf("openapi = %q", doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/api/tasks/{id}"]["put"]; !ok {
+		t.Fatal("PUT /api/tasks/{id} missing from the document")
+	}
+	dto := doc.Components.Schemas["CreateTaskDTO"]
+	if dto.Properties["title"].MaxLength != 200 || len(dto.Required) != 1 || dto.Required[0] != "title" {
+		t.Fatalf("CreateTaskDTO = %+v, want title required with maxLength 200", dto)
+	}
+
+	resp := call(t, app, "GET", "/api/docs", "", nil, nil)
+	expectStatus(t, resp, fiber.StatusOK)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("docs content type %q", ct)
+	}
+}
+
+// signUpAdmin registers email and promotes it the way startup does, through
+// ADMIN_EMAILS and BootstrapAdmins.
+func signUpAdmin(t *testing.T, app *fiber.App, email string) string {
+	t.Helper()
+	token := signUp(t, app, email)
+	cfg := config.Default()
+	cfg.AdminEmails = []string{email}
+	handlers.UseConfig(cfg)
+	defer handlers.UseConfig(config.Default())
+	if err := handlers.BootstrapAdmins(); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// TestStoreBackedGroups runs every handler group against the in-memory store;
+// any handler still reaching for MongoDB directly panics here.
+func TestStoreBackedGroups(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+	admin := signUpAdmin(t, app, "root@example.com")
+	createTask(t, app, token, fiber.Map{"title": "Exported"})
+
+	req := httptest.NewRequest("GET", "/api/account/export", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, resp, fiber.StatusOK)
+	if ct := resp.Header.Get("Content-Type"); ct != "application/zip" {
+		t.Fatalf("export content type %q", ct)
+	}
+	archive, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var workspaces []json.RawMessage
+	for _, f := range zr.File {
+		if f.Name != "workspaces.json" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(r).Decode(&workspaces)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the personal workspace is owned by alice and belongs in her export
+	if len(workspaces) != 1 {
+		t.Fatalf("export holds %d workspaces, want 1", len(workspaces))
+	}
+
+	expectStatus(t, call(t, app, "GET", "/api/tasks", "tdo_x", nil, nil), fiber.StatusUnauthorized)
+
+	var client struct {
+		Data struct {
+			ClientID string `json:"client_id"`
+		} `json:"data"`
+		ClientSecret string `json:"client_secret"`
+	}
+	expectStatus(t, call(t, app, "POST", "/api/oauth/clients", token, fiber.Map{
+		"name": "CLI", "redirect_uris": []string{"http://127.0.0.1:8080/cb"}, "scopes": []string{"read"}, "confidential": true,
+	}, &client), fiber.StatusCreated)
+	var clients struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	expectStatus(t, call(t, app, "GET", "/api/oauth/clients", token, nil, &clients), fiber.StatusOK)
+	if len(clients.Data) != 1 {
+		t.Fatalf("%d oauth clients, want 1", len(clients.Data))
+	}
+	form := url.Values{"token": {"tdo_unknown"}, "client_id": {client.Data.ClientID}, "client_secret": {client.ClientSecret}}
+	req = httptest.NewRequest("POST", "/api/oauth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	introspect, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, introspect, fiber.StatusOK)
+
+	expectStatus(t, call(t, app, "POST", "/api/auth/mfa/enroll", token, nil, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "GET", "/api/auth/oidc/providers", "", nil, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "GET", "/api/auth/oidc/nope/callback?state=x&code=y", "", nil, nil), fiber.StatusNotFound)
+
+	expectStatus(t, call(t, app, "GET", "/api/admin/users", token, nil, nil), fiber.StatusForbidden)
+	var users struct {
+		Data []struct {
+			ID    string `json:"id"`
+			Email string `json:"email"`
+		} `json:"data"`
+	}
+	expectStatus(t, call(t, app, "GET", "/api/admin/users?search=alice", admin, nil, &users), fiber.StatusOK)
+	if len(users.Data) != 1 || users.Data[0].Email != "alice@example.com" {
+		t.Fatalf("admin user search = %+v", users.Data)
+	}
+	expectStatus(t, call(t, app, "GET", "/api/admin/stats", admin, nil, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "POST", "/api/admin/users/"+users.Data[0].ID+"/logout", admin, nil, nil), fiber.StatusOK)
+	var audit struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	expectStatus(t, call(t, app, "GET", "/api/admin/audit", admin, nil, &audit), fiber.StatusOK)
+	if len(audit.Data) != 1 {
+		t.Fatalf("%d audit entries, want 1", len(audit.Data))
+	}
+
+	expectStatus(t, call(t, app, "DELETE", "/api/account", token, fiber.Map{"password": "correct horse"}, nil), fiber.StatusNoContent)
+	expectStatus(t, call(t, app, "POST", "/api/auth/login", "", fiber.Map{"email": "alice@example.com", "password": "correct horse"}, nil), fiber.StatusUnauthorized)
+	expectStatus(t, call(t, app, "GET", "/api/admin/users/"+users.Data[0].ID, admin, nil, nil), fiber.StatusNotFound)
+}
+
+// TestErrorsAreProblemJSON checks that errors outside tasks and projects use
+// the same RFC 7807 body.
+func TestErrorsAreProblemJSON(t *testing.T) {
+	app := newTestApp(t)
+	token := signUp(t, app, "alice@example.com")
+
+	cases := []struct {
+		method, path string
+		body         interface{}
+		status       int
+		code         string
+	}{
+		{"GET", "/api/workspaces/zzz", nil, fiber.StatusBadRequest, "invalid_id"},
+		{"GET", "/api/workspaces/000000000000000000000000", nil, fiber.StatusNotFound, "not_found"},
+		{"POST", "/api/workspaces", fiber.Map{"name": " "}, fiber.StatusBadRequest, "validation_failed"},
+		{"POST", "/api/auth/tokens", fiber.Map{"name": "ci", "scopes": []string{"everything"}}, fiber.StatusBadRequest, "validation_failed"},
+		{"POST", "/api/auth/mfa/disable", fiber.Map{"password": "correct horse"}, fiber.StatusBadRequest, "mfa_not_enabled"},
+		{"GET", "/api/admin/users", nil, fiber.StatusForbidden, "forbidden"},
+		{"DELETE", "/api/oauth/clients/tdcl_nope", nil, fiber.StatusNotFound, "not_found"},
+		{"GET", "/api/auth/oidc/nope/login", nil, fiber.StatusNotFound, "not_found"},
+		{"DELETE", "/api/account", fiber.Map{"password": "wrong"}, fiber.StatusUnauthorized, "invalid_credentials"},
+	}
+	for _, tc := range cases {
+		var p handlers.Problem
+		resp := call(t, app, tc.method, tc.path, token, tc.body, &p)
+		expectStatus(t, resp, tc.status)
+		if ct := resp.Header.Get("Content-Type"); ct != handlers.MIMEProblemJSON {
+			t.Errorf("%s %s: content type %q", tc.method, tc.path, ct)
+		}
+		if p.Code != tc.code || p.Status != tc.status || p.Detail == "" {
+			t.Errorf("%s %s: problem %+v, want code %s", tc.method, tc.path, p, tc.code)
+		}
+	}
+}
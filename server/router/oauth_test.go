@@ -0,0 +1,268 @@
+package router_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	oauthRedirect = "http://127.0.0.1:9000/callback"
+	oauthVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+type oauthTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	Error        string `json:"error"`
+}
+
+// postForm sends an application/x-www-form-urlencoded request, as OAuth
+// clients do, and decodes the JSON response into out.
+func postForm(t *testing.T, app *fiber.App, path string, form url.Values, out interface{}) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("POST %s: decode %q: %v", path, raw, err)
+		}
+	}
+	return resp
+}
+
+// newOAuthClient registers a public client allowed to ask for scopes.
+func newOAuthClient(t *testing.T, app *fiber.App, token string, scopes ...string) string {
+	t.Helper()
+	var res struct {
+		Data struct {
+			ClientID string `json:"client_id"`
+		} `json:"data"`
+	}
+	expectStatus(t, call(t, app, "POST", "/api/oauth/clients", token, fiber.Map{
+		"name": "Sync app", "redirect_uris": []string{oauthRedirect}, "scopes": scopes,
+	}, &res), fiber.StatusCreated)
+	return res.Data.ClientID
+}
+
+// authorize approves an authorization request for scope and returns the code.
+func authorize(t *testing.T, app *fiber.App, token, clientID, scope string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(oauthVerifier))
+	var res struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	expectStatus(t, call(t, app, "POST", "/api/oauth/authorize", token, fiber.Map{
+		"response_type": "code", "client_id": clientID, "redirect_uri": oauthRedirect, "scope": scope,
+		"code_challenge": base64.RawURLEncoding.EncodeToString(sum[:]), "code_challenge_method": "S256",
+		"approve": true,
+	}, &res), fiber.StatusOK)
+	u, err := url.Parse(res.RedirectTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := u.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code in %q", res.RedirectTo)
+	}
+	return code
+}
+
+func exchangeCode(t *testing.T, app *fiber.App, clientID, code, verifier, redirect string) (*http.Response, oauthTokens) {
+	t.Helper()
+	var tok oauthTokens
+	resp := postForm(t, app, "/api/oauth/token", url.Values{
+		"grant_type": {"authorization_code"}, "client_id": {clientID}, "code": {code},
+		"code_verifier": {verifier}, "redirect_uri": {redirect},
+	}, &tok)
+	return resp, tok
+}
+
+func refreshGrant(t *testing.T, app *fiber.App, clientID, refresh, scope string) (*http.Response, oauthTokens) {
+	t.Helper()
+	form := url.Values{"grant_type": {"refresh_token"}, "client_id": {clientID}, "refresh_token": {refresh}}
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+	var tok oauthTokens
+	resp := postForm(t, app, "/api/oauth/token", form, &tok)
+	return resp, tok
+}
+
+func expectOAuthError(t *testing.T, resp *http.Response, tok oauthTokens, want string) {
+	t.Helper()
+	expectStatus(t, resp, fiber.StatusBadRequest)
+	if tok.Error != want {
+		t.Fatalf("error = %q, want %q", tok.Error, want)
+	}
+}
+
+func TestOAuthCodeFlow(t *testing.T) {
+	app := newTestApp(t)
+	user := signUp(t, app, "alice@example.com")
+	clientID := newOAuthClient(t, app, user, "read", "tasks:write")
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code := authorize(t, app, user, clientID, "read")
+		resp, tok := exchangeCode(t, app, clientID, code, strings.Repeat("x", 43), oauthRedirect)
+		expectOAuthError(t, resp, tok, "invalid_grant")
+	})
+
+	t.Run("redirect mismatch", func(t *testing.T) {
+		code := authorize(t, app, user, clientID, "read")
+		resp, tok := exchangeCode(t, app, clientID, code, oauthVerifier, "http://127.0.0.1:9000/other")
+		expectOAuthError(t, resp, tok, "invalid_grant")
+	})
+
+	t.Run("unregistered redirect", func(t *testing.T) {
+		expectStatus(t, call(t, app, "GET", "/api/oauth/authorize?response_type=code&client_id="+clientID+
+			"&scope=read&code_challenge_method=S256&code_challenge="+strings.Repeat("a", 43)+
+			"&redirect_uri="+url.QueryEscape("https://evil.example/cb"), user, nil, nil), fiber.StatusBadRequest)
+	})
+
+	t.Run("scope beyond the client", func(t *testing.T) {
+		expectStatus(t, call(t, app, "GET", "/api/oauth/authorize?response_type=code&client_id="+clientID+
+			"&scope="+url.QueryEscape("read projects:write")+"&code_challenge_method=S256&code_challenge="+strings.Repeat("a", 43)+
+			"&redirect_uri="+url.QueryEscape(oauthRedirect), user, nil, nil), fiber.StatusBadRequest)
+	})
+
+	t.Run("code reuse revokes the grant", func(t *testing.T) {
+		code := authorize(t, app, user, clientID, "read")
+		resp, tok := exchangeCode(t, app, clientID, code, oauthVerifier, oauthRedirect)
+		expectStatus(t, resp, fiber.StatusOK)
+		expectStatus(t, call(t, app, "GET", "/api/tasks", tok.AccessToken, nil, nil), fiber.StatusOK)
+
+		resp, again := exchangeCode(t, app, clientID, code, oauthVerifier, oauthRedirect)
+		expectOAuthError(t, resp, again, "invalid_grant")
+		expectStatus(t, call(t, app, "GET", "/api/tasks", tok.AccessToken, nil, nil), fiber.StatusUnauthorized)
+		resp, _ = refreshGrant(t, app, clientID, tok.RefreshToken, "")
+		expectStatus(t, resp, fiber.StatusBadRequest)
+	})
+
+	t.Run("refresh rotation", func(t *testing.T) {
+		code := authorize(t, app, user, clientID, "read tasks:write")
+		_, first := exchangeCode(t, app, clientID, code, oauthVerifier, oauthRedirect)
+
+		resp, second := refreshGrant(t, app, clientID, first.RefreshToken, "")
+		expectStatus(t, resp, fiber.StatusOK)
+		expectStatus(t, call(t, app, "GET", "/api/tasks", first.AccessToken, nil, nil), fiber.StatusUnauthorized)
+		expectStatus(t, call(t, app, "GET", "/api/tasks", second.AccessToken, nil, nil), fiber.StatusOK)
+
+		resp, old := refreshGrant(t, app, clientID, first.RefreshToken, "")
+		expectOAuthError(t, resp, old, "invalid_grant")
+
+		// narrowing is allowed, widening beyond the consented scopes is not
+		resp, wide := refreshGrant(t, app, clientID, second.RefreshToken, "read projects:write")
+		expectOAuthError(t, resp, wide, "invalid_scope")
+	})
+
+}
+
+func TestDisablingUserDropsPendingCodes(t *testing.T) {
+	app := newTestApp(t)
+	user := signUp(t, app, "alice@example.com")
+	admin := signUpAdmin(t, app, "root@example.com")
+	aliceID := findUser(t, app, admin, "alice@example.com")
+	clientID := newOAuthClient(t, app, user, "read")
+	code := authorize(t, app, user, clientID, "read")
+
+	// the code must not outlive the disable, even once the account is back
+	expectStatus(t, call(t, app, "POST", "/api/admin/users/"+aliceID+"/disable", admin, nil, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "POST", "/api/admin/users/"+aliceID+"/enable", admin, nil, nil), fiber.StatusOK)
+	resp, tok := exchangeCode(t, app, clientID, code, oauthVerifier, oauthRedirect)
+	expectOAuthError(t, resp, tok, "invalid_grant")
+}
+
+func TestOAuthTokenScopes(t *testing.T) {
+	app := newTestApp(t)
+	user := signUp(t, app, "alice@example.com")
+	clientID := newOAuthClient(t, app, user, "read", "tasks:write")
+	_, tok := exchangeCode(t, app, clientID, authorize(t, app, user, clientID, "read"), oauthVerifier, oauthRedirect)
+
+	expectStatus(t, call(t, app, "GET", "/api/tasks", tok.AccessToken, nil, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "GET", "/api/projects", tok.AccessToken, nil, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "POST", "/api/tasks", tok.AccessToken, fiber.Map{"title": "x"}, nil), fiber.StatusForbidden)
+	expectStatus(t, call(t, app, "POST", "/api/projects", tok.AccessToken, fiber.Map{"name": "x"}, nil), fiber.StatusForbidden)
+	// third-party tokens never reach session-only routes
+	expectStatus(t, call(t, app, "GET", "/api/account/export", tok.AccessToken, nil, nil), fiber.StatusForbidden)
+
+	// a refresh may drop scopes
+	_, full := exchangeCode(t, app, clientID, authorize(t, app, user, clientID, "read tasks:write"), oauthVerifier, oauthRedirect)
+	expectStatus(t, call(t, app, "POST", "/api/tasks", full.AccessToken, fiber.Map{"title": "x"}, nil), fiber.StatusCreated)
+	resp, narrow := refreshGrant(t, app, clientID, full.RefreshToken, "read")
+	expectStatus(t, resp, fiber.StatusOK)
+	if narrow.Scope != "read" {
+		t.Fatalf("scope = %q, want read", narrow.Scope)
+	}
+	expectStatus(t, call(t, app, "POST", "/api/tasks", narrow.AccessToken, fiber.Map{"title": "x"}, nil), fiber.StatusForbidden)
+}
+
+func TestOAuthRevoke(t *testing.T) {
+	app := newTestApp(t)
+	user := signUp(t, app, "alice@example.com")
+
+	var client struct {
+		Data struct {
+			ClientID string `json:"client_id"`
+		} `json:"data"`
+		ClientSecret string `json:"client_secret"`
+	}
+	expectStatus(t, call(t, app, "POST", "/api/oauth/clients", user, fiber.Map{
+		"name": "Server app", "redirect_uris": []string{oauthRedirect}, "scopes": []string{"read"}, "confidential": true,
+	}, &client), fiber.StatusCreated)
+	id, secret := client.Data.ClientID, client.ClientSecret
+
+	code := authorize(t, app, user, id, "read")
+	var tok oauthTokens
+	expectStatus(t, postForm(t, app, "/api/oauth/token", url.Values{
+		"grant_type": {"authorization_code"}, "client_id": {id}, "client_secret": {secret},
+		"code": {code}, "code_verifier": {oauthVerifier}, "redirect_uri": {oauthRedirect},
+	}, &tok), fiber.StatusOK)
+
+	introspect := func(token string) bool {
+		var res struct {
+			Active bool `json:"active"`
+		}
+		expectStatus(t, postForm(t, app, "/api/oauth/introspect", url.Values{
+			"client_id": {id}, "client_secret": {secret}, "token": {token},
+		}, &res), fiber.StatusOK)
+		return res.Active
+	}
+	if !introspect(tok.AccessToken) {
+		t.Fatal("fresh access token is not active")
+	}
+
+	expectStatus(t, postForm(t, app, "/api/oauth/revoke", url.Values{
+		"client_id": {id}, "client_secret": {secret}, "token": {tok.AccessToken},
+	}, nil), fiber.StatusOK)
+	if introspect(tok.AccessToken) {
+		t.Fatal("revoked access token is still active")
+	}
+	expectStatus(t, call(t, app, "GET", "/api/tasks", tok.AccessToken, nil, nil), fiber.StatusUnauthorized)
+
+	// revoking the refresh token ends the whole grant
+	if !introspect(tok.RefreshToken) {
+		t.Fatal("refresh token is not active")
+	}
+	expectStatus(t, postForm(t, app, "/api/oauth/revoke", url.Values{
+		"client_id": {id}, "client_secret": {secret}, "token": {tok.RefreshToken},
+	}, nil), fiber.StatusOK)
+	if introspect(tok.RefreshToken) {
+		t.Fatal("revoked refresh token is still active")
+	}
+}
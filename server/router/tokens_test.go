@@ -0,0 +1,95 @@
+package router_test
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newPAT creates a personal access token with scopes and returns its id and
+// plain value.
+func newPAT(t *testing.T, app *fiber.App, session string, scopes ...string) (string, string) {
+	t.Helper()
+	var res struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		Token string `json:"token"`
+	}
+	expectStatus(t, call(t, app, "POST", "/api/auth/tokens", session, fiber.Map{"name": "ci", "scopes": scopes}, &res), fiber.StatusCreated)
+	if res.Token == "" || res.Data.ID == "" {
+		t.Fatalf("token response = %+v", res)
+	}
+	return res.Data.ID, res.Token
+}
+
+func TestRequireScope(t *testing.T) {
+	app := newTestApp(t)
+	session := signUp(t, app, "alice@example.com")
+	work := createProject(t, app, session, "Work")
+	_, read := newPAT(t, app, session, "read")
+	_, tasks := newPAT(t, app, session, "tasks:write")
+
+	// read can list everything and change nothing
+	expectStatus(t, call(t, app, "GET", "/api/tasks", read, nil, nil), fiber.StatusOK)
+	expectStatus(t, call(t, app, "GET", "/api/projects/"+work.ID, read, nil, nil), fiber.StatusOK)
+	resp := call(t, app, "POST", "/api/tasks", read, fiber.Map{"title": "nope"}, nil)
+	expectStatus(t, resp, fiber.StatusForbidden)
+	if got := resp.Header.Get(fiber.HeaderWWWAuthenticate); got != `Bearer error="insufficient_scope", scope="tasks:write"` {
+		t.Errorf("WWW-Authenticate = %q", got)
+	}
+	expectCode(t, app, "DELETE", "/api/projects/"+work.ID, read, fiber.StatusForbidden, "insufficient_scope")
+
+	// tasks:write covers tasks and nothing under projects or workspaces
+	created := createTask(t, app, tasks, fiber.Map{"title": "from CI", "projectId": work.ID})
+	expectStatus(t, call(t, app, "GET", "/api/tasks/"+created.ID, tasks, nil, nil), fiber.StatusOK)
+	expectCode(t, app, "GET", "/api/projects", tasks, fiber.StatusForbidden, "insufficient_scope")
+	expectStatus(t, call(t, app, "POST", "/api/projects", tasks, fiber.Map{"name": "CI"}, nil), fiber.StatusForbidden)
+	expectCode(t, app, "GET", "/api/workspaces", tasks, fiber.StatusForbidden, "insufficient_scope")
+
+	// a session is not limited by scopes
+	expectStatus(t, call(t, app, "POST", "/api/projects", session, fiber.Map{"name": "Home"}, nil), fiber.StatusCreated)
+}
+
+func TestSessionOnly(t *testing.T) {
+	app := newTestApp(t)
+	session := signUp(t, app, "alice@example.com")
+	admin := signUpAdmin(t, app, "root@example.com")
+	_, pat := newPAT(t, app, session, "read", "tasks:write", "projects:write")
+
+	var impersonated tokenResponse
+	aliceID := findUser(t, app, admin, "alice@example.com")
+	expectStatus(t, call(t, app, "POST", "/api/admin/users/"+aliceID+"/impersonate", admin, nil, &impersonated), fiber.StatusOK)
+	_, adminPAT := newPAT(t, app, admin, "read")
+
+	for _, path := range []string{"/api/auth/tokens", "/api/account/export", "/api/oauth/clients"} {
+		expectCode(t, app, "GET", path, pat, fiber.StatusForbidden, "forbidden")
+		expectCode(t, app, "GET", path, impersonated.AccessToken, fiber.StatusForbidden, "forbidden")
+		expectStatus(t, call(t, app, "GET", path, session, nil, nil), fiber.StatusOK)
+	}
+	expectCode(t, app, "DELETE", "/api/account", pat, fiber.StatusForbidden, "forbidden")
+	// projects:write covers a workspace's projects, not the workspace or its members
+	var team struct{ Data struct{ ID string } }
+	expectStatus(t, call(t, app, "POST", "/api/workspaces", pat, fiber.Map{"name": "Team"}, &team), fiber.StatusCreated)
+	expectCode(t, app, "GET", "/api/workspaces/"+team.Data.ID+"/members", pat, fiber.StatusForbidden, "forbidden")
+	expectStatus(t, call(t, app, "POST", "/api/workspaces/"+team.Data.ID+"/members", pat, fiber.Map{"email": "root@example.com"}, nil), fiber.StatusForbidden)
+	expectCode(t, app, "DELETE", "/api/workspaces/"+team.Data.ID, pat, fiber.StatusForbidden, "forbidden")
+	expectStatus(t, call(t, app, "GET", "/api/workspaces/"+team.Data.ID+"/tasks", pat, nil, nil), fiber.StatusOK)
+	_, projectsOnly := newPAT(t, app, session, "projects:write")
+	expectCode(t, app, "GET", "/api/workspaces/"+team.Data.ID+"/tasks", projectsOnly, fiber.StatusForbidden, "insufficient_scope")
+	expectStatus(t, call(t, app, "DELETE", "/api/workspaces/"+team.Data.ID, session, nil, nil), fiber.StatusNoContent)
+	expectCode(t, app, "POST", "/api/auth/mfa/enroll", pat, fiber.StatusForbidden, "forbidden")
+	// an administrator's token is no way into the admin API either
+	expectCode(t, app, "GET", "/api/admin/users", adminPAT, fiber.StatusForbidden, "forbidden")
+	expectStatus(t, call(t, app, "GET", "/api/admin/users", admin, nil, nil), fiber.StatusOK)
+}
+
+func TestRevokedPersonalToken(t *testing.T) {
+	app := newTestApp(t)
+	session := signUp(t, app, "alice@example.com")
+	id, pat := newPAT(t, app, session, "read")
+	expectStatus(t, call(t, app, "GET", "/api/tasks", pat, nil, nil), fiber.StatusOK)
+
+	expectStatus(t, call(t, app, "DELETE", "/api/auth/tokens/"+id, session, nil, nil), fiber.StatusNoContent)
+	expectCode(t, app, "GET", "/api/tasks", pat, fiber.StatusUnauthorized, "invalid_token")
+}
@@ -0,0 +1,1663 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/db"
+	"github.com/Subomi7/todoist-clone/server/models"
+)
+
+// memDB holds every collection of the in-memory stores. Slices keep insertion
+// order so listings without an explicit order are deterministic.
+type memDB struct {
+	mu         sync.Mutex
+	users      []models.User
+	refresh    []models.RefreshToken
+	personal   []models.PersonalAccessToken
+	attempts   map[string]models.LoginAttempt
+	projects   []models.Project
+	tasks      []models.Task
+	workspaces []models.Workspace
+	members    []models.WorkspaceMember
+
+	oauthClients  []models.OAuthClient
+	oauthCodes    []models.OAuthAuthCode
+	oauthTokens   []models.OAuthToken
+	oauthConsents []models.OAuthConsent
+	oidcStates    []models.OIDCState
+	audit         []models.AdminAuditEntry
+}
+
+// NewMemory returns empty stores that keep everything in process memory. They
+// mirror the semantics of the Mongo stores and are meant for tests.
+func NewMemory() Stores {
+	m := &memDB{attempts: map[string]models.LoginAttempt{}}
+	return Stores{
+		Users:      memUsers{m},
+		Tokens:     memTokens{m},
+		Attempts:   memAttempts{m},
+		RateLimits: NewMemoryRateLimits(),
+		Projects:   memProjects{m},
+		Tasks:      memTasks{m},
+		Workspaces: memWorkspaces{m},
+		OAuth:      memOAuth{m},
+		OIDCStates: memOIDCStates{m},
+		Audit:      memAudit{m},
+		Accounts:   memAccounts{m},
+	}
+}
+
+func newID(id primitive.ObjectID) primitive.ObjectID {
+	if id.IsZero() {
+		return primitive.NewObjectID()
+	}
+	return id
+}
+
+func (a Access) allows(userID primitive.ObjectID, workspaceID *primitive.ObjectID) bool {
+	if userID == a.UserID {
+		return true
+	}
+	if workspaceID == nil {
+		return false
+	}
+	for _, id := range a.WorkspaceIDs {
+		if id == *workspaceID {
+			return true
+		}
+	}
+	return false
+}
+
+func sameID(a, b *primitive.ObjectID) bool {
+	return a == nil || (b != nil && *a == *b)
+}
+
+// equalID reports whether two optional ids are both unset or equal, as a
+// unique index compares a missing field.
+func equalID(a, b *primitive.ObjectID) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// -------- users ----------
+
+type memUsers struct{ *memDB }
+
+func (m memUsers) Create(_ context.Context, u *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, x := range m.users {
+		if x.Email == u.Email {
+			return ErrDuplicate
+		}
+	}
+	u.ID = newID(u.ID)
+	m.users = append(m.users, *u)
+	return nil
+}
+
+func (m memUsers) FindByID(_ context.Context, id primitive.ObjectID) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.ID == id {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memUsers) FindByEmail(_ context.Context, email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memUsers) FindByIDs(_ context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.User
+	for _, u := range m.users {
+		for _, id := range ids {
+			if u.ID == id {
+				out = append(out, u)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m memUsers) FindByIdentity(_ context.Context, provider, subject string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		for _, ident := range u.Identities {
+			if ident.Provider == provider && ident.Subject == subject {
+				return &u, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (f UserFilter) matches(u models.User) bool {
+	switch {
+	case f.Search != "" && !strings.Contains(u.Email, f.Search):
+		return false
+	case f.Role == models.RoleAdmin && u.Role != models.RoleAdmin:
+		return false
+	case f.Role == models.RoleUser && u.Role == models.RoleAdmin:
+		return false
+	case f.Disabled != nil && u.Disabled != *f.Disabled:
+		return false
+	}
+	return true
+}
+
+func (m memUsers) List(_ context.Context, f UserFilter, skip, limit int64) ([]models.User, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matched []models.User
+	for _, u := range m.users {
+		if f.matches(u) {
+			matched = append(matched, u)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+	return paginate(matched, skip, limit), int64(len(matched)), nil
+}
+
+func (m memUsers) Count(_ context.Context, f UserFilter) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, u := range m.users {
+		if f.matches(u) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m memUsers) Delete(_ context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.users[:0]
+	for _, u := range m.users {
+		if u.ID != id {
+			out = append(out, u)
+		}
+	}
+	m.users = out
+	return nil
+}
+
+// update applies fn to the user with the given id and returns its result, or
+// false when there is no such user.
+func (m memUsers) update(id primitive.ObjectID, fn func(*models.User) bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.users {
+		if m.users[i].ID == id {
+			return fn(&m.users[i])
+		}
+	}
+	return false
+}
+
+func (m memUsers) SetRole(_ context.Context, id primitive.ObjectID, role string) error {
+	m.update(id, func(u *models.User) bool { u.Role = role; return true })
+	return nil
+}
+
+func (m memUsers) PromoteAdmins(_ context.Context, emails []string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for i := range m.users {
+		if slices.Contains(emails, m.users[i].Email) && m.users[i].Role != models.RoleAdmin {
+			m.users[i].Role = models.RoleAdmin
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m memUsers) SetDisabled(_ context.Context, id primitive.ObjectID, disabled bool, now time.Time) error {
+	m.update(id, func(u *models.User) bool {
+		u.Disabled, u.DisabledAt = disabled, nil
+		if disabled {
+			u.DisabledAt = &now
+		}
+		return true
+	})
+	return nil
+}
+
+func (m memUsers) LinkIdentity(_ context.Context, email string, ident models.ExternalIdentity) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.users {
+		if m.users[i].Email == email {
+			m.users[i].Identities = append(m.users[i].Identities, ident)
+			u := m.users[i]
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memUsers) StartTOTP(_ context.Context, id primitive.ObjectID, secret string) error {
+	m.update(id, func(u *models.User) bool {
+		if !u.TOTPEnabled {
+			u.TOTPSecret, u.TOTPLastStep, u.RecoveryCodeHashes = secret, 0, nil
+		}
+		return true
+	})
+	return nil
+}
+
+func (m memUsers) EnableTOTP(_ context.Context, id primitive.ObjectID, secret string, step uint64, recoveryHashes []string) (bool, error) {
+	return m.update(id, func(u *models.User) bool {
+		if u.TOTPEnabled || u.TOTPSecret != secret {
+			return false
+		}
+		u.TOTPEnabled, u.TOTPLastStep, u.RecoveryCodeHashes = true, step, recoveryHashes
+		return true
+	}), nil
+}
+
+func (m memUsers) ConsumeTOTPStep(_ context.Context, id primitive.ObjectID, step uint64) (bool, error) {
+	return m.update(id, func(u *models.User) bool {
+		if u.TOTPLastStep >= step {
+			return false
+		}
+		u.TOTPLastStep = step
+		return true
+	}), nil
+}
+
+func (m memUsers) ConsumeRecoveryCode(_ context.Context, id primitive.ObjectID, hash string) (bool, error) {
+	return m.update(id, func(u *models.User) bool {
+		i := slices.Index(u.RecoveryCodeHashes, hash)
+		if i < 0 {
+			return false
+		}
+		u.RecoveryCodeHashes = slices.Delete(slices.Clone(u.RecoveryCodeHashes), i, i+1)
+		return true
+	}), nil
+}
+
+func (m memUsers) DisableTOTP(_ context.Context, id primitive.ObjectID) error {
+	m.update(id, func(u *models.User) bool {
+		u.TOTPEnabled, u.TOTPSecret, u.TOTPLastStep, u.RecoveryCodeHashes = false, "", 0, nil
+		return true
+	})
+	return nil
+}
+
+// -------- tokens ----------
+
+type memTokens struct{ *memDB }
+
+func (m memTokens) SaveRefresh(_ context.Context, t *models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, x := range m.refresh {
+		if x.TokenHash == t.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	t.ID = newID(t.ID)
+	m.refresh = append(m.refresh, *t)
+	return nil
+}
+
+func (m memTokens) FindRefresh(_ context.Context, hash string) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.refresh {
+		if t.TokenHash == hash {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memTokens) deleteRefreshWhere(keep func(models.RefreshToken) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.refresh[:0]
+	for _, t := range m.refresh {
+		if keep(t) {
+			out = append(out, t)
+		}
+	}
+	m.refresh = out
+}
+
+func (m memTokens) DeleteRefresh(_ context.Context, hash string) error {
+	m.deleteRefreshWhere(func(t models.RefreshToken) bool { return t.TokenHash != hash })
+	return nil
+}
+
+func (m memTokens) DeleteRefreshForUser(_ context.Context, userID primitive.ObjectID) error {
+	m.deleteRefreshWhere(func(t models.RefreshToken) bool { return t.UserID != userID })
+	return nil
+}
+
+func (m memTokens) DeleteExpiredRefresh(_ context.Context, now time.Time) error {
+	m.deleteRefreshWhere(func(t models.RefreshToken) bool { return !t.ExpiresAt.Before(now) })
+	return nil
+}
+
+func (m memTokens) CountActiveRefresh(_ context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, t := range m.refresh {
+		if !t.Revoked && !t.ExpiresAt.Before(now) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m memTokens) CreatePersonal(_ context.Context, t *models.PersonalAccessToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, x := range m.personal {
+		if x.TokenHash == t.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	t.ID = newID(t.ID)
+	m.personal = append(m.personal, *t)
+	return nil
+}
+
+func (m memTokens) FindPersonal(_ context.Context, hash string) (*models.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.personal {
+		if t.TokenHash == hash {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memTokens) ListPersonal(_ context.Context, userID primitive.ObjectID) ([]models.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []models.PersonalAccessToken{}
+	for _, t := range m.personal {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (m memTokens) CountPersonal(_ context.Context, userID primitive.ObjectID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, t := range m.personal {
+		if t.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m memTokens) TouchPersonal(_ context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.personal {
+		if m.personal[i].ID == id {
+			t := usedAt
+			m.personal[i].LastUsedAt = &t
+		}
+	}
+	return nil
+}
+
+func (m memTokens) DeletePersonal(_ context.Context, id, userID primitive.ObjectID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, t := range m.personal {
+		if t.ID == id && t.UserID == userID {
+			m.personal = append(m.personal[:i], m.personal[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m memTokens) CountAllPersonal(_ context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.personal)), nil
+}
+
+// -------- login attempts ----------
+
+type memAttempts struct{ *memDB }
+
+func (m memAttempts) Find(_ context.Context, keys []string) ([]models.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.LoginAttempt
+	for _, k := range keys {
+		if a, ok := m.attempts[k]; ok {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (m memAttempts) RecordFailure(_ context.Context, key string, now time.Time, window, retention time.Duration) (*models.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.attempts[key]
+	if !ok {
+		a = models.LoginAttempt{Key: key}
+	}
+	if a.LastFailure.Before(now.Add(-window)) {
+		a.Failures = 1
+	} else {
+		a.Failures++
+	}
+	a.LastFailure = now
+	a.ExpiresAt = now.Add(retention)
+	m.attempts[key] = a
+	return &a, nil
+}
+
+func (m memAttempts) update(key string, fn func(*models.LoginAttempt)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if a, ok := m.attempts[key]; ok {
+		fn(&a)
+		m.attempts[key] = a
+	}
+}
+
+func (m memAttempts) SetLockedUntil(_ context.Context, key string, until time.Time) error {
+	m.update(key, func(a *models.LoginAttempt) { a.LockedUntil = until })
+	return nil
+}
+
+func (m memAttempts) SetUnlockToken(_ context.Context, key, hash string) error {
+	m.update(key, func(a *models.LoginAttempt) { a.UnlockTokenHash = hash })
+	return nil
+}
+
+func (m memAttempts) DeleteByUnlockToken(_ context.Context, hash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, a := range m.attempts {
+		if a.UnlockTokenHash != "" && a.UnlockTokenHash == hash {
+			delete(m.attempts, k)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m memAttempts) Delete(_ context.Context, keys []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range keys {
+		delete(m.attempts, k)
+	}
+	return nil
+}
+
+// -------- rate limits ----------
+
+// memRateLimits keeps counters in process memory. Unlike the other in-memory
+// stores it is also meant for production on a single node, where sharing
+// counters through MongoDB buys nothing.
+type memRateLimits struct {
+	mu        sync.Mutex
+	counters  map[string]*models.RateLimitCounter
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimits returns an empty in-memory RateLimitStore.
+func NewMemoryRateLimits() RateLimitStore {
+	return &memRateLimits{counters: map[string]*models.RateLimitCounter{}}
+}
+
+// memRateLimitSweep is how often finished windows are dropped.
+const memRateLimitSweep = time.Minute
+
+func (m *memRateLimits) Hit(_ context.Context, key string, start time.Time, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) >= memRateLimitSweep {
+		for k, c := range m.counters {
+			if !c.ExpiresAt.After(now) {
+				delete(m.counters, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	c := m.counters[key]
+	if c == nil || !c.WindowStart.Equal(start) {
+		c = &models.RateLimitCounter{Key: key, WindowStart: start, ExpiresAt: start.Add(window)}
+		m.counters[key] = c
+	}
+	c.Count++
+	return c.Count, nil
+}
+
+// -------- projects ----------
+
+type memProjects struct{ *memDB }
+
+func (m memProjects) Create(_ context.Context, p *models.Project) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, x := range m.projects {
+		if x.UserID == p.UserID && equalID(x.WorkspaceID, p.WorkspaceID) && x.Name == p.Name {
+			return ErrDuplicate
+		}
+	}
+	p.ID = newID(p.ID)
+	m.projects = append(m.projects, *p)
+	return nil
+}
+
+func (m memProjects) Get(_ context.Context, id primitive.ObjectID, a Access) (*models.Project, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.projects {
+		if p.ID == id && a.allows(p.UserID, p.WorkspaceID) {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memProjects) FindByName(_ context.Context, userID, workspaceID primitive.ObjectID, name string) (*models.Project, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.projects {
+		if p.UserID == userID && equalID(&workspaceID, p.WorkspaceID) && p.Name == name {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memProjects) List(_ context.Context, f ProjectFilter, p ProjectPage) ([]*models.ProjectWithCount, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matched []*models.ProjectWithCount
+	for _, proj := range m.projects {
+		if (f.UserID != nil && proj.UserID != *f.UserID) || !sameID(f.WorkspaceID, proj.WorkspaceID) {
+			continue
+		}
+		pc := &models.ProjectWithCount{Project: proj}
+		for _, t := range m.tasks {
+			if t.ProjectID == proj.ID && !t.Completed {
+				pc.TaskCount++
+			}
+		}
+		matched = append(matched, pc)
+	}
+	newer := func(a, b models.Project) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return idLess(b.ID, a.ID)
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return newer(matched[i].Project, matched[j].Project) })
+	total := int64(len(matched))
+	if p.After != nil {
+		at, _ := p.After.Values[0].(time.Time)
+		pivot := models.Project{ID: p.After.ID, CreatedAt: at}
+		i := sort.Search(len(matched), func(i int) bool { return newer(pivot, matched[i].Project) })
+		matched = matched[i:]
+	}
+	return paginate(matched, p.Skip, p.Limit), total, nil
+}
+
+func (m memProjects) Rename(_ context.Context, id, userID primitive.ObjectID, name string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := -1
+	for i, p := range m.projects {
+		if p.ID == id && p.UserID == userID {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return false, nil
+	}
+	for i, p := range m.projects {
+		if i != idx && p.UserID == userID && equalID(p.WorkspaceID, m.projects[idx].WorkspaceID) && p.Name == name {
+			return false, ErrDuplicate
+		}
+	}
+	m.projects[idx].Name = name
+	m.projects[idx].UpdatedAt = now
+	return true, nil
+}
+
+func (m memProjects) Reassign(_ context.Context, id, userID primitive.ObjectID, name string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := -1
+	for i, p := range m.projects {
+		if p.ID == id {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+	for i, p := range m.projects {
+		if i != idx && p.UserID == userID && equalID(p.WorkspaceID, m.projects[idx].WorkspaceID) && p.Name == name {
+			return ErrDuplicate
+		}
+	}
+	m.projects[idx].UserID = userID
+	m.projects[idx].Name = name
+	m.projects[idx].UpdatedAt = now
+	return nil
+}
+
+func (m memProjects) Delete(_ context.Context, id, userID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.projects[:0]
+	for _, p := range m.projects {
+		if p.ID != id || p.UserID != userID {
+			out = append(out, p)
+		}
+	}
+	m.projects = out
+	return nil
+}
+
+func (m memProjects) DeleteByWorkspace(_ context.Context, workspaceID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.projects[:0]
+	for _, p := range m.projects {
+		if p.WorkspaceID == nil || *p.WorkspaceID != workspaceID {
+			out = append(out, p)
+		}
+	}
+	m.projects = out
+	return nil
+}
+
+func (m memProjects) Count(_ context.Context, f ProjectFilter) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, p := range m.projects {
+		if (f.UserID == nil || p.UserID == *f.UserID) && sameID(f.WorkspaceID, p.WorkspaceID) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// -------- tasks ----------
+
+type memTasks struct{ *memDB }
+
+func (m memTasks) Create(_ context.Context, t *models.Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.ID = newID(t.ID)
+	m.tasks = append(m.tasks, *t)
+	return nil
+}
+
+func (m memTasks) Get(_ context.Context, id primitive.ObjectID, a Access) (*models.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tasks {
+		if t.ID == id && a.allows(t.UserID, t.WorkspaceID) {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// matcher returns whether a task passes the filter.
+func (f TaskFilter) matcher() (func(models.Task) bool, error) {
+	var search *regexp.Regexp
+	if f.Search != "" {
+		re, err := regexp.Compile("(?i)" + regexp.QuoteMeta(f.Search))
+		if err != nil {
+			return nil, err
+		}
+		search = re
+	}
+	return func(t models.Task) bool {
+		return (f.UserID == nil || t.UserID == *f.UserID) &&
+			sameID(f.WorkspaceID, t.WorkspaceID) &&
+			(f.ProjectID == nil || t.ProjectID == *f.ProjectID) &&
+			sameID(f.InboxID, t.InboxID) &&
+			(f.Completed == nil || t.Completed == *f.Completed) &&
+			(search == nil || search.MatchString(t.Title) || search.MatchString(t.Description))
+	}, nil
+}
+
+func (m memTasks) List(_ context.Context, f TaskFilter, p TaskPage) ([]models.Task, int64, error) {
+	match, err := f.matcher()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matched []models.Task
+	for _, t := range m.tasks {
+		if match(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	total := int64(len(matched))
+	if len(p.Sort) > 0 {
+		before := taskBefore(p.Sort)
+		sort.SliceStable(matched, func(i, j int) bool { return before(matched[i], matched[j]) })
+		if p.After != nil {
+			pivot := taskAt(p.Sort, *p.After)
+			i := sort.Search(len(matched), func(i int) bool { return before(pivot, matched[i]) })
+			matched = matched[i:]
+		}
+	}
+	return paginate(matched, p.Skip, p.Limit), total, nil
+}
+
+// taskBefore orders tasks by each key of s in turn, breaking ties by ID in the
+// direction of the last key, as the Mongo store does.
+func taskBefore(s Sort) func(a, b models.Task) bool {
+	return func(a, b models.Task) bool {
+		for _, k := range s {
+			less := taskLess(k.Field)
+			x, y := a, b
+			if k.Desc {
+				x, y = b, a
+			}
+			if less(x, y) {
+				return true
+			}
+			if less(y, x) {
+				return false
+			}
+		}
+		if s.idDesc() {
+			return idLess(b.ID, a.ID)
+		}
+		return idLess(a.ID, b.ID)
+	}
+}
+
+// taskAt returns a task holding only the cursor's sort values and ID, to
+// compare other tasks against. Values of the wrong type are left unset.
+func taskAt(s Sort, c Cursor) models.Task {
+	t := models.Task{ID: c.ID}
+	for i, k := range s {
+		if i >= len(c.Values) {
+			break
+		}
+		switch v := c.Values[i].(type) {
+		case time.Time:
+			switch k.Field {
+			case "createdAt":
+				t.CreatedAt = v
+			case "updatedAt":
+				t.UpdatedAt = v
+			case "dueDate":
+				t.DueDate = &v
+			}
+		case int64:
+			t.Priority = models.Priority(v)
+		case string:
+			t.Title = v
+		case bool:
+			t.Completed = v
+		}
+	}
+	return t
+}
+
+// idLess orders ObjectIDs as MongoDB does, byte by byte.
+func idLess(a, b primitive.ObjectID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// taskLess orders tasks by a document field the way MongoDB does; missing values
+// sort first. Unknown fields leave the order unchanged.
+func taskLess(field string) func(a, b models.Task) bool {
+	switch field {
+	case "createdAt":
+		return func(a, b models.Task) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "updatedAt":
+		return func(a, b models.Task) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	case "dueDate":
+		return func(a, b models.Task) bool {
+			if a.DueDate == nil || b.DueDate == nil {
+				return a.DueDate == nil && b.DueDate != nil
+			}
+			return a.DueDate.Before(*b.DueDate)
+		}
+	case "priority":
+		return func(a, b models.Task) bool { return a.Priority < b.Priority }
+	case "title":
+		return func(a, b models.Task) bool { return a.Title < b.Title }
+	case "completed":
+		return func(a, b models.Task) bool { return !a.Completed && b.Completed }
+	}
+	return func(a, b models.Task) bool { return false }
+}
+
+func (m memTasks) Update(_ context.Context, id primitive.ObjectID, a Access, u TaskUpdate) (*models.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.tasks {
+		t := &m.tasks[i]
+		if t.ID != id || !a.allows(t.UserID, t.WorkspaceID) {
+			continue
+		}
+		t.UpdatedAt = u.UpdatedAt
+		if u.Title != nil {
+			t.Title = *u.Title
+		}
+		if u.Description != nil {
+			t.Description = *u.Description
+		}
+		if u.Completed != nil {
+			t.Completed = *u.Completed
+		}
+		if u.DueDate != nil {
+			d := *u.DueDate
+			t.DueDate = &d
+		}
+		if u.Priority != nil {
+			t.Priority = *u.Priority
+		}
+		if u.Move != nil {
+			t.ProjectID = u.Move.ProjectID
+			t.WorkspaceID = u.Move.WorkspaceID
+		}
+		out := *t
+		return &out, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m memTasks) Delete(_ context.Context, id primitive.ObjectID, a Access) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, t := range m.tasks {
+		if t.ID == id && a.allows(t.UserID, t.WorkspaceID) {
+			m.tasks = append(m.tasks[:i], m.tasks[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m memTasks) OwnersInProject(_ context.Context, projectID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := map[primitive.ObjectID]bool{}
+	var out []primitive.ObjectID
+	for _, t := range m.tasks {
+		if t.ProjectID == projectID && !seen[t.UserID] {
+			seen[t.UserID] = true
+			out = append(out, t.UserID)
+		}
+	}
+	return out, nil
+}
+
+func (m memTasks) MoveProjectTasks(_ context.Context, fromProject, userID primitive.ObjectID, to TaskMove, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.tasks {
+		t := &m.tasks[i]
+		if t.ProjectID == fromProject && t.UserID == userID {
+			t.ProjectID = to.ProjectID
+			t.WorkspaceID = to.WorkspaceID
+			t.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (m memTasks) DeleteByWorkspace(_ context.Context, workspaceID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.tasks[:0]
+	for _, t := range m.tasks {
+		if t.WorkspaceID == nil || *t.WorkspaceID != workspaceID {
+			out = append(out, t)
+		}
+	}
+	m.tasks = out
+	return nil
+}
+
+func (m memTasks) Count(_ context.Context, f TaskFilter) (int64, error) {
+	match, err := f.matcher()
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, t := range m.tasks {
+		if match(t) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// -------- workspaces ----------
+
+type memWorkspaces struct{ *memDB }
+
+func (m memWorkspaces) EnsurePersonal(_ context.Context, userID primitive.ObjectID, name string, now time.Time) (*models.Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ws := range m.workspaces {
+		if ws.OwnerID == userID && ws.Personal {
+			return &ws, nil
+		}
+	}
+	ws := models.Workspace{ID: primitive.NewObjectID(), Name: name, Personal: true, OwnerID: userID, CreatedAt: now, UpdatedAt: now}
+	m.workspaces = append(m.workspaces, ws)
+	m.members = append(m.members, models.WorkspaceMember{
+		ID: primitive.NewObjectID(), WorkspaceID: ws.ID, UserID: userID, Role: models.WorkspaceRoleOwner, JoinedAt: now,
+	})
+	return &ws, nil
+}
+
+func (m memWorkspaces) Create(_ context.Context, ws *models.Workspace, owner *models.WorkspaceMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ws.ID = newID(ws.ID)
+	owner.ID = newID(owner.ID)
+	owner.WorkspaceID = ws.ID
+	m.workspaces = append(m.workspaces, *ws)
+	m.members = append(m.members, *owner)
+	return nil
+}
+
+func (m memWorkspaces) Get(_ context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ws := range m.workspaces {
+		if ws.ID == id {
+			return &ws, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memWorkspaces) List(_ context.Context, ids []primitive.ObjectID) ([]models.Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.Workspace
+	for _, ws := range m.workspaces {
+		for _, id := range ids {
+			if ws.ID == id {
+				out = append(out, ws)
+				break
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Personal != out[j].Personal {
+			return out[i].Personal
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+func (m memWorkspaces) OwnedBy(_ context.Context, userID primitive.ObjectID) ([]models.Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.Workspace
+	for _, ws := range m.workspaces {
+		if ws.OwnerID == userID {
+			out = append(out, ws)
+		}
+	}
+	return out, nil
+}
+
+func (m memWorkspaces) Rename(_ context.Context, id primitive.ObjectID, name string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.workspaces {
+		if m.workspaces[i].ID == id {
+			m.workspaces[i].Name = name
+			m.workspaces[i].UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (m memWorkspaces) SetOwner(_ context.Context, id, userID primitive.ObjectID, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.members {
+		if m.members[i].WorkspaceID == id && m.members[i].UserID == userID {
+			m.members[i].Role = models.WorkspaceRoleOwner
+		}
+	}
+	for i := range m.workspaces {
+		if m.workspaces[i].ID == id {
+			m.workspaces[i].OwnerID = userID
+			m.workspaces[i].UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (m memWorkspaces) Delete(_ context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	members := m.members[:0]
+	for _, mb := range m.members {
+		if mb.WorkspaceID != id {
+			members = append(members, mb)
+		}
+	}
+	m.members = members
+	workspaces := m.workspaces[:0]
+	for _, ws := range m.workspaces {
+		if ws.ID != id {
+			workspaces = append(workspaces, ws)
+		}
+	}
+	m.workspaces = workspaces
+	return nil
+}
+
+func (m memWorkspaces) GetMember(_ context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, mb := range m.members {
+		if mb.WorkspaceID == workspaceID && mb.UserID == userID {
+			return &mb, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memWorkspaces) Members(_ context.Context, workspaceID primitive.ObjectID) ([]models.WorkspaceMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.WorkspaceMember
+	for _, mb := range m.members {
+		if mb.WorkspaceID == workspaceID {
+			out = append(out, mb)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].JoinedAt.Before(out[j].JoinedAt) })
+	return out, nil
+}
+
+func (m memWorkspaces) MembershipsOf(_ context.Context, userID primitive.ObjectID) ([]models.WorkspaceMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.WorkspaceMember
+	for _, mb := range m.members {
+		if mb.UserID == userID {
+			out = append(out, mb)
+		}
+	}
+	return out, nil
+}
+
+func (m memWorkspaces) AddMember(_ context.Context, mb *models.WorkspaceMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, x := range m.members {
+		if x.WorkspaceID == mb.WorkspaceID && x.UserID == mb.UserID {
+			return ErrDuplicate
+		}
+	}
+	mb.ID = newID(mb.ID)
+	m.members = append(m.members, *mb)
+	return nil
+}
+
+func (m memWorkspaces) SetMemberRole(_ context.Context, workspaceID, userID primitive.ObjectID, role string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.members {
+		mb := &m.members[i]
+		if mb.WorkspaceID == workspaceID && mb.UserID == userID && mb.Role != models.WorkspaceRoleOwner {
+			mb.Role = role
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m memWorkspaces) RemoveMember(_ context.Context, workspaceID, userID primitive.ObjectID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, mb := range m.members {
+		if mb.WorkspaceID == workspaceID && mb.UserID == userID && mb.Role != models.WorkspaceRoleOwner {
+			m.members = append(m.members[:i], m.members[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m memWorkspaces) Successor(_ context.Context, workspaceID, ownerID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var best *models.WorkspaceMember
+	for i := range m.members {
+		mb := &m.members[i]
+		if mb.WorkspaceID != workspaceID || mb.UserID == ownerID {
+			continue
+		}
+		if best == nil || mb.Role < best.Role || (mb.Role == best.Role && mb.JoinedAt.Before(best.JoinedAt)) {
+			best = mb
+		}
+	}
+	if best == nil {
+		return nil, ErrNotFound
+	}
+	out := *best
+	return &out, nil
+}
+
+// -------- oauth ----------
+
+type memOAuth struct{ *memDB }
+
+func (m memOAuth) CreateClient(_ context.Context, c *models.OAuthClient) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, x := range m.oauthClients {
+		if x.ClientID == c.ClientID {
+			return ErrDuplicate
+		}
+	}
+	c.ID = newID(c.ID)
+	m.oauthClients = append(m.oauthClients, *c)
+	return nil
+}
+
+func (m memOAuth) FindClient(_ context.Context, clientID string) (*models.OAuthClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.oauthClients {
+		if c.ClientID == clientID {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memOAuth) ListClients(_ context.Context, ownerID primitive.ObjectID) ([]models.OAuthClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []models.OAuthClient{}
+	for _, c := range m.oauthClients {
+		if c.OwnerID == ownerID {
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (m memOAuth) CountClients(_ context.Context, ownerID primitive.ObjectID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, c := range m.oauthClients {
+		if c.OwnerID == ownerID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m memOAuth) CountAllClients(_ context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.oauthClients)), nil
+}
+
+// deleteClients removes the clients for which drop is true, with every code,
+// token and consent issued to them, and reports whether there were any.
+func (m memOAuth) deleteClients(drop func(models.OAuthClient) bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	gone := map[string]bool{}
+	clients := m.oauthClients[:0]
+	for _, c := range m.oauthClients {
+		if drop(c) {
+			gone[c.ClientID] = true
+		} else {
+			clients = append(clients, c)
+		}
+	}
+	m.oauthClients = clients
+
+	codes := m.oauthCodes[:0]
+	for _, c := range m.oauthCodes {
+		if !gone[c.ClientID] {
+			codes = append(codes, c)
+		}
+	}
+	m.oauthCodes = codes
+	tokens := m.oauthTokens[:0]
+	for _, t := range m.oauthTokens {
+		if !gone[t.ClientID] {
+			tokens = append(tokens, t)
+		}
+	}
+	m.oauthTokens = tokens
+	consents := m.oauthConsents[:0]
+	for _, c := range m.oauthConsents {
+		if !gone[c.ClientID] {
+			consents = append(consents, c)
+		}
+	}
+	m.oauthConsents = consents
+	return len(gone) > 0
+}
+
+func (m memOAuth) DeleteClient(_ context.Context, clientID string, ownerID primitive.ObjectID) (bool, error) {
+	return m.deleteClients(func(c models.OAuthClient) bool { return c.ClientID == clientID && c.OwnerID == ownerID }), nil
+}
+
+func (m memOAuth) DeleteClientsOwnedBy(_ context.Context, ownerID primitive.ObjectID) error {
+	m.deleteClients(func(c models.OAuthClient) bool { return c.OwnerID == ownerID })
+	return nil
+}
+
+func (m memOAuth) CreateCode(_ context.Context, code *models.OAuthAuthCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code.ID = newID(code.ID)
+	m.oauthCodes = append(m.oauthCodes, *code)
+	return nil
+}
+
+func (m memOAuth) RedeemCode(_ context.Context, hash string, grantID primitive.ObjectID, now time.Time) (*models.OAuthAuthCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.oauthCodes {
+		c := &m.oauthCodes[i]
+		if c.CodeHash != hash {
+			continue
+		}
+		before := *c
+		if c.UsedAt == nil {
+			c.UsedAt, c.GrantID = &now, grantID
+		}
+		return &before, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m memOAuth) DeleteCodesForUser(_ context.Context, userID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.oauthCodes = keep(m.oauthCodes, userID, func(x models.OAuthAuthCode) primitive.ObjectID { return x.UserID })
+	return nil
+}
+
+func (m memOAuth) SaveTokens(_ context.Context, tokens ...*models.OAuthToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range tokens {
+		t.ID = newID(t.ID)
+		m.oauthTokens = append(m.oauthTokens, *t)
+	}
+	return nil
+}
+
+func (m memOAuth) FindToken(_ context.Context, hash, kind string) (*models.OAuthToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.oauthTokens {
+		if t.TokenHash == hash && (kind == "" || t.Kind == kind) {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memOAuth) TakeToken(_ context.Context, hash, kind string) (*models.OAuthToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, t := range m.oauthTokens {
+		if t.TokenHash == hash && t.Kind == kind {
+			m.oauthTokens = append(m.oauthTokens[:i], m.oauthTokens[i+1:]...)
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memOAuth) deleteTokensWhere(drop func(models.OAuthToken) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.oauthTokens[:0]
+	for _, t := range m.oauthTokens {
+		if !drop(t) {
+			out = append(out, t)
+		}
+	}
+	m.oauthTokens = out
+}
+
+func (m memOAuth) DeleteToken(_ context.Context, id primitive.ObjectID) error {
+	m.deleteTokensWhere(func(t models.OAuthToken) bool { return t.ID == id })
+	return nil
+}
+
+func (m memOAuth) DeleteGrant(_ context.Context, grantID primitive.ObjectID, kind string) error {
+	m.deleteTokensWhere(func(t models.OAuthToken) bool { return t.GrantID == grantID && (kind == "" || t.Kind == kind) })
+	return nil
+}
+
+func (m memOAuth) DeleteTokensForUser(_ context.Context, userID primitive.ObjectID) error {
+	m.deleteTokensWhere(func(t models.OAuthToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (m memOAuth) FindConsent(_ context.Context, userID primitive.ObjectID, clientID string) (*models.OAuthConsent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.oauthConsents {
+		if c.UserID == userID && c.ClientID == clientID {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m memOAuth) AddConsent(_ context.Context, userID primitive.ObjectID, clientID string, scopes []string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.oauthConsents {
+		c := &m.oauthConsents[i]
+		if c.UserID == userID && c.ClientID == clientID {
+			merged := slices.Clone(c.Scopes)
+			for _, s := range scopes {
+				if !slices.Contains(merged, s) {
+					merged = append(merged, s)
+				}
+			}
+			c.Scopes, c.UpdatedAt = merged, now
+			return nil
+		}
+	}
+	m.oauthConsents = append(m.oauthConsents, models.OAuthConsent{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		ClientID:  clientID,
+		Scopes:    slices.Clone(scopes),
+		UpdatedAt: now,
+	})
+	return nil
+}
+
+// -------- oidc states ----------
+
+type memOIDCStates struct{ *memDB }
+
+func (m memOIDCStates) Save(_ context.Context, s *models.OIDCState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.ID = newID(s.ID)
+	m.oidcStates = append(m.oidcStates, *s)
+	return nil
+}
+
+func (m memOIDCStates) Take(_ context.Context, hash, provider string) (*models.OIDCState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, s := range m.oidcStates {
+		if s.StateHash == hash && s.Provider == provider {
+			m.oidcStates = append(m.oidcStates[:i], m.oidcStates[i+1:]...)
+			return &s, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// -------- admin audit log ----------
+
+type memAudit struct{ *memDB }
+
+func (m memAudit) Record(_ context.Context, e *models.AdminAuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.ID = newID(e.ID)
+	m.audit = append(m.audit, *e)
+	return nil
+}
+
+func (m memAudit) List(_ context.Context, targetUserID *primitive.ObjectID, skip, limit int64) ([]models.AdminAuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := []models.AdminAuditEntry{}
+	for _, e := range m.audit {
+		if sameID(targetUserID, e.TargetUserID) {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	return paginate(entries, skip, limit), nil
+}
+
+func (m memAudit) Count(_ context.Context, targetUserID *primitive.ObjectID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, e := range m.audit {
+		if sameID(targetUserID, e.TargetUserID) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// -------- account data ----------
+
+type memAccounts struct{ *memDB }
+
+// owned returns the user's documents in the named collection; it must know
+// every collection in db.UserOwnedCollections.
+func (m memAccounts) owned(name string, userID primitive.ObjectID) ([]any, error) {
+	var docs []any
+	add := func(owner primitive.ObjectID, doc any) {
+		if owner == userID {
+			docs = append(docs, doc)
+		}
+	}
+	switch name {
+	case "users":
+		for _, x := range m.users {
+			add(x.ID, x)
+		}
+	case "refresh_tokens":
+		for _, x := range m.refresh {
+			add(x.UserID, x)
+		}
+	case "personal_access_tokens":
+		for _, x := range m.personal {
+			add(x.UserID, x)
+		}
+	case "oauth_tokens":
+		for _, x := range m.oauthTokens {
+			add(x.UserID, x)
+		}
+	case "oauth_codes":
+		for _, x := range m.oauthCodes {
+			add(x.UserID, x)
+		}
+	case "oauth_consents":
+		for _, x := range m.oauthConsents {
+			add(x.UserID, x)
+		}
+	case "oauth_clients":
+		for _, x := range m.oauthClients {
+			add(x.OwnerID, x)
+		}
+	case "workspace_members":
+		for _, x := range m.members {
+			add(x.UserID, x)
+		}
+	case "tasks":
+		for _, x := range m.tasks {
+			add(x.UserID, x)
+		}
+	case "projects":
+		for _, x := range m.projects {
+			add(x.UserID, x)
+		}
+	case "workspaces":
+		for _, x := range m.workspaces {
+			add(x.OwnerID, x)
+		}
+	default:
+		return nil, fmt.Errorf("store: no in-memory collection %q", name)
+	}
+	return docs, nil
+}
+
+// exportDocs converts documents to their stored form, as Mongo would return them.
+func exportDocs(name string, docs []any) (Collection, error) {
+	out := Collection{Name: name, Docs: []bson.M{}}
+	for _, d := range docs {
+		raw, err := bson.Marshal(d)
+		if err != nil {
+			return out, err
+		}
+		var doc bson.M
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return out, err
+		}
+		out.Docs = append(out.Docs, doc)
+	}
+	return out, nil
+}
+
+func (m memAccounts) Export(_ context.Context, userID primitive.ObjectID) ([]Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := []string{"users"}
+	for _, oc := range db.UserOwnedCollections {
+		names = append(names, oc.Name)
+	}
+	var out []Collection
+	for _, name := range names {
+		docs, err := m.owned(name, userID)
+		if err != nil {
+			return nil, err
+		}
+		col, err := exportDocs(name, docs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out = append(out, col)
+	}
+	return out, nil
+}
+
+// keep returns the elements of items not owned by userID.
+func keep[T any](items []T, userID primitive.ObjectID, owner func(T) primitive.ObjectID) []T {
+	out := items[:0]
+	for _, x := range items {
+		if owner(x) != userID {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func (m memAccounts) DeleteData(_ context.Context, userID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, oc := range db.UserOwnedCollections {
+		if oc.ExportOnly {
+			continue
+		}
+		switch oc.Name {
+		case "refresh_tokens":
+			m.refresh = keep(m.refresh, userID, func(x models.RefreshToken) primitive.ObjectID { return x.UserID })
+		case "personal_access_tokens":
+			m.personal = keep(m.personal, userID, func(x models.PersonalAccessToken) primitive.ObjectID { return x.UserID })
+		case "oauth_tokens":
+			m.oauthTokens = keep(m.oauthTokens, userID, func(x models.OAuthToken) primitive.ObjectID { return x.UserID })
+		case "oauth_codes":
+			m.oauthCodes = keep(m.oauthCodes, userID, func(x models.OAuthAuthCode) primitive.ObjectID { return x.UserID })
+		case "oauth_consents":
+			m.oauthConsents = keep(m.oauthConsents, userID, func(x models.OAuthConsent) primitive.ObjectID { return x.UserID })
+		case "oauth_clients":
+			m.oauthClients = keep(m.oauthClients, userID, func(x models.OAuthClient) primitive.ObjectID { return x.OwnerID })
+		case "workspace_members":
+			m.members = keep(m.members, userID, func(x models.WorkspaceMember) primitive.ObjectID { return x.UserID })
+		case "tasks":
+			m.tasks = keep(m.tasks, userID, func(x models.Task) primitive.ObjectID { return x.UserID })
+		case "projects":
+			m.projects = keep(m.projects, userID, func(x models.Project) primitive.ObjectID { return x.UserID })
+		default:
+			return fmt.Errorf("store: no in-memory collection %q", oc.Name)
+		}
+	}
+	return nil
+}
+
+func paginate[T any](items []T, skip, limit int64) []T {
+	if skip >= int64(len(items)) {
+		return nil
+	}
+	items = items[skip:]
+	if limit > 0 && limit < int64(len(items)) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,1140 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Subomi7/todoist-clone/server/db"
+	"github.com/Subomi7/todoist-clone/server/models"
+)
+
+// NewMongo returns stores backed by the database opened with db.StartMongoDB.
+func NewMongo() Stores {
+	return Stores{
+		Users:      mongoUsers{},
+		Tokens:     mongoTokens{},
+		Attempts:   mongoAttempts{},
+		RateLimits: mongoRateLimits{},
+		Projects:   mongoProjects{},
+		Tasks:      mongoTasks{},
+		Workspaces: mongoWorkspaces{},
+		OAuth:      mongoOAuth{},
+		OIDCStates: mongoOIDCStates{},
+		Audit:      mongoAudit{},
+		Accounts:   mongoAccounts{},
+	}
+}
+
+// mongoErr maps driver errors onto the store's sentinel errors.
+func mongoErr(err error) error {
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return ErrDuplicate
+	}
+	return err
+}
+
+func accessFilter(id primitive.ObjectID, a Access) bson.M {
+	return bson.M{"_id": id, "$or": bson.A{
+		bson.M{"userId": a.UserID},
+		bson.M{"workspaceId": bson.M{"$in": a.WorkspaceIDs}},
+	}}
+}
+
+func distinctIDs(raw []interface{}) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(raw))
+	for _, v := range raw {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// -------- users ----------
+
+type mongoUsers struct{}
+
+func (mongoUsers) Create(ctx context.Context, u *models.User) error {
+	_, err := db.GetCollection("users").InsertOne(ctx, u)
+	return mongoErr(err)
+}
+
+func (mongoUsers) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	var u models.User
+	if err := db.GetCollection("users").FindOne(ctx, bson.M{"_id": id}).Decode(&u); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &u, nil
+}
+
+func (mongoUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var u models.User
+	if err := db.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&u); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &u, nil
+}
+
+func (mongoUsers) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	cur, err := db.GetCollection("users").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	err = cur.All(ctx, &users)
+	return users, err
+}
+
+func (mongoUsers) FindByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	var u models.User
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	if err := db.GetCollection("users").FindOne(ctx, filter).Decode(&u); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &u, nil
+}
+
+func userFilter(f UserFilter) bson.M {
+	filter := bson.M{}
+	if f.Search != "" {
+		filter["email"] = bson.M{"$regex": regexp.QuoteMeta(f.Search)}
+	}
+	switch f.Role {
+	case models.RoleAdmin:
+		filter["role"] = models.RoleAdmin
+	case models.RoleUser:
+		filter["role"] = bson.M{"$ne": models.RoleAdmin}
+	}
+	if f.Disabled != nil {
+		if *f.Disabled {
+			filter["disabled"] = true
+		} else {
+			filter["disabled"] = bson.M{"$ne": true}
+		}
+	}
+	return filter
+}
+
+func (mongoUsers) List(ctx context.Context, f UserFilter, skip, limit int64) ([]models.User, int64, error) {
+	col := db.GetCollection("users")
+	filter := userFilter(f)
+	total, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetSkip(skip).SetLimit(limit)
+	cur, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (mongoUsers) Count(ctx context.Context, f UserFilter) (int64, error) {
+	return db.GetCollection("users").CountDocuments(ctx, userFilter(f))
+}
+
+func (mongoUsers) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := db.GetCollection("users").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (mongoUsers) SetRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	_, err := db.GetCollection("users").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"role": role}})
+	return err
+}
+
+func (mongoUsers) PromoteAdmins(ctx context.Context, emails []string) (int64, error) {
+	res, err := db.GetCollection("users").UpdateMany(ctx,
+		bson.M{"email": bson.M{"$in": emails}, "role": bson.M{"$ne": models.RoleAdmin}},
+		bson.M{"$set": bson.M{"role": models.RoleAdmin}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (mongoUsers) SetDisabled(ctx context.Context, id primitive.ObjectID, disabled bool, now time.Time) error {
+	update := bson.M{"$set": bson.M{"disabled": true, "disabled_at": now}}
+	if !disabled {
+		update = bson.M{"$unset": bson.M{"disabled": "", "disabled_at": ""}}
+	}
+	_, err := db.GetCollection("users").UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (mongoUsers) LinkIdentity(ctx context.Context, email string, ident models.ExternalIdentity) (*models.User, error) {
+	var u models.User
+	err := db.GetCollection("users").FindOneAndUpdate(ctx,
+		bson.M{"email": email},
+		bson.M{"$push": bson.M{"identities": ident}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&u)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	return &u, nil
+}
+
+func (mongoUsers) StartTOTP(ctx context.Context, id primitive.ObjectID, secret string) error {
+	_, err := db.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": id, "totp_enabled": bson.M{"$ne": true}},
+		bson.M{
+			"$set":   bson.M{"totp_secret": secret, "totp_enabled": false},
+			"$unset": bson.M{"totp_last_step": "", "recovery_code_hashes": ""},
+		},
+	)
+	return err
+}
+
+func (mongoUsers) EnableTOTP(ctx context.Context, id primitive.ObjectID, secret string, step uint64, recoveryHashes []string) (bool, error) {
+	res, err := db.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": id, "totp_secret": secret, "totp_enabled": false},
+		bson.M{"$set": bson.M{
+			"totp_enabled":         true,
+			"totp_last_step":       step,
+			"recovery_code_hashes": recoveryHashes,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (mongoUsers) ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step uint64) (bool, error) {
+	// conditional update guards against two concurrent requests using the same code
+	res, err := db.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"totp_last_step": bson.M{"$exists": false}},
+			bson.M{"totp_last_step": bson.M{"$lt": step}},
+		}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (mongoUsers) ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error) {
+	res, err := db.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": id, "recovery_code_hashes": hash},
+		bson.M{"$pull": bson.M{"recovery_code_hashes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (mongoUsers) DisableTOTP(ctx context.Context, id primitive.ObjectID) error {
+	_, err := db.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set":   bson.M{"totp_enabled": false},
+			"$unset": bson.M{"totp_secret": "", "totp_last_step": "", "recovery_code_hashes": ""},
+		},
+	)
+	return err
+}
+
+// -------- tokens ----------
+
+type mongoTokens struct{}
+
+func (mongoTokens) SaveRefresh(ctx context.Context, t *models.RefreshToken) error {
+	_, err := db.GetCollection("refresh_tokens").InsertOne(ctx, t)
+	return mongoErr(err)
+}
+
+func (mongoTokens) FindRefresh(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	if err := db.GetCollection("refresh_tokens").FindOne(ctx, bson.M{"token_hash": hash}).Decode(&t); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &t, nil
+}
+
+func (mongoTokens) DeleteRefresh(ctx context.Context, hash string) error {
+	_, err := db.GetCollection("refresh_tokens").DeleteOne(ctx, bson.M{"token_hash": hash})
+	return err
+}
+
+func (mongoTokens) DeleteRefreshForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := db.GetCollection("refresh_tokens").DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func (mongoTokens) DeleteExpiredRefresh(ctx context.Context, now time.Time) error {
+	_, err := db.GetCollection("refresh_tokens").DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": now}})
+	return err
+}
+
+func (mongoTokens) CountActiveRefresh(ctx context.Context, now time.Time) (int64, error) {
+	return db.GetCollection("refresh_tokens").CountDocuments(ctx, bson.M{"expires_at": bson.M{"$gte": now}, "revoked": bson.M{"$ne": true}})
+}
+
+func (mongoTokens) CreatePersonal(ctx context.Context, t *models.PersonalAccessToken) error {
+	_, err := db.PersonalTokensCol().InsertOne(ctx, t)
+	return mongoErr(err)
+}
+
+func (mongoTokens) FindPersonal(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	var t models.PersonalAccessToken
+	if err := db.PersonalTokensCol().FindOne(ctx, bson.M{"token_hash": hash}).Decode(&t); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &t, nil
+}
+
+func (mongoTokens) ListPersonal(ctx context.Context, userID primitive.ObjectID) ([]models.PersonalAccessToken, error) {
+	cur, err := db.PersonalTokensCol().Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	tokens := []models.PersonalAccessToken{}
+	err = cur.All(ctx, &tokens)
+	return tokens, err
+}
+
+func (mongoTokens) CountPersonal(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return db.PersonalTokensCol().CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
+func (mongoTokens) TouchPersonal(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	_, err := db.PersonalTokensCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	return err
+}
+
+func (mongoTokens) DeletePersonal(ctx context.Context, id, userID primitive.ObjectID) (bool, error) {
+	res, err := db.PersonalTokensCol().DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (mongoTokens) CountAllPersonal(ctx context.Context) (int64, error) {
+	return db.PersonalTokensCol().CountDocuments(ctx, bson.M{})
+}
+
+// -------- login attempts ----------
+
+type mongoAttempts struct{}
+
+func (mongoAttempts) Find(ctx context.Context, keys []string) ([]models.LoginAttempt, error) {
+	cur, err := db.LoginAttemptsCol().Find(ctx, bson.M{"key": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, err
+	}
+	var attempts []models.LoginAttempt
+	err = cur.All(ctx, &attempts)
+	return attempts, err
+}
+
+func (mongoAttempts) RecordFailure(ctx context.Context, key string, now time.Time, window, retention time.Duration) (*models.LoginAttempt, error) {
+	// pipeline update: restart the count when the previous failure is outside the window
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failures": bson.M{"$cond": bson.A{
+			bson.M{"$lt": bson.A{"$last_failure", now.Add(-window)}},
+			1,
+			bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+		}},
+		"last_failure": now,
+		"expires_at":   now.Add(retention),
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var attempt models.LoginAttempt
+	if err := db.LoginAttemptsCol().FindOneAndUpdate(ctx, bson.M{"key": key}, update, opts).Decode(&attempt); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (mongoAttempts) SetLockedUntil(ctx context.Context, key string, until time.Time) error {
+	_, err := db.LoginAttemptsCol().UpdateOne(ctx, bson.M{"key": key}, bson.M{"$set": bson.M{"locked_until": until}})
+	return err
+}
+
+func (mongoAttempts) SetUnlockToken(ctx context.Context, key, hash string) error {
+	_, err := db.LoginAttemptsCol().UpdateOne(ctx, bson.M{"key": key}, bson.M{"$set": bson.M{"unlock_token_hash": hash}})
+	return err
+}
+
+func (mongoAttempts) DeleteByUnlockToken(ctx context.Context, hash string) (bool, error) {
+	res, err := db.LoginAttemptsCol().DeleteOne(ctx, bson.M{"unlock_token_hash": hash})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (mongoAttempts) Delete(ctx context.Context, keys []string) error {
+	_, err := db.LoginAttemptsCol().DeleteMany(ctx, bson.M{"key": bson.M{"$in": keys}})
+	return err
+}
+
+// sortDoc is the $sort document for s, ending with the _id tiebreak.
+func sortDoc(s Sort) bson.D {
+	doc := make(bson.D, 0, len(s)+1)
+	for _, k := range s {
+		doc = append(doc, bson.E{Key: k.Field, Value: direction(k.Desc)})
+	}
+	return append(doc, bson.E{Key: "_id", Value: direction(s.idDesc())})
+}
+
+func direction(desc bool) int {
+	if desc {
+		return -1
+	}
+	return 1
+}
+
+// afterFilter matches the documents that sort after c in order s: those equal
+// on the first keys and past c on the next one, or equal on every key with a
+// later _id. Like MongoDB's sort, it places a missing or null value before
+// every other value.
+func afterFilter(s Sort, c Cursor) bson.M {
+	var after bson.A
+	equal := bson.M{}
+	branch := func(field string, cond any) bson.M {
+		b := bson.M{field: cond}
+		for f, v := range equal {
+			b[f] = v
+		}
+		return b
+	}
+	for i, k := range s {
+		v := c.Values[i]
+		switch {
+		case v == nil && !k.Desc:
+			after = append(after, branch(k.Field, bson.M{"$ne": nil}))
+		case v == nil:
+			// nulls come last, so nothing is past one
+		case k.Desc:
+			after = append(after, branch("$or", bson.A{bson.M{k.Field: bson.M{"$lt": v}}, bson.M{k.Field: nil}}))
+		default:
+			after = append(after, branch(k.Field, bson.M{"$gt": v}))
+		}
+		equal[k.Field] = v
+	}
+	op := "$gt"
+	if s.idDesc() {
+		op = "$lt"
+	}
+	after = append(after, branch("_id", bson.M{op: c.ID}))
+	return bson.M{"$or": after}
+}
+
+// -------- rate limits ----------
+
+type mongoRateLimits struct{}
+
+func (mongoRateLimits) Hit(ctx context.Context, key string, start time.Time, window time.Duration) (int64, error) {
+	// one document per key and window; the TTL index removes it once the window is over
+	filter := bson.M{"key": key, "window_start": start}
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"expires_at": start.Add(window)},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter models.RateLimitCounter
+	err := db.RateLimitsCol().FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	if mongo.IsDuplicateKeyError(err) {
+		// a concurrent first hit inserted the document; the retry increments it
+		err = db.RateLimitsCol().FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return counter.Count, nil
+}
+
+// -------- projects ----------
+
+type mongoProjects struct{}
+
+func (mongoProjects) Create(ctx context.Context, p *models.Project) error {
+	_, err := db.ProjectsCol().InsertOne(ctx, p)
+	return mongoErr(err)
+}
+
+func (mongoProjects) Get(ctx context.Context, id primitive.ObjectID, a Access) (*models.Project, error) {
+	var p models.Project
+	if err := db.ProjectsCol().FindOne(ctx, accessFilter(id, a)).Decode(&p); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &p, nil
+}
+
+func (mongoProjects) FindByName(ctx context.Context, userID, workspaceID primitive.ObjectID, name string) (*models.Project, error) {
+	var p models.Project
+	if err := db.ProjectsCol().FindOne(ctx, bson.M{"userId": userID, "workspaceId": workspaceID, "name": name}).Decode(&p); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &p, nil
+}
+
+func projectFilter(f ProjectFilter) bson.M {
+	match := bson.M{}
+	if f.UserID != nil {
+		match["userId"] = *f.UserID
+	}
+	if f.WorkspaceID != nil {
+		match["workspaceId"] = *f.WorkspaceID
+	}
+	return match
+}
+
+func (mongoProjects) List(ctx context.Context, f ProjectFilter, p ProjectPage) ([]*models.ProjectWithCount, int64, error) {
+	match := projectFilter(f)
+	newestFirst := sortDoc(ProjectOrder)
+
+	// seek and limit before the lookup so only the page's projects count their tasks
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	if p.After != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: afterFilter(ProjectOrder, *p.After)}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: newestFirst}})
+	if p.Skip > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: p.Skip}})
+	}
+	if p.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: p.Limit}})
+	}
+	pipeline = append(pipeline, mongo.Pipeline{
+		// Lookup tasks that belong to each project (excluding completed)
+		{{Key: "$lookup", Value: bson.M{
+			"from": "tasks",
+			"let":  bson.M{"projId": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$projectId", "$$projId"}},
+					bson.M{"$eq": bson.A{"$completed", false}},
+				}}}},
+			},
+			"as": "projectTasks",
+		}}},
+		{{Key: "$addFields", Value: bson.M{"taskCount": bson.M{"$size": "$projectTasks"}}}},
+		{{Key: "$project", Value: bson.M{"projectTasks": 0}}},
+		// $lookup does not preserve order
+		{{Key: "$sort", Value: newestFirst}},
+	}...)
+
+	col := db.ProjectsCol()
+	cur, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	var projects []*models.ProjectWithCount
+	if err := cur.All(ctx, &projects); err != nil {
+		return nil, 0, err
+	}
+	if !p.Count {
+		return projects, 0, nil
+	}
+	total, err := col.CountDocuments(ctx, match)
+	if err != nil {
+		return nil, 0, err
+	}
+	return projects, total, nil
+}
+
+func (mongoProjects) Rename(ctx context.Context, id, userID primitive.ObjectID, name string, now time.Time) (bool, error) {
+	res, err := db.ProjectsCol().UpdateOne(ctx, bson.M{"_id": id, "userId": userID}, bson.M{"$set": bson.M{"name": name, "updatedAt": now}})
+	if err != nil {
+		return false, mongoErr(err)
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (mongoProjects) Reassign(ctx context.Context, id, userID primitive.ObjectID, name string, now time.Time) error {
+	_, err := db.ProjectsCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"userId": userID, "name": name, "updatedAt": now}})
+	return mongoErr(err)
+}
+
+func (mongoProjects) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+	_, err := db.ProjectsCol().DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	return err
+}
+
+func (mongoProjects) DeleteByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := db.ProjectsCol().DeleteMany(ctx, bson.M{"workspaceId": workspaceID})
+	return err
+}
+
+func (mongoProjects) Count(ctx context.Context, f ProjectFilter) (int64, error) {
+	return db.ProjectsCol().CountDocuments(ctx, projectFilter(f))
+}
+
+// -------- tasks ----------
+
+type mongoTasks struct{}
+
+func (mongoTasks) Create(ctx context.Context, t *models.Task) error {
+	_, err := db.TasksCol().InsertOne(ctx, t)
+	return mongoErr(err)
+}
+
+func (mongoTasks) Get(ctx context.Context, id primitive.ObjectID, a Access) (*models.Task, error) {
+	var t models.Task
+	if err := db.TasksCol().FindOne(ctx, accessFilter(id, a)).Decode(&t); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &t, nil
+}
+
+func taskFilter(f TaskFilter) bson.M {
+	filter := bson.M{}
+	if f.UserID != nil {
+		filter["userId"] = *f.UserID
+	}
+	if f.WorkspaceID != nil {
+		filter["workspaceId"] = *f.WorkspaceID
+	}
+	if f.ProjectID != nil {
+		filter["projectId"] = *f.ProjectID
+	}
+	if f.InboxID != nil {
+		filter["inboxId"] = *f.InboxID
+	}
+	if f.Completed != nil {
+		filter["completed"] = *f.Completed
+	}
+	if f.Search != "" {
+		// quoted, so the search is a plain substring and never a user-supplied pattern
+		search := regexp.QuoteMeta(f.Search)
+		filter["$or"] = []bson.M{
+			{"title": bson.M{"$regex": search, "$options": "i"}},
+			{"description": bson.M{"$regex": search, "$options": "i"}},
+		}
+	}
+	return filter
+}
+
+func (mongoTasks) List(ctx context.Context, f TaskFilter, p TaskPage) ([]models.Task, int64, error) {
+	filter := taskFilter(f)
+	col := db.TasksCol()
+	var total int64
+	if p.Count {
+		n, err := col.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		total = n
+	}
+
+	findOpts := options.Find().SetSkip(p.Skip).SetLimit(p.Limit)
+	if len(p.Sort) > 0 {
+		findOpts.SetSort(sortDoc(p.Sort))
+		if p.After != nil {
+			// combined with $and: the search condition already uses $or
+			filter["$and"] = bson.A{afterFilter(p.Sort, *p.After)}
+		}
+	}
+	cur, err := col.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var tasks []models.Task
+	if err := cur.All(ctx, &tasks); err != nil {
+		return nil, 0, err
+	}
+	return tasks, total, nil
+}
+
+func (mongoTasks) Update(ctx context.Context, id primitive.ObjectID, a Access, u TaskUpdate) (*models.Task, error) {
+	set := bson.M{"updatedAt": u.UpdatedAt}
+	if u.Title != nil {
+		set["title"] = *u.Title
+	}
+	if u.Description != nil {
+		set["description"] = *u.Description
+	}
+	if u.Completed != nil {
+		set["completed"] = *u.Completed
+	}
+	if u.DueDate != nil {
+		set["dueDate"] = *u.DueDate
+	}
+	if u.Priority != nil {
+		set["priority"] = *u.Priority
+	}
+	if u.Move != nil {
+		set["projectId"] = u.Move.ProjectID
+		set["workspaceId"] = u.Move.WorkspaceID
+	}
+
+	var t models.Task
+	err := db.TasksCol().FindOneAndUpdate(ctx, accessFilter(id, a), bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&t)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	return &t, nil
+}
+
+func (mongoTasks) Delete(ctx context.Context, id primitive.ObjectID, a Access) (bool, error) {
+	res, err := db.TasksCol().DeleteOne(ctx, accessFilter(id, a))
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (mongoTasks) OwnersInProject(ctx context.Context, projectID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	raw, err := db.TasksCol().Distinct(ctx, "userId", bson.M{"projectId": projectID})
+	if err != nil {
+		return nil, err
+	}
+	return distinctIDs(raw), nil
+}
+
+func (mongoTasks) MoveProjectTasks(ctx context.Context, fromProject, userID primitive.ObjectID, to TaskMove, now time.Time) error {
+	_, err := db.TasksCol().UpdateMany(ctx,
+		bson.M{"projectId": fromProject, "userId": userID},
+		bson.M{"$set": bson.M{"projectId": to.ProjectID, "workspaceId": to.WorkspaceID, "updatedAt": now}},
+	)
+	return err
+}
+
+func (mongoTasks) DeleteByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	_, err := db.TasksCol().DeleteMany(ctx, bson.M{"workspaceId": workspaceID})
+	return err
+}
+
+func (mongoTasks) Count(ctx context.Context, f TaskFilter) (int64, error) {
+	return db.TasksCol().CountDocuments(ctx, taskFilter(f))
+}
+
+// -------- workspaces ----------
+
+type mongoWorkspaces struct{}
+
+func (mongoWorkspaces) EnsurePersonal(ctx context.Context, userID primitive.ObjectID, name string, now time.Time) (*models.Workspace, error) {
+	var ws models.Workspace
+	err := db.WorkspacesCol().FindOneAndUpdate(ctx,
+		bson.M{"owner_id": userID, "personal": true},
+		bson.M{"$setOnInsert": bson.M{"name": name, "created_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&ws)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.WorkspaceMembersCol().UpdateOne(ctx,
+		bson.M{"workspace_id": ws.ID, "user_id": userID},
+		bson.M{"$setOnInsert": bson.M{"role": models.WorkspaceRoleOwner, "joined_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
+func (mongoWorkspaces) Create(ctx context.Context, ws *models.Workspace, owner *models.WorkspaceMember) error {
+	if _, err := db.WorkspacesCol().InsertOne(ctx, ws); err != nil {
+		return mongoErr(err)
+	}
+	if _, err := db.WorkspaceMembersCol().InsertOne(ctx, owner); err != nil {
+		db.WorkspacesCol().DeleteOne(ctx, bson.M{"_id": ws.ID})
+		return mongoErr(err)
+	}
+	return nil
+}
+
+func (mongoWorkspaces) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	var ws models.Workspace
+	if err := db.WorkspacesCol().FindOne(ctx, bson.M{"_id": id}).Decode(&ws); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &ws, nil
+}
+
+func (mongoWorkspaces) List(ctx context.Context, ids []primitive.ObjectID) ([]models.Workspace, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "personal", Value: -1}, {Key: "name", Value: 1}})
+	cur, err := db.WorkspacesCol().Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var workspaces []models.Workspace
+	err = cur.All(ctx, &workspaces)
+	return workspaces, err
+}
+
+func (mongoWorkspaces) OwnedBy(ctx context.Context, userID primitive.ObjectID) ([]models.Workspace, error) {
+	cur, err := db.WorkspacesCol().Find(ctx, bson.M{"owner_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	var workspaces []models.Workspace
+	err = cur.All(ctx, &workspaces)
+	return workspaces, err
+}
+
+func (mongoWorkspaces) Rename(ctx context.Context, id primitive.ObjectID, name string, now time.Time) error {
+	_, err := db.WorkspacesCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"name": name, "updated_at": now}})
+	return err
+}
+
+func (mongoWorkspaces) SetOwner(ctx context.Context, id, userID primitive.ObjectID, now time.Time) error {
+	_, err := db.WorkspaceMembersCol().UpdateOne(ctx,
+		bson.M{"workspace_id": id, "user_id": userID},
+		bson.M{"$set": bson.M{"role": models.WorkspaceRoleOwner}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = db.WorkspacesCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"owner_id": userID, "updated_at": now}})
+	return err
+}
+
+func (mongoWorkspaces) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := db.WorkspaceMembersCol().DeleteMany(ctx, bson.M{"workspace_id": id}); err != nil {
+		return err
+	}
+	_, err := db.WorkspacesCol().DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (mongoWorkspaces) GetMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	var m models.WorkspaceMember
+	if err := db.WorkspaceMembersCol().FindOne(ctx, bson.M{"workspace_id": workspaceID, "user_id": userID}).Decode(&m); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &m, nil
+}
+
+func (mongoWorkspaces) Members(ctx context.Context, workspaceID primitive.ObjectID) ([]models.WorkspaceMember, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "joined_at", Value: 1}})
+	cur, err := db.WorkspaceMembersCol().Find(ctx, bson.M{"workspace_id": workspaceID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var members []models.WorkspaceMember
+	err = cur.All(ctx, &members)
+	return members, err
+}
+
+func (mongoWorkspaces) MembershipsOf(ctx context.Context, userID primitive.ObjectID) ([]models.WorkspaceMember, error) {
+	cur, err := db.WorkspaceMembersCol().Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	var members []models.WorkspaceMember
+	err = cur.All(ctx, &members)
+	return members, err
+}
+
+func (mongoWorkspaces) AddMember(ctx context.Context, m *models.WorkspaceMember) error {
+	_, err := db.WorkspaceMembersCol().InsertOne(ctx, m)
+	return mongoErr(err)
+}
+
+func (mongoWorkspaces) SetMemberRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role string) (bool, error) {
+	res, err := db.WorkspaceMembersCol().UpdateOne(ctx,
+		bson.M{"workspace_id": workspaceID, "user_id": userID, "role": bson.M{"$ne": models.WorkspaceRoleOwner}},
+		bson.M{"$set": bson.M{"role": role}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (mongoWorkspaces) RemoveMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (bool, error) {
+	res, err := db.WorkspaceMembersCol().DeleteOne(ctx,
+		bson.M{"workspace_id": workspaceID, "user_id": userID, "role": bson.M{"$ne": models.WorkspaceRoleOwner}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (mongoWorkspaces) Successor(ctx context.Context, workspaceID, ownerID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	var m models.WorkspaceMember
+	err := db.WorkspaceMembersCol().FindOne(ctx,
+		bson.M{"workspace_id": workspaceID, "user_id": bson.M{"$ne": ownerID}},
+		// "admin" sorts before "member"
+		options.FindOne().SetSort(bson.D{{Key: "role", Value: 1}, {Key: "joined_at", Value: 1}}),
+	).Decode(&m)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	return &m, nil
+}
+
+// -------- oauth ----------
+
+type mongoOAuth struct{}
+
+func (mongoOAuth) CreateClient(ctx context.Context, c *models.OAuthClient) error {
+	_, err := db.OAuthClientsCol().InsertOne(ctx, c)
+	return mongoErr(err)
+}
+
+func (mongoOAuth) FindClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var c models.OAuthClient
+	if err := db.OAuthClientsCol().FindOne(ctx, bson.M{"client_id": clientID}).Decode(&c); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &c, nil
+}
+
+func (mongoOAuth) ListClients(ctx context.Context, ownerID primitive.ObjectID) ([]models.OAuthClient, error) {
+	cur, err := db.OAuthClientsCol().Find(ctx, bson.M{"owner_id": ownerID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	clients := []models.OAuthClient{}
+	err = cur.All(ctx, &clients)
+	return clients, err
+}
+
+func (mongoOAuth) CountClients(ctx context.Context, ownerID primitive.ObjectID) (int64, error) {
+	return db.OAuthClientsCol().CountDocuments(ctx, bson.M{"owner_id": ownerID})
+}
+
+func (mongoOAuth) CountAllClients(ctx context.Context) (int64, error) {
+	return db.OAuthClientsCol().CountDocuments(ctx, bson.M{})
+}
+
+// deleteIssuedTo removes every token, code and consent issued to the given clients.
+func deleteIssuedTo(ctx context.Context, clientIDs bson.A) error {
+	filter := bson.M{"client_id": bson.M{"$in": clientIDs}}
+	for _, col := range []*mongo.Collection{db.OAuthTokensCol(), db.OAuthCodesCol(), db.OAuthConsentsCol()} {
+		if _, err := col.DeleteMany(ctx, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mongoOAuth) DeleteClient(ctx context.Context, clientID string, ownerID primitive.ObjectID) (bool, error) {
+	res, err := db.OAuthClientsCol().DeleteOne(ctx, bson.M{"client_id": clientID, "owner_id": ownerID})
+	if err != nil || res.DeletedCount == 0 {
+		return false, err
+	}
+	return true, deleteIssuedTo(ctx, bson.A{clientID})
+}
+
+func (mongoOAuth) DeleteClientsOwnedBy(ctx context.Context, ownerID primitive.ObjectID) error {
+	ids, err := db.OAuthClientsCol().Distinct(ctx, "client_id", bson.M{"owner_id": ownerID})
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	// what was issued first, so a failure part way leaves the clients to retry with
+	if err := deleteIssuedTo(ctx, ids); err != nil {
+		return err
+	}
+	_, err = db.OAuthClientsCol().DeleteMany(ctx, bson.M{"owner_id": ownerID})
+	return err
+}
+
+func (mongoOAuth) CreateCode(ctx context.Context, code *models.OAuthAuthCode) error {
+	_, err := db.OAuthCodesCol().InsertOne(ctx, code)
+	return mongoErr(err)
+}
+
+func (mongoOAuth) RedeemCode(ctx context.Context, hash string, grantID primitive.ObjectID, now time.Time) (*models.OAuthAuthCode, error) {
+	var code models.OAuthAuthCode
+	// the unused filter makes redemption atomic; the default return is the
+	// document before the update
+	err := db.OAuthCodesCol().FindOneAndUpdate(ctx,
+		bson.M{"code_hash": hash, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": now, "grant_id": grantID}},
+	).Decode(&code)
+	if err == mongo.ErrNoDocuments {
+		// already used, or unknown
+		err = db.OAuthCodesCol().FindOne(ctx, bson.M{"code_hash": hash}).Decode(&code)
+	}
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	return &code, nil
+}
+
+func (mongoOAuth) DeleteCodesForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := db.OAuthCodesCol().DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func (mongoOAuth) SaveTokens(ctx context.Context, tokens ...*models.OAuthToken) error {
+	docs := make([]interface{}, len(tokens))
+	for i, t := range tokens {
+		docs[i] = t
+	}
+	_, err := db.OAuthTokensCol().InsertMany(ctx, docs)
+	return mongoErr(err)
+}
+
+func tokenFilter(hash, kind string) bson.M {
+	filter := bson.M{"token_hash": hash}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+	return filter
+}
+
+func (mongoOAuth) FindToken(ctx context.Context, hash, kind string) (*models.OAuthToken, error) {
+	var t models.OAuthToken
+	if err := db.OAuthTokensCol().FindOne(ctx, tokenFilter(hash, kind)).Decode(&t); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &t, nil
+}
+
+func (mongoOAuth) TakeToken(ctx context.Context, hash, kind string) (*models.OAuthToken, error) {
+	var t models.OAuthToken
+	if err := db.OAuthTokensCol().FindOneAndDelete(ctx, tokenFilter(hash, kind)).Decode(&t); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &t, nil
+}
+
+func (mongoOAuth) DeleteToken(ctx context.Context, id primitive.ObjectID) error {
+	_, err := db.OAuthTokensCol().DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (mongoOAuth) DeleteGrant(ctx context.Context, grantID primitive.ObjectID, kind string) error {
+	filter := bson.M{"grant_id": grantID}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+	_, err := db.OAuthTokensCol().DeleteMany(ctx, filter)
+	return err
+}
+
+func (mongoOAuth) DeleteTokensForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := db.OAuthTokensCol().DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func (mongoOAuth) FindConsent(ctx context.Context, userID primitive.ObjectID, clientID string) (*models.OAuthConsent, error) {
+	var c models.OAuthConsent
+	if err := db.OAuthConsentsCol().FindOne(ctx, bson.M{"user_id": userID, "client_id": clientID}).Decode(&c); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &c, nil
+}
+
+func (mongoOAuth) AddConsent(ctx context.Context, userID primitive.ObjectID, clientID string, scopes []string, now time.Time) error {
+	_, err := db.OAuthConsentsCol().UpdateOne(ctx,
+		bson.M{"user_id": userID, "client_id": clientID},
+		bson.M{"$addToSet": bson.M{"scopes": bson.M{"$each": scopes}}, "$set": bson.M{"updated_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// -------- oidc states ----------
+
+type mongoOIDCStates struct{}
+
+func (mongoOIDCStates) Save(ctx context.Context, s *models.OIDCState) error {
+	_, err := db.OIDCStatesCol().InsertOne(ctx, s)
+	return mongoErr(err)
+}
+
+func (mongoOIDCStates) Take(ctx context.Context, hash, provider string) (*models.OIDCState, error) {
+	var s models.OIDCState
+	if err := db.OIDCStatesCol().FindOneAndDelete(ctx, bson.M{"state_hash": hash, "provider": provider}).Decode(&s); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &s, nil
+}
+
+// -------- admin audit log ----------
+
+type mongoAudit struct{}
+
+func (mongoAudit) Record(ctx context.Context, e *models.AdminAuditEntry) error {
+	_, err := db.AdminAuditCol().InsertOne(ctx, e)
+	return err
+}
+
+func (mongoAudit) List(ctx context.Context, targetUserID *primitive.ObjectID, skip, limit int64) ([]models.AdminAuditEntry, error) {
+	filter := bson.M{}
+	if targetUserID != nil {
+		filter["target_user_id"] = *targetUserID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetSkip(skip).SetLimit(limit)
+	cur, err := db.AdminAuditCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	entries := []models.AdminAuditEntry{}
+	err = cur.All(ctx, &entries)
+	return entries, err
+}
+
+func (mongoAudit) Count(ctx context.Context, targetUserID *primitive.ObjectID) (int64, error) {
+	filter := bson.M{}
+	if targetUserID != nil {
+		filter["target_user_id"] = *targetUserID
+	}
+	return db.AdminAuditCol().CountDocuments(ctx, filter)
+}
+
+// -------- account data ----------
+
+type mongoAccounts struct{}
+
+func findDocs(ctx context.Context, name string, filter bson.M) ([]bson.M, error) {
+	cur, err := db.GetCollection(name).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	docs := []bson.M{}
+	err = cur.All(ctx, &docs)
+	return docs, err
+}
+
+func (mongoAccounts) Export(ctx context.Context, userID primitive.ObjectID) ([]Collection, error) {
+	users, err := findDocs(ctx, "users", bson.M{"_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	out := []Collection{{Name: "users", Docs: users}}
+	for _, oc := range db.UserOwnedCollections {
+		docs, err := findDocs(ctx, oc.Name, bson.M{oc.UserField: userID})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", oc.Name, err)
+		}
+		out = append(out, Collection{Name: oc.Name, Docs: docs})
+	}
+	return out, nil
+}
+
+func (mongoAccounts) DeleteData(ctx context.Context, userID primitive.ObjectID) error {
+	for _, oc := range db.UserOwnedCollections {
+		if oc.ExportOnly {
+			continue
+		}
+		if _, err := db.GetCollection(oc.Name).DeleteMany(ctx, bson.M{oc.UserField: userID}); err != nil {
+			return fmt.Errorf("%s: %w", oc.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Subomi7/todoist-clone/server/models"
+)
+
+// ErrInvalidSort is returned by ParseTaskSort for a spec it does not accept.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// TaskSortFields are the task fields a listing can be sorted by. Both stores
+// order them alike and can resume a cursor from any of them.
+var TaskSortFields = []string{"createdAt", "updatedAt", "dueDate", "priority", "title", "completed"}
+
+// SortKey orders a listing by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Sort orders a listing by each key in turn. Items equal on every key are
+// ordered by _id, in the direction of the last key.
+type Sort []SortKey
+
+// ParseTaskSort reads a comma-separated sort spec such as "priority,-dueDate":
+// field names from TaskSortFields, each prefixed with "-" for descending order.
+// A field may appear only once.
+func ParseTaskSort(spec string) (Sort, error) {
+	var s Sort
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if key.Field == "" {
+			return nil, fmt.Errorf("%w: empty sort key in %q", ErrInvalidSort, spec)
+		}
+		if !isTaskSortField(key.Field) {
+			return nil, fmt.Errorf("%w: cannot sort by %q; use one of %s", ErrInvalidSort, key.Field, strings.Join(TaskSortFields, ", "))
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("%w: %q appears more than once", ErrInvalidSort, key.Field)
+		}
+		seen[key.Field] = true
+		s = append(s, key)
+	}
+	return s, nil
+}
+
+func isTaskSortField(field string) bool {
+	for _, f := range TaskSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats s as the spec ParseTaskSort reads.
+func (s Sort) String() string {
+	parts := make([]string, len(s))
+	for i, k := range s {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// idDesc reports the direction of the _id tiebreak.
+func (s Sort) idDesc() bool {
+	return len(s) > 0 && s[len(s)-1].Desc
+}
+
+// TaskCursor returns the cursor that resumes a listing in order s after t.
+func TaskCursor(t models.Task, s Sort) Cursor {
+	c := Cursor{Values: make([]any, len(s)), ID: t.ID}
+	for i, k := range s {
+		c.Values[i] = taskValue(t, k.Field)
+	}
+	return c
+}
+
+// taskValue returns t's value of a sort field as a cursor holds it.
+func taskValue(t models.Task, field string) any {
+	switch field {
+	case "createdAt":
+		return t.CreatedAt
+	case "updatedAt":
+		return t.UpdatedAt
+	case "dueDate":
+		if t.DueDate != nil {
+			return *t.DueDate
+		}
+	case "priority":
+		return int64(t.Priority)
+	case "title":
+		return t.Title
+	case "completed":
+		return t.Completed
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseTaskSort(t *testing.T) {
+	s, err := ParseTaskSort(" priority , -dueDate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Sort{{Field: "priority"}, {Field: "dueDate", Desc: true}}); !reflect.DeepEqual(s, want) {
+		t.Fatalf("sort = %+v, want %+v", s, want)
+	}
+	if s.String() != "priority,-dueDate" {
+		t.Errorf("String() = %q", s.String())
+	}
+
+	for _, bad := range []string{"", "-", "password", "$where", "title,,priority", "title,-title"} {
+		if _, err := ParseTaskSort(bad); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("ParseTaskSort(%q) = %v, want ErrInvalidSort", bad, err)
+		}
+	}
+}
+
+func TestAfterFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+	s := Sort{{Field: "priority", Desc: true}, {Field: "dueDate"}}
+	got := afterFilter(s, Cursor{Values: []any{int64(3), nil}, ID: id})
+	want := bson.M{"$or": bson.A{
+		bson.M{"$or": bson.A{bson.M{"priority": bson.M{"$lt": int64(3)}}, bson.M{"priority": nil}}},
+		bson.M{"priority": int64(3), "dueDate": bson.M{"$ne": nil}},
+		bson.M{"priority": int64(3), "dueDate": nil, "_id": bson.M{"$gt": id}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("afterFilter = %v\nwant %v", got, want)
+	}
+	if doc := sortDoc(s); !reflect.DeepEqual(doc, bson.D{{Key: "priority", Value: -1}, {Key: "dueDate", Value: 1}, {Key: "_id", Value: 1}}) {
+		t.Errorf("sortDoc = %v", doc)
+	}
+}
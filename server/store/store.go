@@ -0,0 +1,324 @@
+// Package store defines the persistence interfaces used by the HTTP handlers,
+// with a MongoDB implementation for production and an in-memory one for tests.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Subomi7/todoist-clone/server/models"
+)
+
+var (
+	// ErrNotFound is returned when no document matches.
+	ErrNotFound = errors.New("store: not found")
+	// ErrDuplicate is returned when a write violates a uniqueness constraint.
+	ErrDuplicate = errors.New("store: duplicate")
+)
+
+// Access selects documents visible to a user: those the user owns plus those in
+// any of WorkspaceIDs.
+type Access struct {
+	UserID       primitive.ObjectID
+	WorkspaceIDs []primitive.ObjectID
+}
+
+// Owner restricts access to the user's own documents.
+func Owner(userID primitive.ObjectID) Access {
+	return Access{UserID: userID}
+}
+
+// UserFilter selects users for the admin listing; empty fields are ignored.
+type UserFilter struct {
+	Search   string // substring of the email, matched literally
+	Role     string // models.RoleAdmin, or models.RoleUser for everyone else
+	Disabled *bool
+}
+
+// UserStore persists user accounts.
+type UserStore interface {
+	Create(ctx context.Context, u *models.User) error // ErrDuplicate on email
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error)
+	FindByIdentity(ctx context.Context, provider, subject string) (*models.User, error)
+	// List returns one page of matching users, newest first, and the total number of matches.
+	List(ctx context.Context, f UserFilter, skip, limit int64) ([]models.User, int64, error)
+	Count(ctx context.Context, f UserFilter) (int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+
+	SetRole(ctx context.Context, id primitive.ObjectID, role string) error
+	// PromoteAdmins grants the admin role to the users with the given emails and
+	// returns how many were not admins yet.
+	PromoteAdmins(ctx context.Context, emails []string) (int64, error)
+	SetDisabled(ctx context.Context, id primitive.ObjectID, disabled bool, now time.Time) error
+	// LinkIdentity adds an external identity to the user with the given email
+	// and returns the updated user; ErrNotFound when there is no such user.
+	LinkIdentity(ctx context.Context, email string, ident models.ExternalIdentity) (*models.User, error)
+
+	// StartTOTP stores a pending TOTP secret, replacing any earlier pending one,
+	// unless two-factor authentication is already enabled.
+	StartTOTP(ctx context.Context, id primitive.ObjectID, secret string) error
+	// EnableTOTP turns on two-factor authentication for the pending secret. It
+	// reports false when the secret changed or 2FA was enabled in the meantime.
+	EnableTOTP(ctx context.Context, id primitive.ObjectID, secret string, step uint64, recoveryHashes []string) (bool, error)
+	// ConsumeTOTPStep records step as used; false when it or a later step was used already.
+	ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step uint64) (bool, error)
+	// ConsumeRecoveryCode removes a recovery code; false when the user has no such code.
+	ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error)
+	DisableTOTP(ctx context.Context, id primitive.ObjectID) error
+}
+
+// TokenStore persists refresh tokens and personal access tokens. Only token hashes are stored.
+type TokenStore interface {
+	SaveRefresh(ctx context.Context, t *models.RefreshToken) error
+	FindRefresh(ctx context.Context, hash string) (*models.RefreshToken, error)
+	DeleteRefresh(ctx context.Context, hash string) error
+	DeleteRefreshForUser(ctx context.Context, userID primitive.ObjectID) error
+	DeleteExpiredRefresh(ctx context.Context, now time.Time) error
+	// CountActiveRefresh counts unexpired, unrevoked refresh tokens: one per signed-in session.
+	CountActiveRefresh(ctx context.Context, now time.Time) (int64, error)
+
+	CreatePersonal(ctx context.Context, t *models.PersonalAccessToken) error
+	FindPersonal(ctx context.Context, hash string) (*models.PersonalAccessToken, error)
+	ListPersonal(ctx context.Context, userID primitive.ObjectID) ([]models.PersonalAccessToken, error) // newest first
+	CountPersonal(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	TouchPersonal(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+	DeletePersonal(ctx context.Context, id, userID primitive.ObjectID) (bool, error)
+	CountAllPersonal(ctx context.Context) (int64, error)
+}
+
+// AttemptStore persists failed sign-in counters for login throttling.
+type AttemptStore interface {
+	Find(ctx context.Context, keys []string) ([]models.LoginAttempt, error)
+	// RecordFailure atomically increments the failures of key, restarting the
+	// count when the previous failure is older than window.
+	RecordFailure(ctx context.Context, key string, now time.Time, window, retention time.Duration) (*models.LoginAttempt, error)
+	SetLockedUntil(ctx context.Context, key string, until time.Time) error
+	SetUnlockToken(ctx context.Context, key, hash string) error
+	DeleteByUnlockToken(ctx context.Context, hash string) (bool, error)
+	Delete(ctx context.Context, keys []string) error
+}
+
+// RateLimitStore counts requests per key in fixed windows.
+type RateLimitStore interface {
+	// Hit atomically counts one request for key in the window starting at start
+	// and returns the count so far, this request included. Counters are
+	// forgotten once the window is over.
+	Hit(ctx context.Context, key string, start time.Time, window time.Duration) (int64, error)
+}
+
+// ProjectFilter selects projects for listing; nil fields are ignored.
+type ProjectFilter struct {
+	UserID      *primitive.ObjectID
+	WorkspaceID *primitive.ObjectID
+}
+
+// ProjectOrder is the order of project listings.
+var ProjectOrder = Sort{{Field: "createdAt", Desc: true}}
+
+// ProjectPage selects a window of a project listing, newest first. After, when
+// set, replaces Skip: the page starts right after that project. The total is
+// only counted when Count is set.
+type ProjectPage struct {
+	Skip  int64
+	Limit int64
+	After *Cursor
+	Count bool
+}
+
+// ProjectCursor returns the cursor that resumes a listing after p.
+func ProjectCursor(p models.Project) Cursor {
+	return Cursor{Values: []any{p.CreatedAt}, ID: p.ID}
+}
+
+// ProjectStore persists projects.
+type ProjectStore interface {
+	Create(ctx context.Context, p *models.Project) error // ErrDuplicate on a name taken in the same workspace
+	Get(ctx context.Context, id primitive.ObjectID, access Access) (*models.Project, error)
+	FindByName(ctx context.Context, userID, workspaceID primitive.ObjectID, name string) (*models.Project, error)
+	// List returns one page (newest first) with open task counts, and the total number of matches when p.Count is set.
+	List(ctx context.Context, f ProjectFilter, p ProjectPage) ([]*models.ProjectWithCount, int64, error)
+	Rename(ctx context.Context, id, userID primitive.ObjectID, name string, now time.Time) (bool, error) // ErrDuplicate on a name taken in the same workspace
+	// Reassign gives a project to userID under name; ErrDuplicate when userID already has that name in the workspace.
+	Reassign(ctx context.Context, id, userID primitive.ObjectID, name string, now time.Time) error
+	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	DeleteByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+	Count(ctx context.Context, f ProjectFilter) (int64, error)
+}
+
+// TaskFilter selects tasks for listing; nil and empty fields are ignored.
+type TaskFilter struct {
+	UserID      *primitive.ObjectID
+	WorkspaceID *primitive.ObjectID
+	ProjectID   *primitive.ObjectID
+	InboxID     *primitive.ObjectID
+	Completed   *bool
+	Search      string // case-insensitive substring of the title or description, matched literally
+}
+
+// Cursor resumes a listing after the item it was taken from. Listings are
+// ordered by their sort keys and then by _id, so the values and ID together
+// identify a position even when sort values repeat.
+type Cursor struct {
+	Values []any              // that item's value of each sort key: time.Time, int64, string, bool, or nil when unset
+	ID     primitive.ObjectID // _id of that item
+}
+
+// TaskPage selects the window and order of a task listing. After, when set,
+// replaces Skip: the page starts right after that task. The total is only
+// counted when Count is set.
+type TaskPage struct {
+	Sort  Sort
+	Skip  int64
+	Limit int64
+	After *Cursor
+	Count bool
+}
+
+// TaskMove moves a task to another project and that project's workspace.
+type TaskMove struct {
+	ProjectID   primitive.ObjectID
+	WorkspaceID *primitive.ObjectID
+}
+
+// TaskUpdate lists the fields to change; nil fields are left untouched.
+type TaskUpdate struct {
+	Title       *string
+	Description *string
+	Completed   *bool
+	DueDate     *time.Time
+	Priority    *models.Priority
+	Move        *TaskMove
+	UpdatedAt   time.Time
+}
+
+// TaskStore persists tasks.
+type TaskStore interface {
+	Create(ctx context.Context, t *models.Task) error
+	Get(ctx context.Context, id primitive.ObjectID, access Access) (*models.Task, error)
+	// List returns one page of matching tasks, and the total number of matches when p.Count is set.
+	List(ctx context.Context, f TaskFilter, p TaskPage) ([]models.Task, int64, error)
+	Update(ctx context.Context, id primitive.ObjectID, access Access, u TaskUpdate) (*models.Task, error)
+	Delete(ctx context.Context, id primitive.ObjectID, access Access) (bool, error)
+	// OwnersInProject returns the distinct users with tasks in a project.
+	OwnersInProject(ctx context.Context, projectID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// MoveProjectTasks moves one user's tasks out of a project.
+	MoveProjectTasks(ctx context.Context, fromProject, userID primitive.ObjectID, to TaskMove, now time.Time) error
+	DeleteByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+	Count(ctx context.Context, f TaskFilter) (int64, error)
+}
+
+// WorkspaceStore persists workspaces and their memberships.
+type WorkspaceStore interface {
+	// EnsurePersonal returns the user's personal workspace, creating it and the owner membership if needed.
+	EnsurePersonal(ctx context.Context, userID primitive.ObjectID, name string, now time.Time) (*models.Workspace, error)
+	Create(ctx context.Context, ws *models.Workspace, owner *models.WorkspaceMember) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error)
+	// List returns the given workspaces, personal first, then by name.
+	List(ctx context.Context, ids []primitive.ObjectID) ([]models.Workspace, error)
+	OwnedBy(ctx context.Context, userID primitive.ObjectID) ([]models.Workspace, error)
+	Rename(ctx context.Context, id primitive.ObjectID, name string, now time.Time) error
+	SetOwner(ctx context.Context, id, userID primitive.ObjectID, now time.Time) error
+	// Delete removes the workspace and its memberships.
+	Delete(ctx context.Context, id primitive.ObjectID) error
+
+	GetMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error)
+	Members(ctx context.Context, workspaceID primitive.ObjectID) ([]models.WorkspaceMember, error) // by join date
+	MembershipsOf(ctx context.Context, userID primitive.ObjectID) ([]models.WorkspaceMember, error)
+	AddMember(ctx context.Context, m *models.WorkspaceMember) error // ErrDuplicate if already a member
+	// SetMemberRole and RemoveMember never touch the owner; they report whether a member matched.
+	SetMemberRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role string) (bool, error)
+	RemoveMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (bool, error)
+	// Successor returns the member that inherits a workspace from its owner:
+	// the longest-standing admin, else the longest-standing member.
+	Successor(ctx context.Context, workspaceID, ownerID primitive.ObjectID) (*models.WorkspaceMember, error)
+}
+
+// OAuthStore persists the clients, authorization codes, tokens and consents of
+// the OAuth2 provider. Only code and token hashes are stored.
+type OAuthStore interface {
+	CreateClient(ctx context.Context, c *models.OAuthClient) error
+	FindClient(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	ListClients(ctx context.Context, ownerID primitive.ObjectID) ([]models.OAuthClient, error) // newest first
+	CountClients(ctx context.Context, ownerID primitive.ObjectID) (int64, error)
+	CountAllClients(ctx context.Context) (int64, error)
+	// DeleteClient removes one of the owner's clients with every code, token and
+	// consent issued to it. It reports whether the owner had such a client.
+	DeleteClient(ctx context.Context, clientID string, ownerID primitive.ObjectID) (bool, error)
+	// DeleteClientsOwnedBy removes all of the owner's clients the same way.
+	DeleteClientsOwnedBy(ctx context.Context, ownerID primitive.ObjectID) error
+
+	CreateCode(ctx context.Context, code *models.OAuthAuthCode) error
+	// RedeemCode marks the code with the given hash as used by grantID and
+	// returns it as it was before. A code that comes back with UsedAt set was
+	// already redeemed, and its GrantID names the grant issued the first time.
+	RedeemCode(ctx context.Context, hash string, grantID primitive.ObjectID, now time.Time) (*models.OAuthAuthCode, error)
+	DeleteCodesForUser(ctx context.Context, userID primitive.ObjectID) error
+
+	SaveTokens(ctx context.Context, tokens ...*models.OAuthToken) error
+	// FindToken returns the token with the given hash; kind "" matches either kind.
+	FindToken(ctx context.Context, hash, kind string) (*models.OAuthToken, error)
+	// TakeToken deletes and returns the token with the given hash and kind.
+	TakeToken(ctx context.Context, hash, kind string) (*models.OAuthToken, error)
+	DeleteToken(ctx context.Context, id primitive.ObjectID) error
+	// DeleteGrant removes the tokens of a grant; kind "" removes both kinds.
+	DeleteGrant(ctx context.Context, grantID primitive.ObjectID, kind string) error
+	DeleteTokensForUser(ctx context.Context, userID primitive.ObjectID) error
+
+	FindConsent(ctx context.Context, userID primitive.ObjectID, clientID string) (*models.OAuthConsent, error)
+	// AddConsent adds scopes to the user's consent for a client, creating it if needed.
+	AddConsent(ctx context.Context, userID primitive.ObjectID, clientID string, scopes []string, now time.Time) error
+}
+
+// OIDCStateStore persists in-flight OpenID Connect logins.
+type OIDCStateStore interface {
+	Save(ctx context.Context, s *models.OIDCState) error
+	// Take deletes and returns the state with the given hash and provider, so it works once.
+	Take(ctx context.Context, hash, provider string) (*models.OIDCState, error)
+}
+
+// AuditStore persists the administrator audit log.
+type AuditStore interface {
+	Record(ctx context.Context, e *models.AdminAuditEntry) error
+	// List returns one page of entries, newest first, optionally only those about targetUserID.
+	List(ctx context.Context, targetUserID *primitive.ObjectID, skip, limit int64) ([]models.AdminAuditEntry, error)
+	Count(ctx context.Context, targetUserID *primitive.ObjectID) (int64, error)
+}
+
+// Collection holds the documents of one collection, in their stored field names.
+type Collection struct {
+	Name string
+	Docs []bson.M
+}
+
+// AccountStore reads and removes a user's data across every collection that
+// holds any, for data export and account deletion.
+type AccountStore interface {
+	// Export returns the user document in "users", then the user's documents in
+	// each per-user collection.
+	Export(ctx context.Context, userID primitive.ObjectID) ([]Collection, error)
+	// DeleteData removes the user's documents from every per-user collection
+	// that is not export-only, credentials first. The user document itself is
+	// left to Users.Delete.
+	DeleteData(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// Stores bundles every store the handlers use.
+type Stores struct {
+	Users      UserStore
+	Tokens     TokenStore
+	Attempts   AttemptStore
+	RateLimits RateLimitStore
+	Projects   ProjectStore
+	Tasks      TaskStore
+	Workspaces WorkspaceStore
+	OAuth      OAuthStore
+	OIDCStates OIDCStateStore
+	Audit      AuditStore
+	Accounts   AccountStore
+}
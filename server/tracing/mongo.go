@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"context"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartMongoSpan starts a client span for one MongoDB command as a child of
+// the span in ctx. End it with End.
+func StartMongoSpan(ctx context.Context, database, collection, command string) trace.Span {
+	name := command
+	if collection != "" {
+		name += " " + collection
+	}
+	_, span := tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameMongoDB,
+			semconv.DBNamespace(database),
+			semconv.DBOperationName(command),
+		),
+	)
+	if collection != "" {
+		span.SetAttributes(semconv.DBCollectionName(collection))
+	}
+	return span
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return rec
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareSpans(t *testing.T) {
+	rec := recordSpans(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		// stands in for a Mongo command issued with the request context
+		StartMongoSpan(c.UserContext(), "todoist", "items", "find").End()
+		return c.SendString("ok")
+	})
+	app.Get("/boom", func(c *fiber.Ctx) error { return fiber.ErrBadGateway })
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("GET", "/items/42", nil)
+	req.Header.Set("traceparent", parent)
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Test(httptest.NewRequest("GET", "/boom", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	mongo, server, failed := spans[0], spans[1], spans[2]
+
+	if server.Name() != "GET /items/:id" || server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("server span = %q (%v)", server.Name(), server.SpanKind())
+	}
+	if got := server.Parent().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span did not continue the caller's trace: %s", got)
+	}
+	if got := attr(server, "http.response.status_code").AsInt64(); got != 200 {
+		t.Errorf("status attribute = %d", got)
+	}
+
+	if mongo.Name() != "find items" || mongo.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("mongo span %q is not a child of the request span", mongo.Name())
+	}
+	if got := attr(mongo, "db.collection.name").AsString(); got != "items" {
+		t.Errorf("db.collection.name = %q", got)
+	}
+
+	if failed.Status().Code != codes.Error {
+		t.Errorf("5xx span status = %v, want Error", failed.Status().Code)
+	}
+}
@@ -0,0 +1,219 @@
+// Package validate checks request DTOs against rules declared in struct tags
+// and reports every failing field at once:
+//
+//	type CreateProjectDTO struct {
+//		Name string `json:"name" validate:"required,max=100"`
+//	}
+//
+// Fields are named after their json tag. Rules, comma separated:
+//
+//	required   present and, for strings, not blank
+//	notblank   when present, a string must not be blank (optional updates)
+//	omitempty  skip the remaining rules for a zero value
+//	min=N      strings: at least N characters; numbers: at least N
+//	max=N      strings: at most N characters; numbers: at most N
+//	maxbytes=N strings: at most N bytes of UTF-8, for limits such as bcrypt's 72
+//	oneof=A B  the value is one of the space separated options
+//	email      a single bare email address, without a display name or <>
+//	rfc3339    a timestamp such as 2025-08-01T15:04:05Z
+//	objectid   a 24 character hex ObjectID
+//
+// Pointer fields are checked through the pointer: a nil pointer only fails
+// required. Rules on an unsupported kind panic, so a typo fails the first test
+// that touches the DTO.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Field error codes name what is wrong with one field.
+const (
+	Required = "required"
+	Invalid  = "invalid"
+	TooShort = "too_short"
+	TooLong  = "too_long"
+	Unknown  = "unknown"
+)
+
+// FieldError describes one invalid field of a request body or query.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Struct validates the struct v points to and returns its invalid fields in
+// declaration order, or nil.
+func Struct(v any) []FieldError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	var errs []FieldError
+	for _, f := range fieldsOf(rv.Type()) {
+		if fe, ok := f.check(rv.Field(f.index)); !ok {
+			errs = append(errs, fe)
+		}
+	}
+	return errs
+}
+
+// Rule is one parsed rule of a validate tag: "max=200" is {Name: "max", Arg: "200"}.
+type Rule struct {
+	Name string
+	Arg  string
+}
+
+// Rules parses the validate tag of sf. The openapi package uses it to
+// describe the same constraints in the spec.
+func Rules(sf reflect.StructField) []Rule {
+	tag := sf.Tag.Get("validate")
+	if tag == "" {
+		return nil
+	}
+	var rules []Rule
+	for _, r := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(r, "=")
+		rules = append(rules, Rule{Name: name, Arg: arg})
+	}
+	return rules
+}
+
+type field struct {
+	index int
+	name  string
+	rules []Rule
+}
+
+var cache sync.Map // reflect.Type -> []field
+
+// fieldsOf parses the validate tags of t once.
+func fieldsOf(t reflect.Type) []field {
+	if f, ok := cache.Load(t); ok {
+		return f.([]field)
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if rules := Rules(sf); rules != nil {
+			fields = append(fields, field{index: i, name: JSONName(sf), rules: rules})
+		}
+	}
+	cache.Store(t, fields)
+	return fields
+}
+
+// JSONName returns the name a struct field has in JSON.
+func JSONName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+func (f field) check(v reflect.Value) (FieldError, bool) {
+	present := true
+	if v.Kind() == reflect.Pointer {
+		present = !v.IsNil()
+		if present {
+			v = v.Elem()
+		}
+	}
+	for _, r := range f.rules {
+		if r.Name == Required {
+			if !present || v.IsZero() || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+				return f.fail(Required, "%s is required", f.name), false
+			}
+			continue
+		}
+		if !present || (r.Name == "omitempty" && v.IsZero()) {
+			return FieldError{}, true
+		}
+		if fe, ok := f.apply(r, v); !ok {
+			return fe, false
+		}
+	}
+	return FieldError{}, true
+}
+
+func (f field) apply(r Rule, v reflect.Value) (FieldError, bool) {
+	switch r.Name {
+	case "omitempty":
+	case "notblank":
+		if strings.TrimSpace(v.String()) == "" {
+			return f.fail(Required, "%s cannot be empty", f.name), false
+		}
+	case "min", "max":
+		n, err := strconv.ParseInt(r.Arg, 10, 64)
+		if err != nil {
+			panic("validate: bad " + r.Name + " on " + f.name)
+		}
+		size, unit := f.size(v)
+		if r.Name == "min" && size < n {
+			return f.fail(TooShort, "%s must be at least %d%s", f.name, n, unit), false
+		}
+		if r.Name == "max" && size > n {
+			return f.fail(TooLong, "%s must be at most %d%s", f.name, n, unit), false
+		}
+	case "maxbytes":
+		n, err := strconv.Atoi(r.Arg)
+		if err != nil || v.Kind() != reflect.String {
+			panic("validate: bad maxbytes on " + f.name)
+		}
+		if len(v.String()) > n {
+			return f.fail(TooLong, "%s must be at most %d bytes", f.name, n), false
+		}
+	case "oneof":
+		got := v.String()
+		if v.CanInt() {
+			got = strconv.FormatInt(v.Int(), 10)
+		}
+		for _, opt := range strings.Fields(r.Arg) {
+			if got == opt {
+				return FieldError{}, true
+			}
+		}
+		return f.fail(Invalid, "%s must be one of: %s", f.name, strings.Join(strings.Fields(r.Arg), ", ")), false
+	case "email":
+		// ParseAddress also takes RFC 5322 forms like "Bob <bob@x.io>"
+		if addr, err := mail.ParseAddress(v.String()); err != nil || addr.Address != v.String() {
+			return f.fail(Invalid, "%s must be a valid email address", f.name), false
+		}
+	case "rfc3339":
+		if _, err := time.Parse(time.RFC3339, v.String()); err != nil {
+			return f.fail(Invalid, "%s must be an RFC 3339 timestamp (e.g. 2025-08-01T15:04:05Z)", f.name), false
+		}
+	case "objectid":
+		if !primitive.IsValidObjectID(v.String()) {
+			return f.fail(Invalid, "%s must be a valid id", f.name), false
+		}
+	default:
+		panic("validate: unknown rule " + r.Name + " on " + f.name)
+	}
+	return FieldError{}, true
+}
+
+// size measures v for min and max: characters of a string, or a number's value.
+func (f field) size(v reflect.Value) (int64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), ""
+	case reflect.Slice:
+		return int64(v.Len()), " items"
+	}
+	panic("validate: min/max on unsupported field " + f.name)
+}
+
+func (f field) fail(code, format string, args ...any) FieldError {
+	return FieldError{Field: f.name, Code: code, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,69 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+type dto struct {
+	Title    string  `json:"title" validate:"required,max=5"`
+	Note     *string `json:"note,omitempty" validate:"notblank,max=3"`
+	Priority int     `json:"priority,omitempty" validate:"omitempty,oneof=1 2 3"`
+	Email    string  `json:"email,omitempty" validate:"omitempty,email"`
+	Due      string  `json:"dueDate,omitempty" validate:"omitempty,rfc3339"`
+	Project  string  `json:"projectId,omitempty" validate:"omitempty,objectid"`
+	Password string  `json:"password,omitempty" validate:"omitempty,min=8"`
+	Secret   string  `json:"secret,omitempty" validate:"maxbytes=4"`
+	Untagged string
+}
+
+func ptr(s string) *string { return &s }
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name string
+		in   dto
+		want []string // field:code
+	}{
+		{"valid", dto{Title: "ok", Note: ptr("abc"), Priority: 2, Email: "a@b.co", Due: "2025-08-01T15:04:05Z", Project: "64b7f0c2a1b2c3d4e5f60718"}, nil},
+		{"optional fields omitted", dto{Title: "ok"}, nil},
+		{"blank required", dto{Title: "   "}, []string{"title:required"}},
+		{"every failure at once", dto{
+			Title:    "too long",
+			Note:     ptr(" "),
+			Priority: 7,
+			Email:    "nope",
+			Due:      "tomorrow",
+			Project:  "../etc",
+			Password: "short",
+		}, []string{"title:too_long", "note:required", "priority:invalid", "email:invalid", "dueDate:invalid", "projectId:invalid", "password:too_short"}},
+		{"display name", dto{Title: "ok", Email: "Bob <bob@x.io>"}, []string{"email:invalid"}},
+		{"angle brackets", dto{Title: "ok", Email: "<bob@x.io>"}, []string{"email:invalid"}},
+		{"characters, not bytes", dto{Title: "héllo", Note: ptr("日本語")}, nil},
+		{"bytes, not characters", dto{Title: "ok", Secret: "日本"}, []string{"secret:too_long"}},
+		{"byte limit reached", dto{Title: "ok", Secret: "héh"}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, fe := range Struct(&tt.in) {
+			got = append(got, fe.Field+":"+fe.Code)
+			if fe.Message == "" || !strings.HasPrefix(fe.Message, fe.Field) {
+				t.Errorf("%s: message %q does not name the field", tt.name, fe.Message)
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownRulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a misspelled rule")
+		}
+	}()
+	Struct(&struct {
+		Name string `json:"name" validate:"requird"`
+	}{})
+}